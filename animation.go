@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	animationFormatAPNG = "apng"
+	animationFormatWebM = "webm"
+
+	gifDefaultDelay = 10
+)
+
+var animationFormats = []string{animationFormatAPNG, animationFormatWebM}
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+type animationFrame struct {
+	Image *image.NRGBA
+	Delay int
+}
+
+func animationExtension(format string) string {
+	if format == animationFormatWebM {
+		return ".webm"
+	}
+	return ".png"
+}
+
+func decodeGIFFrames(reader io.Reader) ([]animationFrame, int, error) {
+	animation, err := gif.DecodeAll(reader)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	canvas := image.NewNRGBA(image.Rect(0, 0, animation.Config.Width, animation.Config.Height))
+	frames := make([]animationFrame, 0, len(animation.Image))
+	for index, paletted := range animation.Image {
+		var disposal byte
+		if index < len(animation.Disposal) {
+			disposal = animation.Disposal[index]
+		}
+		var previous *image.NRGBA
+		if disposal == gif.DisposalPrevious {
+			previous = cloneNRGBA(canvas)
+		}
+
+		draw.Draw(canvas, paletted.Bounds(), paletted, paletted.Bounds().Min, draw.Over)
+		delay := gifDefaultDelay
+		if index < len(animation.Delay) && animation.Delay[index] > 0 {
+			delay = animation.Delay[index]
+		}
+		frames = append(frames, animationFrame{Image: cloneNRGBA(canvas), Delay: delay})
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, paletted.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return frames, animation.LoopCount, nil
+}
+
+func cloneNRGBA(source *image.NRGBA) *image.NRGBA {
+	clone := image.NewNRGBA(source.Rect)
+	copy(clone.Pix, source.Pix)
+	return clone
+}
+
+func writePNGChunk(writer io.Writer, chunkType string, data []byte) error {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], chunkType)
+	checksum := crc32.NewIEEE()
+	checksum.Write(header[4:])
+	checksum.Write(data)
+	var footer [4]byte
+	binary.BigEndian.PutUint32(footer[:], checksum.Sum32())
+
+	for _, part := range [][]byte{header[:], data, footer[:]} {
+		if _, err := writer.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func compressNRGBA(frame *image.NRGBA) ([]byte, error) {
+	var buffer bytes.Buffer
+	compressor, err := zlib.NewWriterLevel(&buffer, zlib.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	width := frame.Rect.Dx()
+	for row := 0; row < frame.Rect.Dy(); row++ {
+		offset := row * frame.Stride
+		compressor.Write([]byte{0})
+		compressor.Write(frame.Pix[offset : offset+width*4])
+	}
+	if err := compressor.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func writeAPNG(writer io.Writer, frames []animationFrame, loopCount int) error {
+	if len(frames) == 0 {
+		return errors.New("animation has no frames")
+	}
+	bounds := frames[0].Image.Rect
+	if _, err := writer.Write(pngSignature); err != nil {
+		return err
+	}
+
+	header := make([]byte, 13)
+	binary.BigEndian.PutUint32(header[0:], uint32(bounds.Dx()))
+	binary.BigEndian.PutUint32(header[4:], uint32(bounds.Dy()))
+	header[8] = 8
+	header[9] = 6
+	if err := writePNGChunk(writer, "IHDR", header); err != nil {
+		return err
+	}
+
+	plays := 0
+	switch {
+	case loopCount < 0:
+		plays = 1
+	case loopCount > 0:
+		plays = loopCount + 1
+	}
+	animationControl := make([]byte, 8)
+	binary.BigEndian.PutUint32(animationControl[0:], uint32(len(frames)))
+	binary.BigEndian.PutUint32(animationControl[4:], uint32(plays))
+	if err := writePNGChunk(writer, "acTL", animationControl); err != nil {
+		return err
+	}
+
+	var sequence uint32
+	for index, frame := range frames {
+		frameControl := make([]byte, 26)
+		binary.BigEndian.PutUint32(frameControl[0:], sequence)
+		binary.BigEndian.PutUint32(frameControl[4:], uint32(bounds.Dx()))
+		binary.BigEndian.PutUint32(frameControl[8:], uint32(bounds.Dy()))
+		binary.BigEndian.PutUint16(frameControl[20:], uint16(frame.Delay))
+		binary.BigEndian.PutUint16(frameControl[22:], 100)
+		sequence++
+		if err := writePNGChunk(writer, "fcTL", frameControl); err != nil {
+			return err
+		}
+
+		compressed, err := compressNRGBA(frame.Image)
+		if err != nil {
+			return err
+		}
+		if index == 0 {
+			err = writePNGChunk(writer, "IDAT", compressed)
+		} else {
+			frameData := make([]byte, 4, 4+len(compressed))
+			binary.BigEndian.PutUint32(frameData, sequence)
+			sequence++
+			err = writePNGChunk(writer, "fdAT", append(frameData, compressed...))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return writePNGChunk(writer, "IEND", nil)
+}
+
+func convertGIFToAPNG(store storage, sourcePath string, outputPath string) (bool, error) {
+	sourceBytes, err := store.ReadFile(sourcePath)
+	if err != nil {
+		return false, err
+	}
+	frames, loopCount, err := decodeGIFFrames(bytes.NewReader(sourceBytes))
+	if err != nil {
+		return false, err
+	}
+	if len(frames) < 2 {
+		return false, nil
+	}
+
+	var buffer bytes.Buffer
+	if err := writeAPNG(&buffer, frames, loopCount); err != nil {
+		return false, err
+	}
+	return true, store.WriteFile(outputPath, buffer.Bytes())
+}
+
+func convertGIFToWebM(sourcePath string, outputPath string) (bool, error) {
+	sourceFile, err := os.Open(sourcePath)
+	if err != nil {
+		return false, err
+	}
+	animation, err := gif.DecodeAll(sourceFile)
+	sourceFile.Close()
+	if err != nil {
+		return false, err
+	}
+	if len(animation.Image) < 2 {
+		return false, nil
+	}
+
+	ffmpegPath, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return false, errors.New("webm conversion needs ffmpeg on PATH")
+	}
+	temporaryPath := outputPath + ".tmp.webm"
+	command := exec.Command(ffmpegPath, "-y", "-loglevel", "error", "-i", sourcePath,
+		"-c:v", "libvpx-vp9", "-pix_fmt", "yuva420p", "-b:v", "0", "-crf", "30", "-an", temporaryPath)
+	if output, err := command.CombinedOutput(); err != nil {
+		os.Remove(temporaryPath)
+		return false, fmt.Errorf("ffmpeg: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return true, os.Rename(temporaryPath, outputPath)
+}
+
+func convertAnimatedFile(store storage, outputRoot string, file *manifestFile, format string, logFunc func(string)) {
+	if format == "" || !strings.HasSuffix(file.Path, ".gif") {
+		return
+	}
+	convertedPath := strings.TrimSuffix(file.Path, ".gif") + animationExtension(format)
+	outputPath := filepath.Join(outputRoot, filepath.FromSlash(convertedPath))
+	if file.Converted == convertedPath && store.Exists(outputPath) {
+		return
+	}
+
+	sourcePath := filepath.Join(outputRoot, filepath.FromSlash(file.Path))
+	var converted bool
+	var err error
+	switch {
+	case format != animationFormatWebM:
+		converted, err = convertGIFToAPNG(store, sourcePath, outputPath)
+	case isLocalStorage(store):
+		converted, err = convertGIFToWebM(sourcePath, outputPath)
+	default:
+		err = errors.New("webm conversion needs a local output folder")
+	}
+	if err != nil {
+		logFunc(fmt.Sprintf("[error] cannot convert %s to %s: %v", filepath.Base(file.Path), format, err))
+		return
+	}
+	if converted {
+		file.Converted = convertedPath
+		logFunc(fmt.Sprintf("[ok] %s", filepath.Base(convertedPath)))
+	}
+}
+
+func extractAnimationFrames(store storage, outputRoot string, file manifestFile, logFunc func(string)) {
+	if !strings.HasSuffix(file.Path, ".gif") {
+		return
+	}
+	framesPath := strings.TrimSuffix(file.Path, ".gif") + "_frames"
+	framesDirectory := filepath.Join(outputRoot, filepath.FromSlash(framesPath))
+	if store.Exists(filepath.Join(framesDirectory, "001.png")) {
+		return
+	}
+
+	sourceBytes, err := store.ReadFile(filepath.Join(outputRoot, filepath.FromSlash(file.Path)))
+	if err != nil {
+		logFunc(fmt.Sprintf("[error] cannot extract frames of %s: %v", filepath.Base(file.Path), err))
+		return
+	}
+	frames, _, err := decodeGIFFrames(bytes.NewReader(sourceBytes))
+	if err != nil {
+		logFunc(fmt.Sprintf("[error] cannot extract frames of %s: %v", filepath.Base(file.Path), err))
+		return
+	}
+	if len(frames) < 2 {
+		return
+	}
+
+	for index, frame := range frames {
+		var buffer bytes.Buffer
+		if err := png.Encode(&buffer, frame.Image); err != nil {
+			logFunc(fmt.Sprintf("[error] cannot encode frame %d of %s: %v", index+1, filepath.Base(file.Path), err))
+			return
+		}
+		framePath := filepath.Join(framesDirectory, fmt.Sprintf("%03d.png", index+1))
+		if err := store.WriteFile(framePath, buffer.Bytes()); err != nil {
+			logFunc(fmt.Sprintf("[error] cannot write %s: %v", framePath, err))
+			return
+		}
+	}
+	logFunc(fmt.Sprintf("[ok] %s (%d frames)", filepath.Base(framesPath), len(frames)))
+}
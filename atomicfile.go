@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+func writeFileAtomic(path string, data []byte, durable bool) error {
+	temporaryFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	temporaryPath := temporaryFile.Name()
+
+	_, err = temporaryFile.Write(data)
+	if err == nil && durable {
+		err = temporaryFile.Sync()
+	}
+	if closeError := temporaryFile.Close(); err == nil {
+		err = closeError
+	}
+	if err == nil {
+		err = os.Chmod(temporaryPath, 0o644)
+	}
+	if err != nil {
+		os.Remove(temporaryPath)
+		return err
+	}
+	return commitFile(temporaryPath, path, durable)
+}
+
+func commitFile(temporaryPath string, path string, durable bool) error {
+	if durable {
+		temporaryFile, err := os.OpenFile(temporaryPath, os.O_RDWR, 0)
+		if err != nil {
+			return err
+		}
+		err = temporaryFile.Sync()
+		if closeError := temporaryFile.Close(); err == nil {
+			err = closeError
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(temporaryPath, path); err != nil {
+		os.Remove(temporaryPath)
+		return err
+	}
+	if durable {
+		return syncDirectory(filepath.Dir(path))
+	}
+	return nil
+}
+
+func syncDirectory(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	directory, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer directory.Close()
+	return directory.Sync()
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type rateLimitTransport struct {
+	base        http.RoundTripper
+	interval    time.Duration
+	lock        sync.Mutex
+	nextRequest time.Time
+}
+
+func (transport *rateLimitTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	transport.lock.Lock()
+	now := time.Now()
+	if transport.nextRequest.Before(now) {
+		transport.nextRequest = now
+	}
+	wait := transport.nextRequest.Sub(now)
+	transport.nextRequest = transport.nextRequest.Add(transport.interval)
+	transport.lock.Unlock()
+
+	if wait > 0 {
+		if err := sleepContext(request.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+	return transport.base.RoundTrip(request)
+}
+
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func rateLimitedClient(httpClient *http.Client, requestsPerSecond float64) *http.Client {
+	if requestsPerSecond <= 0 {
+		return httpClient
+	}
+	baseTransport := httpClient.Transport
+	if baseTransport == nil {
+		baseTransport = http.DefaultTransport
+	}
+	limitedClient := *httpClient
+	limitedClient.Transport = &rateLimitTransport{
+		base:     baseTransport,
+		interval: time.Duration(float64(time.Second) / requestsPerSecond),
+	}
+	return &limitedClient
+}
+
+func readChannelList(reader io.Reader) ([]string, error) {
+	var channels []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		channels = append(channels, line)
+	}
+	return channels, scanner.Err()
+}
+
+func expandStdinArguments(positional []string, stdin io.Reader) ([]string, error) {
+	expanded := make([]string, 0, len(positional))
+	stdinRead := false
+	for _, argument := range positional {
+		if argument != "-" {
+			expanded = append(expanded, argument)
+			continue
+		}
+		if stdinRead {
+			continue
+		}
+		stdinRead = true
+
+		channels, err := readChannelList(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("reading channels from standard input: %w", err)
+		}
+		expanded = append(expanded, channels...)
+	}
+	if stdinRead && len(expanded) == 0 {
+		return nil, errors.New("no channel identifiers on standard input")
+	}
+	return expanded, nil
+}
+
+func runBatchMode(httpClient *http.Client, channelIdentifiers []string, options downloadOptions) int {
+	var outputLock sync.Mutex
+	var failuresLock sync.Mutex
+	failedChannels := make([]string, 0)
+	restoreSignals := stopOnSignal(&options)
+	defer restoreSignals()
+
+	channelInputs := make(chan string)
+	var workers sync.WaitGroup
+	for worker := 0; worker < min(options.ChannelConcurrency, len(channelIdentifiers)); worker++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for channelIdentifier := range channelInputs {
+				logFunc := func(line string) {
+					outputLock.Lock()
+					fmt.Printf("%s[%s] %s\n", logTimestamp(options.Timestamps), channelIdentifier, line)
+					outputLock.Unlock()
+					options.Log.write(fmt.Sprintf("[%s] %s", channelIdentifier, line))
+				}
+				if stopRequested(options) {
+					continue
+				}
+				_, err := downloadChannelInput(httpClient, channelIdentifier, options, logFunc)
+				if errors.Is(err, errDownloadInterrupted) {
+					continue
+				}
+				if err != nil {
+					logFunc(fmt.Sprintf("Error: %v", err))
+					failuresLock.Lock()
+					failedChannels = append(failedChannels, channelIdentifier)
+					failuresLock.Unlock()
+				}
+				if options.ChannelDone != nil {
+					options.ChannelDone(channelIdentifier, err)
+				}
+			}
+		}()
+	}
+
+	for _, channelIdentifier := range channelIdentifiers {
+		channelIdentifier = strings.TrimSpace(channelIdentifier)
+		if channelIdentifier == "" {
+			continue
+		}
+		channelInputs <- channelIdentifier
+	}
+	close(channelInputs)
+	workers.Wait()
+
+	if stopRequested(options) {
+		printResumeHint(options)
+		return interruptedExitCode
+	}
+
+	if len(failedChannels) > 0 {
+		summary := fmt.Sprintf("%d of %d channels failed: %s", len(failedChannels), len(channelIdentifiers), strings.Join(failedChannels, ", "))
+		fmt.Fprintln(os.Stderr, summary)
+		options.Log.write(summary)
+		notifyIfRequested(options, true, summary)
+		return 1
+	}
+	notifyIfRequested(options, false, fmt.Sprintf("%d channels downloaded", len(channelIdentifiers)))
+	return 0
+}
+
+func downloadChannelInput(httpClient *http.Client, channelIdentifier string, options downloadOptions, logFunc func(string)) (string, error) {
+	provider, providerIdentifier, err := selectProvider(channelIdentifier, options.Provider)
+	if err != nil {
+		return "", err
+	}
+
+	channelClient := throttledClient(rateLimitedClient(httpClient, options.ChannelRateLimit), logFunc)
+	channelID, err := provider.ResolveChannelID(channelClient, providerIdentifier)
+	if err != nil {
+		appMetrics.recordChannel(provider.Name(), err)
+		return "", fmt.Errorf("resolving channel: %w", err)
+	}
+	outputRoot, err := downloadChannelEmotes(channelClient, provider, channelID, options, logFunc)
+	appMetrics.recordChannel(provider.Name(), err)
+	return outputRoot, err
+}
+
+type scrapeDelayTransport struct {
+	base    http.RoundTripper
+	host    string
+	limiter *rateLimitTransport
+}
+
+func newScrapeDelayTransport(base http.RoundTripper, delay time.Duration) *scrapeDelayTransport {
+	host := strings.TrimPrefix(twitchemotesBaseURL, "https://")
+	return &scrapeDelayTransport{
+		base:    base,
+		host:    host,
+		limiter: &rateLimitTransport{base: base, interval: delay},
+	}
+}
+
+func (transport *scrapeDelayTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if strings.EqualFold(strings.TrimPrefix(request.URL.Hostname(), "www."), transport.host) {
+		return transport.limiter.RoundTrip(request)
+	}
+	return transport.base.RoundTrip(request)
+}
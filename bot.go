@@ -0,0 +1,454 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	discordAPIBaseURL            = "https://discord.com/api/v10"
+	discordRequestTimeout        = 60 * time.Second
+	discordUploadLimit           = 10 << 20
+	discordEmojiMaxBytes         = 256 << 10
+	discordMaxInteractionBytes   = 1 << 20
+	discordManageExpressions     = 1 << 30
+	discordInteractionPing       = 1
+	discordInteractionCommand    = 2
+	discordResponsePong          = 1
+	discordResponseMessage       = 4
+	discordResponseDeferred      = 5
+	discordMessageEphemeral      = 1 << 6
+	discordOptionString          = 3
+	discordOptionBoolean         = 5
+	discordErrorMaxEmojis        = 30008
+	botCommandName               = "emotes"
+	botChannelOption             = "channel"
+	botUploadOption              = "upload"
+	defaultBotListenAddress      = ":8081"
+	discordInteractionsPath      = "/interactions"
+	discordSignatureHeader       = "X-Signature-Ed25519"
+	discordSignatureTimestampKey = "X-Signature-Timestamp"
+)
+
+type botOptions struct {
+	Token  string
+	Listen string
+}
+
+type discordApplication struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	VerifyKey string `json:"verify_key"`
+}
+
+type discordCommandOption struct {
+	Type        int    `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+type discordCommand struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Options     []discordCommandOption `json:"options"`
+}
+
+type discordInteraction struct {
+	Type    int    `json:"type"`
+	Token   string `json:"token"`
+	GuildID string `json:"guild_id"`
+	Member  *struct {
+		Permissions string `json:"permissions"`
+	} `json:"member"`
+	Data struct {
+		Name    string `json:"name"`
+		Options []struct {
+			Name  string `json:"name"`
+			Value any    `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+}
+
+type discordInteractionResponse struct {
+	Type int                 `json:"type"`
+	Data *discordMessageEdit `json:"data,omitempty"`
+}
+
+type discordMessageEdit struct {
+	Content     string              `json:"content"`
+	Flags       int                 `json:"flags,omitempty"`
+	Attachments []discordAttachment `json:"attachments,omitempty"`
+}
+
+type discordAttachment struct {
+	ID       int    `json:"id"`
+	Filename string `json:"filename"`
+}
+
+type discordAPIError struct {
+	Status  int
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (err *discordAPIError) Error() string {
+	if err.Message == "" {
+		return fmt.Sprintf("Discord API returned status %d", err.Status)
+	}
+	return fmt.Sprintf("Discord API returned status %d: %s", err.Status, err.Message)
+}
+
+type discordBot struct {
+	httpClient  *http.Client
+	apiClient   *http.Client
+	token       string
+	application discordApplication
+	publicKey   ed25519.PublicKey
+	options     downloadOptions
+	slots       chan struct{}
+	outputLock  sync.Mutex
+}
+
+func (bot *discordBot) request(method string, path string, body io.Reader, contentType string, result any) error {
+	request, err := http.NewRequest(method, discordAPIBaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bot "+bot.token)
+	request.Header.Set("User-Agent", "DiscordBot (https://github.com/odesaur/twe-dlp, 1)")
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	response, err := bot.apiClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		apiErr := &discordAPIError{Status: response.StatusCode}
+		json.NewDecoder(io.LimitReader(response.Body, 64<<10)).Decode(apiErr)
+		return apiErr
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+func (bot *discordBot) requestJSON(method string, path string, body any, result any) error {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return bot.request(method, path, bytes.NewReader(bodyBytes), "application/json", result)
+}
+
+func (bot *discordBot) registerCommands() error {
+	commands := []discordCommand{{
+		Name:        botCommandName,
+		Description: "Archive a channel's emotes",
+		Options: []discordCommandOption{
+			{Type: discordOptionString, Name: botChannelOption, Description: "Channel name, URL or provider:channel", Required: true},
+			{Type: discordOptionBoolean, Name: botUploadOption, Description: "Upload the emotes to this server instead of replying with a ZIP"},
+		},
+	}}
+	return bot.requestJSON(http.MethodPut, fmt.Sprintf("/applications/%s/commands", bot.application.ID), commands, nil)
+}
+
+func verifyDiscordSignature(publicKey ed25519.PublicKey, signature string, timestamp string, body []byte) bool {
+	signatureBytes, err := hex.DecodeString(signature)
+	if err != nil || len(signatureBytes) != ed25519.SignatureSize || timestamp == "" {
+		return false
+	}
+	return ed25519.Verify(publicKey, append([]byte(timestamp), body...), signatureBytes)
+}
+
+func (interaction discordInteraction) canManageExpressions() bool {
+	if interaction.Member == nil {
+		return false
+	}
+	permissions, err := strconv.ParseUint(interaction.Member.Permissions, 10, 64)
+	return err == nil && permissions&discordManageExpressions != 0
+}
+
+func (bot *discordBot) handleInteraction(writer http.ResponseWriter, request *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(request.Body, discordMaxInteractionBytes))
+	if err != nil {
+		writeJSONError(writer, http.StatusBadRequest, err)
+		return
+	}
+	if !verifyDiscordSignature(bot.publicKey, request.Header.Get(discordSignatureHeader), request.Header.Get(discordSignatureTimestampKey), body) {
+		writeJSONError(writer, http.StatusUnauthorized, errors.New("invalid request signature"))
+		return
+	}
+	var interaction discordInteraction
+	if err := json.Unmarshal(body, &interaction); err != nil {
+		writeJSONError(writer, http.StatusBadRequest, err)
+		return
+	}
+
+	switch {
+	case interaction.Type == discordInteractionPing:
+		writeJSON(writer, http.StatusOK, discordInteractionResponse{Type: discordResponsePong})
+		return
+	case interaction.Type != discordInteractionCommand || interaction.Data.Name != botCommandName:
+		writeJSONError(writer, http.StatusBadRequest, errors.New("unknown interaction"))
+		return
+	}
+
+	var channelInput string
+	var upload bool
+	for _, option := range interaction.Data.Options {
+		switch option.Name {
+		case botChannelOption:
+			channelInput, _ = option.Value.(string)
+		case botUploadOption:
+			upload, _ = option.Value.(bool)
+		}
+	}
+	channelInput = strings.TrimSpace(channelInput)
+	reject := ""
+	switch {
+	case channelInput == "":
+		reject = "Tell me which channel to archive."
+	case upload && interaction.GuildID == "":
+		reject = "Emotes can only be uploaded inside a server."
+	case upload && !interaction.canManageExpressions():
+		reject = "Uploading emotes needs the Manage Expressions permission."
+	}
+	if reject != "" {
+		writeJSON(writer, http.StatusOK, discordInteractionResponse{Type: discordResponseMessage, Data: &discordMessageEdit{Content: reject, Flags: discordMessageEphemeral}})
+		return
+	}
+
+	writeJSON(writer, http.StatusOK, discordInteractionResponse{Type: discordResponseDeferred})
+	go bot.run(interaction, channelInput, upload)
+}
+
+func (bot *discordBot) run(interaction discordInteraction, channelInput string, upload bool) {
+	bot.slots <- struct{}{}
+	defer func() { <-bot.slots }()
+
+	options := bot.options
+	logFunc := func(line string) {
+		bot.outputLock.Lock()
+		fmt.Printf("%s[%s] %s\n", logTimestamp(options.Timestamps), channelInput, line)
+		bot.outputLock.Unlock()
+		options.Log.write(fmt.Sprintf("[%s] %s", channelInput, line))
+	}
+
+	outputRoot, err := downloadChannelInput(bot.httpClient, channelInput, options, logFunc)
+	if err != nil {
+		logFunc(fmt.Sprintf("Error: %v", err))
+		bot.reply(interaction, fmt.Sprintf("Could not archive %s: %v", channelInput, err), "", nil, logFunc)
+		return
+	}
+	if upload {
+		bot.reply(interaction, bot.uploadGuildEmojis(interaction.GuildID, outputRoot, logFunc), "", nil, logFunc)
+		return
+	}
+
+	var archive bytes.Buffer
+	if err := writeDirectoryZip(&archive, outputRoot); err != nil {
+		bot.reply(interaction, fmt.Sprintf("Archived %s, but could not build the ZIP: %v", channelInput, err), "", nil, logFunc)
+		return
+	}
+	if archive.Len() > discordUploadLimit {
+		bot.reply(interaction, fmt.Sprintf("The archive of %s is %s, over Discord's %s upload limit; it was saved on the bot's host in %s.", channelInput, formatByteSize(int64(archive.Len())), formatByteSize(discordUploadLimit), filepath.Base(outputRoot)), "", nil, logFunc)
+		return
+	}
+	fileName := filepath.Base(outputRoot) + ".zip"
+	bot.reply(interaction, fmt.Sprintf("Emotes of %s:", channelInput), fileName, archive.Bytes(), logFunc)
+}
+
+func (bot *discordBot) reply(interaction discordInteraction, content string, fileName string, file []byte, logFunc func(string)) {
+	path := fmt.Sprintf("/webhooks/%s/%s/messages/@original", bot.application.ID, interaction.Token)
+	message := discordMessageEdit{Content: content}
+	var err error
+	if file == nil {
+		err = bot.requestJSON(http.MethodPatch, path, message, nil)
+	} else {
+		message.Attachments = []discordAttachment{{ID: 0, Filename: fileName}}
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		payload, _ := json.Marshal(message)
+		payloadHeader := textproto.MIMEHeader{}
+		payloadHeader.Set("Content-Disposition", `form-data; name="payload_json"`)
+		payloadHeader.Set("Content-Type", "application/json")
+		if part, partErr := form.CreatePart(payloadHeader); partErr == nil {
+			part.Write(payload)
+		}
+		if part, partErr := form.CreateFormFile("files[0]", fileName); partErr == nil {
+			part.Write(file)
+		}
+		form.Close()
+		err = bot.request(http.MethodPatch, path, &body, form.FormDataContentType(), nil)
+	}
+	if err != nil {
+		logFunc(fmt.Sprintf("[error] cannot reply on Discord: %v", err))
+	}
+}
+
+func discordEmojiName(code string) string {
+	var builder strings.Builder
+	for _, character := range code {
+		if character < 128 && (character == '_' || character >= '0' && character <= '9' || character >= 'a' && character <= 'z' || character >= 'A' && character <= 'Z') {
+			builder.WriteRune(character)
+		}
+	}
+	name := builder.String()
+	for len(name) < 2 {
+		name += "_"
+	}
+	return name[:min(len(name), 32)]
+}
+
+func discordEmojiFile(emote manifestEmote) (manifestFile, string, bool) {
+	for index := len(emote.Files) - 1; index >= 0; index-- {
+		file := emote.Files[index]
+		contentType := file.ContentType
+		if contentType == "" {
+			contentType = mime.TypeByExtension(filepath.Ext(file.Path))
+		}
+		switch contentType {
+		case "image/png", "image/gif", "image/jpeg", "image/webp":
+		default:
+			continue
+		}
+		if file.Bytes > 0 && file.Bytes <= discordEmojiMaxBytes {
+			return file, contentType, true
+		}
+	}
+	return manifestFile{}, "", false
+}
+
+func (bot *discordBot) uploadGuildEmojis(guildID string, outputRoot string, logFunc func(string)) string {
+	manifest, err := loadManifest(localStorage{}, outputRoot)
+	if err != nil {
+		return fmt.Sprintf("Could not read the manifest: %v", err)
+	}
+	emotes := append([]manifestEmote(nil), manifest.Emotes...)
+	sort.Slice(emotes, func(left, right int) bool { return emotes[left].Code < emotes[right].Code })
+
+	uploaded, skipped := 0, 0
+	var failures []string
+	for _, emote := range emotes {
+		file, contentType, found := discordEmojiFile(emote)
+		if !found {
+			skipped++
+			continue
+		}
+		imageBytes, err := os.ReadFile(filepath.Join(outputRoot, filepath.FromSlash(file.Path)))
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", emote.Code, err))
+			continue
+		}
+		body := map[string]string{
+			"name":  discordEmojiName(emote.Code),
+			"image": "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(imageBytes),
+		}
+		err = bot.requestJSON(http.MethodPost, fmt.Sprintf("/guilds/%s/emojis", guildID), body, nil)
+		var apiErr *discordAPIError
+		if errors.As(err, &apiErr) && apiErr.Code == discordErrorMaxEmojis {
+			failures = append(failures, "the server has no emoji slots left")
+			break
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", emote.Code, err))
+			continue
+		}
+		uploaded++
+		logFunc(fmt.Sprintf("[discord] uploaded %s as :%s:", emote.Code, body["name"]))
+	}
+
+	summary := fmt.Sprintf("Uploaded %d of %d emotes from %s to this server", uploaded, len(emotes), manifest.ChannelName)
+	if skipped > 0 {
+		summary += fmt.Sprintf(", %d skipped (no image under %s)", skipped, formatByteSize(discordEmojiMaxBytes))
+	}
+	if len(failures) > 0 {
+		summary += fmt.Sprintf(", %d failed (%s)", len(failures), failures[0])
+	}
+	return summary + "."
+}
+
+func newBotFlagSet(options *downloadOptions, bot *botOptions) *flag.FlagSet {
+	flagSet := newCommandFlagSet("bot", options)
+	flagSet.StringVar(&bot.Token, "token", os.Getenv("DISCORD_BOT_TOKEN"), "Discord bot token")
+	flagSet.StringVar(&bot.Listen, "listen", defaultBotListenAddress, "address to receive Discord interactions on")
+	return flagSet
+}
+
+func runBotCommand(arguments []string) int {
+	var options downloadOptions
+	var botFlags botOptions
+
+	flagSet := newBotFlagSet(&options, &botFlags)
+	positional, err := parseCommandLine(flagSet, &options, arguments)
+	if err != nil {
+		return exitCodeForParseError(err)
+	}
+	if len(positional) != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: twe-dlp bot --token <bot token> [--listen <address>]")
+		return 2
+	}
+	token := strings.TrimPrefix(strings.TrimSpace(botFlags.Token), "Bot ")
+	if token == "" {
+		fmt.Fprintln(os.Stderr, "Error: bot needs a Discord bot token; pass --token or set DISCORD_BOT_TOKEN")
+		return 2
+	}
+	if !readableOutput(options) {
+		fmt.Fprintln(os.Stderr, "Error: bot reads the downloaded images back from disk, so it needs local output in the folders or flat layout (no --dest or --layout cas)")
+		return 2
+	}
+
+	bot := &discordBot{
+		httpClient: createHTTPClient(options),
+		apiClient:  &http.Client{Timeout: discordRequestTimeout},
+		token:      token,
+		options:    options,
+		slots:      make(chan struct{}, options.ChannelConcurrency),
+	}
+	if err := bot.request(http.MethodGet, "/oauth2/applications/@me", nil, "", &bot.application); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot look up the bot application: %v\n", err)
+		return 1
+	}
+	publicKey, err := hex.DecodeString(bot.application.VerifyKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		fmt.Fprintf(os.Stderr, "Error: the application %s has no valid public key\n", bot.application.Name)
+		return 1
+	}
+	bot.publicKey = publicKey
+	if err := bot.registerCommands(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot register the /%s command: %v\n", botCommandName, err)
+		return 1
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+discordInteractionsPath, bot.handleInteraction)
+	fmt.Printf("Running as %s; listening on %s\n", bot.application.Name, botFlags.Listen)
+	fmt.Printf("Set the application's Interactions Endpoint URL to https://<this host>%s\n", discordInteractionsPath)
+	if err := http.ListenAndServe(botFlags.Listen, mux); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
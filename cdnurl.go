@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var twitchCDNURLPattern = regexp.MustCompile(`^(?:https?://)?static-cdn\.jtvnw\.net/emoticons/v[12]/([^/?#]+)`)
+
+type twitchCDNProvider struct {
+	formats []string
+}
+
+func parseTwitchCDNURL(input string) (string, bool) {
+	match := twitchCDNURLPattern.FindStringSubmatch(strings.TrimSpace(input))
+	if len(match) != 2 || !twitchEmoteIDPattern.MatchString(match[1]) {
+		return "", false
+	}
+	return match[1], true
+}
+
+func (*twitchCDNProvider) Name() string {
+	return defaultProviderName
+}
+
+func (provider *twitchCDNProvider) ResolveChannelID(httpClient *http.Client, emoteID string) (string, error) {
+	provider.formats = []string{"static"}
+	response, err := httpClient.Get(fmt.Sprintf("%s/%s/animated/dark/1.0", twitchEmoteCDNBaseURL, emoteID))
+	if err != nil {
+		return "", err
+	}
+	io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+	if response.StatusCode == http.StatusOK {
+		provider.formats = append(provider.formats, "animated")
+	}
+	return emoteID, nil
+}
+
+func (provider *twitchCDNProvider) FetchChannel(httpClient *http.Client, emoteID string) (*ChannelData, error) {
+	emoteData := EmoteData{
+		BaseURL:    fmt.Sprintf("%s/%s", twitchEmoteCDNBaseURL, emoteID),
+		FormatType: provider.formats[len(provider.formats)-1],
+		EmoteCode:  emoteID,
+	}
+	if owner, err := lookupTwitchEmoteOwner(httpClient, emoteID); err == nil && owner.Code != "" {
+		emoteData.EmoteCode = owner.Code
+	}
+	return &ChannelData{
+		ID:          "emotes",
+		DisplayName: "emotes",
+		Emotes:      map[string]EmoteData{emoteID: emoteData},
+	}, nil
+}
+
+func (provider *twitchCDNProvider) Sizes() []string {
+	formats := provider.formats
+	if len(formats) == 0 {
+		formats = []string{"static"}
+	}
+	sizes := make([]string, 0, len(formats)*len(emoteSizeList)*2)
+	for _, formatType := range formats {
+		for _, theme := range []string{"light", "dark"} {
+			for _, sizeValue := range emoteSizeList {
+				sizes = append(sizes, strings.Join([]string{formatType, theme, sizeValue}, "_"))
+			}
+		}
+	}
+	return sizes
+}
+
+func (*twitchCDNProvider) ImageURL(emoteData EmoteData, sizeValue string) string {
+	return emoteData.BaseURL + "/" + strings.ReplaceAll(sizeValue, "_", "/")
+}
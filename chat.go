@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var chatterinoLinePattern = regexp.MustCompile(`^\[[0-9:]+\]\s+[^\s:]+:\s?(.*)$`)
+
+type chatEmoteUsage struct {
+	EmoteCodes map[string]string
+	Words      map[string]int
+	Channels   []string
+}
+
+type chatExport struct {
+	Streamer struct {
+		Name string `json:"name"`
+	} `json:"streamer"`
+	Comments []struct {
+		Message struct {
+			Body      string `json:"body"`
+			Fragments []struct {
+				Text     string `json:"text"`
+				Emoticon *struct {
+					EmoticonID string `json:"emoticon_id"`
+				} `json:"emoticon"`
+			} `json:"fragments"`
+		} `json:"message"`
+	} `json:"comments"`
+}
+
+func newChatEmoteUsage() *chatEmoteUsage {
+	return &chatEmoteUsage{
+		EmoteCodes: make(map[string]string),
+		Words:      make(map[string]int),
+	}
+}
+
+func (usage *chatEmoteUsage) addChannel(channelName string) {
+	channelName = strings.ToLower(strings.TrimSpace(channelName))
+	if channelName == "" {
+		return
+	}
+	for _, existing := range usage.Channels {
+		if existing == channelName {
+			return
+		}
+	}
+	usage.Channels = append(usage.Channels, channelName)
+}
+
+func (usage *chatEmoteUsage) addWords(text string) {
+	for _, word := range strings.Fields(text) {
+		usage.Words[word]++
+	}
+}
+
+func parseChatLog(logPath string) (*chatEmoteUsage, error) {
+	logBytes, err := os.ReadFile(logPath)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := newChatEmoteUsage()
+	if trimmed := bytes.TrimSpace(logBytes); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := parseChatExport(trimmed, usage); err != nil {
+			return nil, fmt.Errorf("cannot parse chat export %s: %w", logPath, err)
+		}
+		return usage, nil
+	}
+
+	logDirectory := filepath.Base(filepath.Dir(logPath))
+	if strings.HasPrefix(filepath.Base(logPath), logDirectory+"-") {
+		usage.addChannel(logDirectory)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(logBytes))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, "@") {
+			parseIRCLine(line, usage)
+			continue
+		}
+		if match := chatterinoLinePattern.FindStringSubmatch(line); len(match) == 2 {
+			usage.addWords(match[1])
+			continue
+		}
+		usage.addWords(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
+
+func parseChatExport(exportBytes []byte, usage *chatEmoteUsage) error {
+	var export chatExport
+	if err := json.Unmarshal(exportBytes, &export); err != nil {
+		return err
+	}
+
+	usage.addChannel(export.Streamer.Name)
+	for _, comment := range export.Comments {
+		usage.addWords(comment.Message.Body)
+		for _, fragment := range comment.Message.Fragments {
+			if fragment.Emoticon == nil || fragment.Emoticon.EmoticonID == "" {
+				continue
+			}
+			usage.EmoteCodes[fragment.Emoticon.EmoticonID] = strings.TrimSpace(fragment.Text)
+		}
+	}
+	return nil
+}
+
+func parseIRCLine(line string, usage *chatEmoteUsage) {
+	tagText, remainder, hasRemainder := strings.Cut(line[1:], " ")
+	if !hasRemainder {
+		return
+	}
+
+	_, command, hasCommand := strings.Cut(remainder, " PRIVMSG #")
+	if !hasCommand {
+		return
+	}
+	channelName, message, _ := strings.Cut(command, " :")
+	usage.addChannel(channelName)
+	usage.addWords(message)
+
+	emotesTag := ""
+	for _, tag := range strings.Split(tagText, ";") {
+		key, value, _ := strings.Cut(tag, "=")
+		if key == "emotes" {
+			emotesTag = value
+			break
+		}
+	}
+	if emotesTag == "" {
+		return
+	}
+
+	messageRunes := []rune(message)
+	for _, emoteEntry := range strings.Split(emotesTag, "/") {
+		emoteIdentifier, positions, hasPositions := strings.Cut(emoteEntry, ":")
+		if !hasPositions || emoteIdentifier == "" {
+			continue
+		}
+		firstRange, _, _ := strings.Cut(positions, ",")
+		startText, endText, _ := strings.Cut(firstRange, "-")
+		start, startError := strconv.Atoi(startText)
+		end, endError := strconv.Atoi(endText)
+		emoteCode := emoteIdentifier
+		if startError == nil && endError == nil && start >= 0 && start <= end && end < len(messageRunes) {
+			emoteCode = string(messageRunes[start : end+1])
+		}
+		usage.EmoteCodes[emoteIdentifier] = emoteCode
+	}
+}
+
+func newFromChatFlagSet(options *downloadOptions, channelInputs *stringListFlag) *flag.FlagSet {
+	flagSet := newCommandFlagSet("from-chat", options)
+	flagSet.Var(channelInputs, "channel", "channel whose emotes may appear in the log (repeatable, accepts provider prefixes)")
+	return flagSet
+}
+
+func runFromChatCommand(arguments []string) int {
+	var options downloadOptions
+	var channelInputs stringListFlag
+
+	flagSet := newFromChatFlagSet(&options, &channelInputs)
+	positional, err := parseCommandLine(flagSet, &options, arguments)
+	if err != nil {
+		return exitCodeForParseError(err)
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: twe-dlp from-chat [options] <logfile>")
+		return 2
+	}
+	httpClient := createHTTPClient(options)
+
+	logPath := positional[0]
+	usage, err := parseChatLog(logPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading chat log: %v\n", err)
+		return 1
+	}
+
+	logFunc := func(line string) {
+		fmt.Println(logTimestamp(options.Timestamps) + line)
+		options.Log.write(line)
+	}
+
+	logName := strings.TrimSuffix(filepath.Base(logPath), filepath.Ext(logPath))
+	options.OutputDir = makeSafeName(logName, options.KeepUnicode) + "_emotes"
+
+	channelInputs = append(channelInputs, usage.Channels...)
+	if len(channelInputs) == 0 && len(usage.EmoteCodes) == 0 {
+		fmt.Fprintln(os.Stderr, "No channels or emote IDs found in the log; pass --channel to choose which channels to match against.")
+		return 1
+	}
+
+	logFunc(fmt.Sprintf("Found %d distinct words and %d emote IDs in %s", len(usage.Words), len(usage.EmoteCodes), logPath))
+
+	remainingEmotes := make(map[string]string, len(usage.EmoteCodes))
+	for emoteIdentifier, emoteCode := range usage.EmoteCodes {
+		remainingEmotes[emoteIdentifier] = emoteCode
+	}
+
+	exitCode := 0
+	seenChannels := make(map[string]bool)
+	for _, channelInput := range channelInputs {
+		provider, providerIdentifier, err := selectProvider(channelInput, options.Provider)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exitCode = 1
+			continue
+		}
+		channelKey := provider.Name() + ":" + strings.ToLower(providerIdentifier)
+		if seenChannels[channelKey] {
+			continue
+		}
+		seenChannels[channelKey] = true
+
+		channelID, err := provider.ResolveChannelID(httpClient, providerIdentifier)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error resolving channel %s: %v\n", channelInput, err)
+			exitCode = 1
+			continue
+		}
+		channel, err := provider.FetchChannel(httpClient, channelID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error fetching channel %s: %v\n", channelInput, err)
+			exitCode = 1
+			continue
+		}
+
+		usedEmotes := make(map[string]EmoteData)
+		for emoteIdentifier, emoteData := range channel.Emotes {
+			_, usedByID := usage.EmoteCodes[emoteIdentifier]
+			if usedByID || usage.Words[emoteData.EmoteCode] > 0 {
+				usedEmotes[emoteIdentifier] = emoteData
+				delete(remainingEmotes, emoteIdentifier)
+			}
+		}
+		if len(usedEmotes) == 0 {
+			logFunc(fmt.Sprintf("No emotes from %s appear in the log", channelInput))
+			continue
+		}
+
+		channel.Emotes = usedEmotes
+		if err := downloadChannelData(httpClient, provider, channel, options, logFunc); err != nil {
+			fmt.Fprintf(os.Stderr, "Error downloading emotes for %s: %v\n", channelInput, err)
+			exitCode = 1
+		}
+	}
+
+	if len(remainingEmotes) > 0 {
+		logFunc(fmt.Sprintf("Downloading %d emotes not owned by any listed channel", len(remainingEmotes)))
+
+		otherChannel := &ChannelData{
+			ID:          "other",
+			DisplayName: "other",
+			Emotes:      make(map[string]EmoteData, len(remainingEmotes)),
+		}
+		for emoteIdentifier, emoteCode := range remainingEmotes {
+			otherChannel.Emotes[emoteIdentifier] = EmoteData{
+				BaseURL:    fmt.Sprintf("%s/%s/default", twitchEmoteCDNBaseURL, emoteIdentifier),
+				FormatType: "default",
+				EmoteCode:  emoteCode,
+			}
+		}
+		if err := downloadChannelData(httpClient, twitchProvider{}, otherChannel, options, logFunc); err != nil {
+			fmt.Fprintf(os.Stderr, "Error downloading emotes: %v\n", err)
+			exitCode = 1
+		}
+	}
+
+	return exitCode
+}
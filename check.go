@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+type imageCheck struct {
+	EmoteID       string
+	Code          string
+	Size          string
+	URL           string
+	Status        string
+	Live          bool
+	ContentType   string
+	ContentLength int64
+}
+
+func headImage(httpClient *http.Client, imageURL string) (*http.Response, error) {
+	request, err := http.NewRequest("HEAD", imageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+	return response, nil
+}
+
+func checkImageURL(httpClient *http.Client, check imageCheck) imageCheck {
+	check.ContentLength = -1
+	response, err := headImage(httpClient, check.URL)
+	if err != nil {
+		check.Status = err.Error()
+		return check
+	}
+	check.Status = response.Status
+	check.Live = response.StatusCode == http.StatusOK
+	check.ContentType = response.Header.Get("Content-Type")
+	check.ContentLength = response.ContentLength
+	return check
+}
+
+func checkChannelImages(httpClient *http.Client, provider emoteProvider, channel *ChannelData, options downloadOptions, logFunc func(string)) error {
+	emoteMap := filterEmotes(channel.Emotes, options)
+	logFunc(fmt.Sprintf("Channel ID: %s", channel.ID))
+	if channel.DisplayName != "" {
+		logFunc(fmt.Sprintf("Channel Name: %s", channel.DisplayName))
+	}
+	sizes := downloadSizes(provider, options)
+	logFunc(fmt.Sprintf("Checking %d emotes in %d sizes...", len(emoteMap), len(sizes)))
+
+	pending := make(chan imageCheck)
+	checks := make([]imageCheck, 0, len(emoteMap)*len(sizes))
+	var checksLock sync.Mutex
+	var workers sync.WaitGroup
+	for worker := 0; worker < sizeEstimateWorkers; worker++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for check := range pending {
+				check = checkImageURL(httpClient, check)
+				checksLock.Lock()
+				checks = append(checks, check)
+				checksLock.Unlock()
+			}
+		}()
+	}
+	for emoteIdentifier, emoteData := range emoteMap {
+		for _, sizeValue := range sizes {
+			pending <- imageCheck{
+				EmoteID: emoteIdentifier,
+				Code:    emoteData.EmoteCode,
+				Size:    sizeValue,
+				URL:     provider.ImageURL(emoteData, sizeValue),
+			}
+		}
+	}
+	close(pending)
+	workers.Wait()
+
+	sizeOrder := make(map[string]int)
+	for index, sizeValue := range sizes {
+		sizeOrder[sizeValue] = index
+	}
+	sort.Slice(checks, func(left, right int) bool {
+		if checks[left].EmoteID != checks[right].EmoteID {
+			return checks[left].EmoteID < checks[right].EmoteID
+		}
+		return sizeOrder[checks[left].Size] < sizeOrder[checks[right].Size]
+	})
+
+	liveCount, unknownCount := 0, 0
+	var totalBytes int64
+	for _, check := range checks {
+		if !check.Live {
+			logFunc(fmt.Sprintf("[dead] %s %s (%s) %s", check.Code, check.Size, check.Status, check.URL))
+			continue
+		}
+		liveCount++
+		size := "unknown size"
+		if check.ContentLength >= 0 {
+			totalBytes += check.ContentLength
+			size = formatByteSize(check.ContentLength)
+		} else {
+			unknownCount++
+		}
+		logFunc(fmt.Sprintf("[live] %s %s %s, %s %s", check.Code, check.Size, check.ContentType, size, check.URL))
+	}
+
+	summary := fmt.Sprintf("%d of %d URLs live, %s total", liveCount, len(checks), formatByteSize(totalBytes))
+	if unknownCount > 0 {
+		summary += fmt.Sprintf(" (%d without a size)", unknownCount)
+	}
+	logFunc(summary)
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	checksumSHA256 = "sha256"
+
+	sha256SumsFileName = "SHA256SUMS"
+)
+
+var checksumAlgorithms = []string{checksumSHA256}
+
+var checksumPathEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`)
+
+func writeChecksums(store storage, outputRoot string, manifest *channelManifest) error {
+	checksums := make(map[string]string)
+	for _, emote := range manifest.Emotes {
+		for _, file := range emote.Files {
+			if file.SHA256 != "" {
+				checksums[file.Path] = file.SHA256
+			}
+		}
+		if emote.Largest != "" && len(emote.Files) > 0 && emote.Files[len(emote.Files)-1].SHA256 != "" {
+			checksums[emote.Largest] = emote.Files[len(emote.Files)-1].SHA256
+		}
+	}
+	filePaths := make([]string, 0, len(checksums))
+	for filePath := range checksums {
+		filePaths = append(filePaths, filePath)
+	}
+	sort.Strings(filePaths)
+
+	var buffer bytes.Buffer
+	for _, filePath := range filePaths {
+		escaped := checksumPathEscaper.Replace(filePath)
+		if escaped != filePath {
+			buffer.WriteString(`\`)
+		}
+		fmt.Fprintf(&buffer, "%s  %s\n", checksums[filePath], escaped)
+	}
+	return store.WriteFile(filepath.Join(outputRoot, sha256SumsFileName), buffer.Bytes())
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	completionArgumentsNone     = ""
+	completionArgumentsChannels = "channels"
+	completionArgumentsFiles    = "files"
+	completionArgumentsFav      = "fav"
+	completionArgumentsShells   = "shells"
+	completionArgumentsExport   = "export"
+	completionArgumentsFolders  = "folders"
+	completionArgumentsAuth     = "auth"
+)
+
+var completionShells = []string{"bash", "zsh", "fish"}
+
+var subcommandFlagSets = map[string]func() *flag.FlagSet{
+	"from-chat":  func() *flag.FlagSet { return newFromChatFlagSet(&downloadOptions{}, &stringListFlag{}) },
+	"emote":      func() *flag.FlagSet { return newEmoteFlagSet(&downloadOptions{}, &stringListFlag{}) },
+	"fav":        nil,
+	"serve":      func() *flag.FlagSet { return newServeFlagSet(&downloadOptions{}, &serveOptions{}) },
+	"watch":      func() *flag.FlagSet { return newWatchFlagSet(&downloadOptions{}, &watchOptions{}) },
+	"doctor":     func() *flag.FlagSet { return newCommandFlagSet("doctor", &downloadOptions{}) },
+	"completion": nil,
+	"auth":       nil,
+	"follows":    func() *flag.FlagSet { return newFollowsFlagSet(&downloadOptions{}, &followsOptions{}) },
+	"top":        func() *flag.FlagSet { return newTopFlagSet(&downloadOptions{}, &topOptions{}) },
+	"whois": func() *flag.FlagSet {
+		var download, jsonOutput bool
+		return newWhoisFlagSet(&downloadOptions{}, &download, &jsonOutput)
+	},
+	"search": func() *flag.FlagSet {
+		var sourceNames []string
+		var limit int
+		var selection string
+		var pick, jsonOutput bool
+		return newSearchFlagSet(&downloadOptions{}, &sourceNames, &limit, &selection, &pick, &jsonOutput)
+	},
+	"stats": func() *flag.FlagSet {
+		var jsonOutput bool
+		return newStatsFlagSet(&jsonOutput)
+	},
+	"gif": func() *flag.FlagSet {
+		var limit string
+		return newGIFFlagSet(&limit)
+	},
+	"resolve": func() *flag.FlagSet {
+		var jsonOutput bool
+		return newResolveFlagSet(&downloadOptions{}, &jsonOutput)
+	},
+	"export": func() *flag.FlagSet {
+		var outputDir string
+		var durable bool
+		return newExportFlagSet(&outputDir, &durable)
+	},
+	"verify": func() *flag.FlagSet {
+		var repair bool
+		return newVerifyFlagSet(&downloadOptions{}, &repair)
+	},
+	"retry-failed": func() *flag.FlagSet { return newCommandFlagSet("retry-failed", &downloadOptions{}) },
+	"bot":          func() *flag.FlagSet { return newBotFlagSet(&downloadOptions{}, &botOptions{}) },
+	"diff": func() *flag.FlagSet {
+		var jsonOutput bool
+		return newDiffFlagSet(&jsonOutput)
+	},
+}
+
+var completionArguments = map[string]string{
+	"":             completionArgumentsChannels,
+	"from-chat":    completionArgumentsFiles,
+	"fav":          completionArgumentsFav,
+	"watch":        completionArgumentsChannels,
+	"doctor":       completionArgumentsChannels,
+	"completion":   completionArgumentsShells,
+	"resolve":      completionArgumentsChannels,
+	"export":       completionArgumentsExport,
+	"verify":       completionArgumentsFolders,
+	"retry-failed": completionArgumentsFolders,
+	"diff":         completionArgumentsFiles,
+	"bot":          completionArgumentsNone,
+	"auth":         completionArgumentsAuth,
+	"gif":          completionArgumentsFolders,
+	"follows":      completionArgumentsNone,
+	"top":          completionArgumentsNone,
+	"search":       completionArgumentsNone,
+	"whois":        completionArgumentsNone,
+}
+
+var completionFlagChoices = map[string][]string{
+	"provider":         providerNames(),
+	"theme":            themeNames(),
+	"preview":          previewModes,
+	"layout":           layouts,
+	"manifest-format":  manifestFormats,
+	"convert-animated": animationFormats,
+	"largest":          largestModes,
+	"checksums":        checksumAlgorithms,
+	"progress":         progressModes,
+	"webhook-format":   {webhookFormatJSON, webhookFormatDiscord},
+	"source":           emoteSearchSourceNames(),
+}
+
+var completionDirectoryFlags = map[string]bool{
+	"cache-dir": true,
+	"output":    true,
+}
+
+type completionFlag struct {
+	Name       string
+	Usage      string
+	TakesValue bool
+	Repeatable bool
+}
+
+type completionCommand struct {
+	Name      string
+	Flags     []completionFlag
+	Arguments string
+}
+
+func completionFlags(flagSet *flag.FlagSet) []completionFlag {
+	if flagSet == nil {
+		return nil
+	}
+	flags := make([]completionFlag, 0)
+	flagSet.VisitAll(func(definition *flag.Flag) {
+		boolValue, isBool := definition.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, completionFlag{
+			Name:       definition.Name,
+			Usage:      definition.Usage,
+			TakesValue: !isBool || !boolValue.IsBoolFlag(),
+			Repeatable: strings.Contains(definition.Usage, "repeatable"),
+		})
+	})
+	return flags
+}
+
+func completionCommands() []completionCommand {
+	commands := []completionCommand{{
+		Flags:     completionFlags(newCommandFlagSet("twe-dlp", &downloadOptions{})),
+		Arguments: completionArguments[""],
+	}}
+	for _, name := range sortedKeys(subcommandFlagSets) {
+		var flagSet *flag.FlagSet
+		if newFlagSet := subcommandFlagSets[name]; newFlagSet != nil {
+			flagSet = newFlagSet()
+		}
+		commands = append(commands, completionCommand{Name: name, Flags: completionFlags(flagSet), Arguments: completionArguments[name]})
+	}
+	return commands
+}
+
+func bashCompletion(commands []completionCommand) string {
+	var script strings.Builder
+	subcommandNames := sortedKeys(subcommandFlagSets)
+	valueFlags := make(map[string]bool)
+	for _, command := range commands {
+		for _, commandFlag := range command.Flags {
+			if commandFlag.TakesValue {
+				valueFlags[commandFlag.Name] = true
+			}
+		}
+	}
+
+	script.WriteString("# bash completion for twe-dlp\n")
+	script.WriteString("_twe_dlp() {\n")
+	script.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	script.WriteString("    local command=\"\" flags=\"\"\n")
+	script.WriteString("    if [[ ${COMP_CWORD} -gt 1 ]]; then\n")
+	script.WriteString("        case \"${COMP_WORDS[1]}\" in\n")
+	fmt.Fprintf(&script, "            %s) command=\"${COMP_WORDS[1]}\" ;;\n", strings.Join(subcommandNames, "|"))
+	script.WriteString("        esac\n    fi\n\n")
+
+	script.WriteString("    case \"$prev\" in\n")
+	for _, name := range sortedKeys(valueFlags) {
+		switch {
+		case completionFlagChoices[name] != nil:
+			fmt.Fprintf(&script, "        --%s|-%s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")); return ;;\n", name, name, strings.Join(completionFlagChoices[name], " "))
+		case completionDirectoryFlags[name]:
+			fmt.Fprintf(&script, "        --%s|-%s) COMPREPLY=($(compgen -d -- \"$cur\")); return ;;\n", name, name)
+		default:
+			fmt.Fprintf(&script, "        --%s|-%s) COMPREPLY=(); return ;;\n", name, name)
+		}
+	}
+	script.WriteString("    esac\n\n")
+
+	script.WriteString("    if [[ \"$cur\" == -* ]]; then\n        case \"$command\" in\n")
+	for _, command := range commands {
+		names := make([]string, 0, len(command.Flags))
+		for _, commandFlag := range command.Flags {
+			names = append(names, "--"+commandFlag.Name)
+		}
+		pattern := command.Name
+		if pattern == "" {
+			pattern = "\"\""
+		}
+		fmt.Fprintf(&script, "            %s) flags=\"%s\" ;;\n", pattern, strings.Join(names, " "))
+	}
+	script.WriteString("        esac\n        COMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))\n        return\n    fi\n\n")
+
+	script.WriteString("    case \"$command\" in\n")
+	for _, command := range commands {
+		pattern := command.Name
+		if pattern == "" {
+			pattern = "\"\""
+		}
+		switch command.Arguments {
+		case completionArgumentsChannels:
+			words := "$(twe-dlp fav list 2>/dev/null)"
+			if command.Name == "" {
+				words = strings.Join(subcommandNames, " ") + " " + words
+			}
+			fmt.Fprintf(&script, "        %s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")) ;;\n", pattern, words)
+		case completionArgumentsFiles:
+			fmt.Fprintf(&script, "        %s) COMPREPLY=($(compgen -f -- \"$cur\")) ;;\n", pattern)
+		case completionArgumentsFav:
+			fmt.Fprintf(&script, "        %s)\n", pattern)
+			script.WriteString("            if [[ ${COMP_CWORD} -eq 2 ]]; then\n")
+			script.WriteString("                COMPREPLY=($(compgen -W \"add remove list\" -- \"$cur\"))\n")
+			script.WriteString("            else\n")
+			script.WriteString("                COMPREPLY=($(compgen -W \"$(twe-dlp fav list 2>/dev/null)\" -- \"$cur\"))\n")
+			script.WriteString("            fi ;;\n")
+		case completionArgumentsShells:
+			fmt.Fprintf(&script, "        %s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")) ;;\n", pattern, strings.Join(completionShells, " "))
+		case completionArgumentsExport:
+			fmt.Fprintf(&script, "        %s)\n", pattern)
+			script.WriteString("            if [[ ${COMP_CWORD} -eq 2 ]]; then\n")
+			fmt.Fprintf(&script, "                COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(exportTargetNames(), " "))
+			script.WriteString("            else\n")
+			script.WriteString("                COMPREPLY=($(compgen -d -- \"$cur\"))\n")
+			script.WriteString("            fi ;;\n")
+		case completionArgumentsFolders:
+			fmt.Fprintf(&script, "        %s) COMPREPLY=($(compgen -d -- \"$cur\")) ;;\n", pattern)
+		case completionArgumentsAuth:
+			fmt.Fprintf(&script, "        %s)\n", pattern)
+			script.WriteString("            if [[ ${COMP_CWORD} -eq 2 ]]; then\n")
+			script.WriteString("                COMPREPLY=($(compgen -W \"login logout status\" -- \"$cur\"))\n")
+			script.WriteString("            else\n")
+			fmt.Fprintf(&script, "                COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(credentialProviderNames(), " "))
+			script.WriteString("            fi ;;\n")
+		}
+	}
+	script.WriteString("    esac\n}\n\ncomplete -F _twe_dlp twe-dlp\n")
+	return script.String()
+}
+
+func zshEscape(text string) string {
+	replacer := strings.NewReplacer("'", "'\\''", "[", "\\[", "]", "\\]", ":", "\\:")
+	return replacer.Replace(text)
+}
+
+func zshFlagSpec(commandFlag completionFlag) string {
+	spec := fmt.Sprintf("--%s[%s]", commandFlag.Name, zshEscape(commandFlag.Usage))
+	if commandFlag.Repeatable {
+		spec = "*" + spec
+	}
+	if commandFlag.TakesValue {
+		switch {
+		case completionFlagChoices[commandFlag.Name] != nil:
+			spec += fmt.Sprintf(":%s:(%s)", commandFlag.Name, strings.Join(completionFlagChoices[commandFlag.Name], " "))
+		case completionDirectoryFlags[commandFlag.Name]:
+			spec += fmt.Sprintf(":%s:_files -/", commandFlag.Name)
+		default:
+			spec += fmt.Sprintf(":%s: ", commandFlag.Name)
+		}
+	}
+	return "'" + spec + "'"
+}
+
+func zshCompletion(commands []completionCommand) string {
+	var script strings.Builder
+	script.WriteString("#compdef twe-dlp\n\n")
+	script.WriteString("_twe_dlp_favorites() {\n")
+	script.WriteString("    local -a favorites\n")
+	script.WriteString("    favorites=(${(f)\"$(twe-dlp fav list 2>/dev/null)\"})\n")
+	script.WriteString("    compadd -a favorites\n}\n\n")
+	script.WriteString("_twe_dlp_root_arguments() {\n")
+	fmt.Fprintf(&script, "    compadd -- %s\n", strings.Join(sortedKeys(subcommandFlagSets), " "))
+	script.WriteString("    _twe_dlp_favorites\n}\n\n")
+
+	script.WriteString("_twe_dlp() {\n    case ${words[2]} in\n")
+	var rootCommand completionCommand
+	for _, command := range commands {
+		if command.Name == "" {
+			rootCommand = command
+			continue
+		}
+		fmt.Fprintf(&script, "        %s)\n", command.Name)
+		script.WriteString("            shift words\n            (( CURRENT-- ))\n")
+		specs := make([]string, 0, len(command.Flags)+2)
+		for _, commandFlag := range command.Flags {
+			specs = append(specs, zshFlagSpec(commandFlag))
+		}
+		switch command.Arguments {
+		case completionArgumentsChannels:
+			specs = append(specs, "'*:channel:_twe_dlp_favorites'")
+		case completionArgumentsFiles:
+			specs = append(specs, "'*:file:_files'")
+		case completionArgumentsFav:
+			specs = append(specs, "'1:action:(add remove list)'", "'*:channel:_twe_dlp_favorites'")
+		case completionArgumentsShells:
+			specs = append(specs, fmt.Sprintf("'1:shell:(%s)'", strings.Join(completionShells, " ")))
+		case completionArgumentsExport:
+			specs = append(specs, fmt.Sprintf("'1:target:(%s)'", strings.Join(exportTargetNames(), " ")), "'*:channel folder:_files -/'")
+		case completionArgumentsFolders:
+			specs = append(specs, "'*:channel folder:_files -/'")
+		case completionArgumentsAuth:
+			specs = append(specs, "'1:action:(login logout status)'", fmt.Sprintf("'2:provider:(%s)'", strings.Join(credentialProviderNames(), " ")))
+		}
+		if len(specs) == 0 {
+			script.WriteString("            ;;\n")
+			continue
+		}
+		fmt.Fprintf(&script, "            _arguments -S \\\n                %s\n            ;;\n", strings.Join(specs, " \\\n                "))
+	}
+
+	specs := make([]string, 0, len(rootCommand.Flags)+1)
+	for _, commandFlag := range rootCommand.Flags {
+		specs = append(specs, zshFlagSpec(commandFlag))
+	}
+	specs = append(specs, "'*:channel or command:_twe_dlp_root_arguments'")
+	fmt.Fprintf(&script, "        *)\n            _arguments -S \\\n                %s\n            ;;\n", strings.Join(specs, " \\\n                "))
+	script.WriteString("    esac\n}\n\n_twe_dlp \"$@\"\n")
+	return script.String()
+}
+
+func fishQuote(text string) string {
+	return "'" + strings.NewReplacer("\\", "\\\\", "'", "\\'").Replace(text) + "'"
+}
+
+func fishCompletion(commands []completionCommand) string {
+	var script strings.Builder
+	subcommandNames := strings.Join(sortedKeys(subcommandFlagSets), " ")
+	rootCondition := fishQuote("not __fish_seen_subcommand_from " + subcommandNames)
+
+	script.WriteString("# fish completion for twe-dlp\n")
+	script.WriteString("complete -c twe-dlp -f\n")
+	fmt.Fprintf(&script, "complete -c twe-dlp -n %s -a %s\n", rootCondition, fishQuote(subcommandNames))
+
+	for _, command := range commands {
+		condition := rootCondition
+		if command.Name != "" {
+			condition = fishQuote("__fish_seen_subcommand_from " + command.Name)
+		}
+		for _, commandFlag := range command.Flags {
+			line := fmt.Sprintf("complete -c twe-dlp -n %s -l %s", condition, commandFlag.Name)
+			if commandFlag.TakesValue {
+				switch {
+				case completionFlagChoices[commandFlag.Name] != nil:
+					line += " -x -a " + fishQuote(strings.Join(completionFlagChoices[commandFlag.Name], " "))
+				case completionDirectoryFlags[commandFlag.Name]:
+					line += " -x -a '(__fish_complete_directories)'"
+				default:
+					line += " -x"
+				}
+			}
+			fmt.Fprintf(&script, "%s -d %s\n", line, fishQuote(commandFlag.Usage))
+		}
+
+		switch command.Arguments {
+		case completionArgumentsChannels:
+			fmt.Fprintf(&script, "complete -c twe-dlp -n %s -a '(twe-dlp fav list 2>/dev/null)' -d 'favorite channel'\n", condition)
+		case completionArgumentsFiles:
+			fmt.Fprintf(&script, "complete -c twe-dlp -n %s -F\n", condition)
+		case completionArgumentsFav:
+			fmt.Fprintf(&script, "complete -c twe-dlp -n %s -a 'add remove list'\n", condition)
+			fmt.Fprintf(&script, "complete -c twe-dlp -n %s -a '(twe-dlp fav list 2>/dev/null)' -d 'favorite channel'\n", condition)
+		case completionArgumentsShells:
+			fmt.Fprintf(&script, "complete -c twe-dlp -n %s -a %s\n", condition, fishQuote(strings.Join(completionShells, " ")))
+		case completionArgumentsExport:
+			fmt.Fprintf(&script, "complete -c twe-dlp -n %s -a %s\n", condition, fishQuote(strings.Join(exportTargetNames(), " ")))
+			fmt.Fprintf(&script, "complete -c twe-dlp -n %s -a '(__fish_complete_directories)'\n", condition)
+		case completionArgumentsFolders:
+			fmt.Fprintf(&script, "complete -c twe-dlp -n %s -a '(__fish_complete_directories)'\n", condition)
+		case completionArgumentsAuth:
+			fmt.Fprintf(&script, "complete -c twe-dlp -n %s -a 'login logout status'\n", condition)
+			fmt.Fprintf(&script, "complete -c twe-dlp -n %s -a %s\n", condition, fishQuote(strings.Join(credentialProviderNames(), " ")))
+		}
+	}
+	return script.String()
+}
+
+func runCompletionCommand(arguments []string) int {
+	if len(arguments) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: twe-dlp completion %s\n", strings.Join(completionShells, "|"))
+		return 2
+	}
+
+	commands := completionCommands()
+	switch arguments[0] {
+	case "bash":
+		fmt.Print(bashCompletion(commands))
+	case "zsh":
+		fmt.Print(zshCompletion(commands))
+	case "fish":
+		fmt.Print(fishCompletion(commands))
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown shell %q (available: %s)\n", arguments[0], strings.Join(completionShells, ", "))
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/x/term"
+)
+
+const (
+	credentialsFileName = "credentials.json"
+	keyringService      = "twe-dlp"
+
+	credentialStoreKeyring = "keyring"
+	credentialStoreFile    = "file"
+)
+
+// Twitch GQL requests carry the web client's Client-ID, which a third-party
+// token does not belong to, so only the Helix host gets the Twitch token.
+var providerCredentialHosts = map[string]map[string]string{
+	"twitch":  {"api.twitch.tv": "Bearer "},
+	"7tv":     {"7tv.io": "Bearer "},
+	"kick":    {"kick.com": "Bearer "},
+	"youtube": {"www.googleapis.com": "Bearer ", "youtube.googleapis.com": "Bearer "},
+}
+
+var credentialCache = struct {
+	lock   sync.Mutex
+	tokens map[string]string
+}{tokens: make(map[string]string)}
+
+type credentialTransport struct {
+	base http.RoundTripper
+}
+
+func keyringCommand(action string, providerName string) (*exec.Cmd, bool) {
+	var arguments []string
+	switch runtime.GOOS {
+	case "darwin":
+		switch action {
+		case "store":
+			arguments = []string{"security", "add-generic-password", "-U", "-s", keyringService, "-a", providerName, "-w"}
+		case "lookup":
+			arguments = []string{"security", "find-generic-password", "-s", keyringService, "-a", providerName, "-w"}
+		case "clear":
+			arguments = []string{"security", "delete-generic-password", "-s", keyringService, "-a", providerName}
+		}
+	case "windows":
+		return nil, false
+	default:
+		switch action {
+		case "store":
+			arguments = []string{"secret-tool", "store", "--label", keyringService + " " + providerName, "service", keyringService, "provider", providerName}
+		case "lookup":
+			arguments = []string{"secret-tool", "lookup", "service", keyringService, "provider", providerName}
+		case "clear":
+			arguments = []string{"secret-tool", "clear", "service", keyringService, "provider", providerName}
+		}
+	}
+	if _, err := exec.LookPath(arguments[0]); err != nil {
+		return nil, false
+	}
+	return exec.Command(arguments[0], arguments[1:]...), true
+}
+
+func storeKeyringToken(providerName string, token string) error {
+	command, available := keyringCommand("store", providerName)
+	if !available {
+		return errors.New("no keyring tool found")
+	}
+	if runtime.GOOS == "darwin" {
+		command.Args = append(command.Args, token)
+	} else {
+		command.Stdin = strings.NewReader(token)
+	}
+	if output, err := command.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func lookupKeyringToken(providerName string) string {
+	command, available := keyringCommand("lookup", providerName)
+	if !available {
+		return ""
+	}
+	output, err := command.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func clearKeyringToken(providerName string) bool {
+	command, available := keyringCommand("clear", providerName)
+	if !available {
+		return false
+	}
+	return command.Run() == nil
+}
+
+func credentialsFilePath() (string, error) {
+	directory, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(directory, credentialsFileName), nil
+}
+
+func loadCredentialsFile() map[string]string {
+	tokens := make(map[string]string)
+	filePath, err := credentialsFilePath()
+	if err != nil {
+		return tokens
+	}
+	fileBytes, err := os.ReadFile(filePath)
+	if err != nil {
+		return tokens
+	}
+	if err := json.Unmarshal(fileBytes, &tokens); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring %s: %v\n", credentialsFileName, err)
+	}
+	return tokens
+}
+
+func saveCredentialsFile(tokens map[string]string) error {
+	filePath, err := credentialsFilePath()
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+	fileBytes, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	temporaryFile, err := os.CreateTemp(filepath.Dir(filePath), "."+credentialsFileName+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = temporaryFile.Write(append(fileBytes, '\n'))
+	if closeError := temporaryFile.Close(); err == nil {
+		err = closeError
+	}
+	if err == nil {
+		err = os.Chmod(temporaryFile.Name(), 0o600)
+	}
+	if err != nil {
+		os.Remove(temporaryFile.Name())
+		return err
+	}
+	return commitFile(temporaryFile.Name(), filePath, false)
+}
+
+func lookupCredential(providerName string) string {
+	credentialCache.lock.Lock()
+	defer credentialCache.lock.Unlock()
+	if token, cached := credentialCache.tokens[providerName]; cached {
+		return token
+	}
+	token := lookupKeyringToken(providerName)
+	if token == "" {
+		token = loadCredentialsFile()[providerName]
+	}
+	credentialCache.tokens[providerName] = token
+	return token
+}
+
+func saveCredential(providerName string, token string) (string, error) {
+	tokens := loadCredentialsFile()
+	if keyringErr := storeKeyringToken(providerName, token); keyringErr == nil {
+		if _, stored := tokens[providerName]; stored {
+			delete(tokens, providerName)
+			if err := saveCredentialsFile(tokens); err != nil {
+				return credentialStoreKeyring, err
+			}
+		}
+		return credentialStoreKeyring, nil
+	}
+	tokens[providerName] = token
+	return credentialStoreFile, saveCredentialsFile(tokens)
+}
+
+func removeCredential(providerName string) (bool, error) {
+	removed := clearKeyringToken(providerName)
+	tokens := loadCredentialsFile()
+	if _, stored := tokens[providerName]; stored {
+		delete(tokens, providerName)
+		removed = true
+		if err := saveCredentialsFile(tokens); err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+func credentialProvider(hostname string) (string, string) {
+	for providerName, hosts := range providerCredentialHosts {
+		if scheme, matches := hosts[strings.ToLower(hostname)]; matches {
+			return providerName, scheme
+		}
+	}
+	return "", ""
+}
+
+func (transport *credentialTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.URL.Scheme == "https" && request.Header.Get("Authorization") == "" {
+		if providerName, scheme := credentialProvider(request.URL.Hostname()); providerName != "" {
+			if token := lookupCredential(providerName); token != "" {
+				request = request.Clone(request.Context())
+				request.Header.Set("Authorization", scheme+token)
+			}
+		}
+	}
+	return transport.base.RoundTrip(request)
+}
+
+func readCredentialToken(providerName string) (string, error) {
+	if term.IsTerminal(os.Stdin.Fd()) {
+		fmt.Fprintf(os.Stderr, "Token for %s: ", providerName)
+		tokenBytes, err := term.ReadPassword(os.Stdin.Fd())
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		return string(bytes.TrimSpace(tokenBytes)), nil
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func credentialProviderNames() []string {
+	names := make([]string, 0, len(providerCredentialHosts))
+	for name := range providerCredentialHosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func runAuthCommand(arguments []string) int {
+	usage := "Usage: twe-dlp auth login|logout <provider> | twe-dlp auth status"
+	if len(arguments) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	action := arguments[0]
+	switch action {
+	case "status":
+		for _, providerName := range credentialProviderNames() {
+			state := "not logged in"
+			if lookupKeyringToken(providerName) != "" {
+				state = "logged in (" + credentialStoreKeyring + ")"
+			} else if loadCredentialsFile()[providerName] != "" {
+				state = "logged in (" + credentialStoreFile + ")"
+			}
+			fmt.Printf("%-8s %s\n", providerName, state)
+		}
+		return 0
+	case "login", "logout":
+		if len(arguments) != 2 {
+			fmt.Fprintln(os.Stderr, usage)
+			return 2
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown auth action %q (available: login, logout, status)\n", action)
+		return 2
+	}
+
+	providerName := strings.ToLower(strings.TrimSpace(arguments[1]))
+	if _, supported := providerCredentialHosts[providerName]; !supported {
+		fmt.Fprintf(os.Stderr, "Unknown provider %q (available: %s)\n", arguments[1], strings.Join(credentialProviderNames(), ", "))
+		return 2
+	}
+
+	if action == "logout" {
+		removed, err := removeCredential(providerName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error removing credentials: %v\n", err)
+			return 1
+		}
+		if removed {
+			fmt.Printf("Logged out of %s\n", providerName)
+		} else {
+			fmt.Printf("No credentials stored for %s\n", providerName)
+		}
+		return 0
+	}
+
+	token, err := readCredentialToken(providerName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading token: %v\n", err)
+		return 1
+	}
+	if token == "" {
+		fmt.Fprintln(os.Stderr, "Error: empty token")
+		return 2
+	}
+	store, err := saveCredential(providerName, token)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving credentials: %v\n", err)
+		return 1
+	}
+	if store == credentialStoreFile {
+		filePath, _ := credentialsFilePath()
+		fmt.Printf("Saved %s token to %s (no OS keyring available)\n", providerName, filePath)
+	} else {
+		fmt.Printf("Saved %s token to the OS keyring\n", providerName)
+	}
+	return 0
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var cronMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+type cronField struct {
+	Name    string
+	Minimum int
+	Maximum int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+type cronSchedule struct {
+	minutes          uint64
+	hours            uint64
+	days             uint64
+	months           uint64
+	weekdays         uint64
+	daysAnyValue     bool
+	weekdaysAnyValue bool
+}
+
+func parseCronSchedule(expression string) (*cronSchedule, error) {
+	expression = strings.TrimSpace(expression)
+	if macro, exists := cronMacros[expression]; exists {
+		expression = macro
+	}
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields (minute hour day-of-month month day-of-week)", expression)
+	}
+
+	values := make([]uint64, len(fields))
+	for index, field := range fields {
+		bits, err := parseCronField(field, cronFields[index])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", expression, err)
+		}
+		values[index] = bits
+	}
+
+	weekdays := values[4]
+	if weekdays&(1<<7) != 0 {
+		weekdays |= 1
+	}
+	return &cronSchedule{
+		minutes:          values[0],
+		hours:            values[1],
+		days:             values[2],
+		months:           values[3],
+		weekdays:         weekdays,
+		daysAnyValue:     fields[2] == "*",
+		weekdaysAnyValue: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, limits cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangeText, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			parsedStep, err := strconv.Atoi(stepText)
+			if err != nil || parsedStep <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s", stepText, limits.Name)
+			}
+			step = parsedStep
+		}
+
+		start, end := limits.Minimum, limits.Maximum
+		if rangeText != "*" {
+			startText, endText, isRange := strings.Cut(rangeText, "-")
+			var err error
+			if start, err = strconv.Atoi(startText); err != nil {
+				return 0, fmt.Errorf("invalid value %q in %s", startText, limits.Name)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(endText); err != nil {
+					return 0, fmt.Errorf("invalid value %q in %s", endText, limits.Name)
+				}
+			} else if hasStep {
+				end = limits.Maximum
+			}
+		}
+		if start < limits.Minimum || end > limits.Maximum || start > end {
+			return 0, fmt.Errorf("%s value %q is out of range %d-%d", limits.Name, rangeText, limits.Minimum, limits.Maximum)
+		}
+
+		for value := start; value <= end; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+func (schedule *cronSchedule) matches(moment time.Time) bool {
+	if schedule.minutes&(1<<moment.Minute()) == 0 || schedule.hours&(1<<moment.Hour()) == 0 || schedule.months&(1<<int(moment.Month())) == 0 {
+		return false
+	}
+
+	dayMatches := schedule.days&(1<<moment.Day()) != 0
+	weekdayMatches := schedule.weekdays&(1<<int(moment.Weekday())) != 0
+	switch {
+	case schedule.daysAnyValue && schedule.weekdaysAnyValue:
+		return true
+	case schedule.daysAnyValue:
+		return weekdayMatches
+	case schedule.weekdaysAnyValue:
+		return dayMatches
+	}
+	return dayMatches || weekdayMatches
+}
+
+func (schedule *cronSchedule) next(after time.Time) (time.Time, error) {
+	moment := after.Truncate(time.Minute).Add(time.Minute)
+	limit := moment.AddDate(5, 0, 0)
+	for moment.Before(limit) {
+		if schedule.months&(1<<int(moment.Month())) == 0 {
+			moment = time.Date(moment.Year(), moment.Month()+1, 1, 0, 0, 0, 0, moment.Location())
+			continue
+		}
+		if schedule.matches(moment) {
+			return moment, nil
+		}
+		moment = moment.Add(time.Minute)
+	}
+	return time.Time{}, errors.New("schedule never fires")
+}
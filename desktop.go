@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+func openInFileManager(path string) error {
+	var command *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		command = exec.Command("open", path)
+	case "windows":
+		command = exec.Command("explorer", path)
+	default:
+		command = exec.Command("xdg-open", path)
+	}
+	if err := command.Start(); err != nil {
+		return err
+	}
+	go command.Wait()
+	return nil
+}
+
+func copyToClipboard(text string) error {
+	var candidates [][]string
+	switch runtime.GOOS {
+	case "darwin":
+		candidates = [][]string{{"pbcopy"}}
+	case "windows":
+		candidates = [][]string{{"clip"}}
+	default:
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			candidates = append(candidates, []string{"wl-copy"})
+		}
+		candidates = append(candidates, []string{"xclip", "-selection", "clipboard"}, []string{"xsel", "--clipboard", "--input"})
+	}
+
+	for _, candidate := range candidates {
+		if _, err := exec.LookPath(candidate[0]); err != nil {
+			continue
+		}
+		command := exec.Command(candidate[0], candidate[1:]...)
+		command.Stdin = strings.NewReader(text)
+		return command.Run()
+	}
+
+	if os.Getenv("TERM") == "" || os.Getenv("TERM") == "dumb" {
+		return errors.New("no clipboard tool found")
+	}
+	_, err := fmt.Fprintf(os.Stdout, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
+
+const windowsToastScript = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$texts = $template.GetElementsByTagName('text')
+$texts.Item(0).AppendChild($template.CreateTextNode($env:TWE_DLP_NOTIFY_TITLE)) > $null
+$texts.Item(1).AppendChild($template.CreateTextNode($env:TWE_DLP_NOTIFY_BODY)) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('twe-dlp').Show([Windows.UI.Notifications.ToastNotification]::new($template))`
+
+func sendNotification(title string, body string) error {
+	var command *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		command = exec.Command("osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, body)
+	case "windows":
+		command = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", windowsToastScript)
+		command.Env = append(os.Environ(), "TWE_DLP_NOTIFY_TITLE="+title, "TWE_DLP_NOTIFY_BODY="+body)
+	default:
+		command = exec.Command("notify-send", "--app-name=twe-dlp", title, body)
+	}
+	return command.Run()
+}
+
+func notifyIfRequested(options downloadOptions, failed bool, body string) {
+	if !options.Notify {
+		return
+	}
+	title := "twe-dlp: download finished"
+	if failed {
+		title = "twe-dlp: download failed"
+	}
+	if err := sendNotification(title, body); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cannot send notification: %v\n", err)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type emoteChange struct {
+	ID           string   `json:"id"`
+	Code         string   `json:"code"`
+	PreviousCode string   `json:"previous_code,omitempty"`
+	Changes      []string `json:"changes"`
+}
+
+type manifestDiff struct {
+	Added   []manifestEmote `json:"added"`
+	Removed []manifestEmote `json:"removed"`
+	Changed []emoteChange   `json:"changed"`
+}
+
+func (diff manifestDiff) empty() bool {
+	return len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0
+}
+
+func diffManifests(previous *channelManifest, current *channelManifest) manifestDiff {
+	diff := manifestDiff{Added: []manifestEmote{}, Removed: []manifestEmote{}, Changed: []emoteChange{}}
+	previousEmotes := previous.emotesByID()
+	currentEmotes := current.emotesByID()
+	for _, emote := range current.Emotes {
+		previousEmote, known := previousEmotes[emote.ID]
+		if !known {
+			diff.Added = append(diff.Added, emote)
+			continue
+		}
+		if change, changed := diffManifestEmote(previousEmote, emote); changed {
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+	for _, emote := range previous.Emotes {
+		if _, stillListed := currentEmotes[emote.ID]; !stillListed {
+			diff.Removed = append(diff.Removed, emote)
+		}
+	}
+	sort.Slice(diff.Added, func(left, right int) bool { return diff.Added[left].ID < diff.Added[right].ID })
+	sort.Slice(diff.Removed, func(left, right int) bool { return diff.Removed[left].ID < diff.Removed[right].ID })
+	sort.Slice(diff.Changed, func(left, right int) bool { return diff.Changed[left].ID < diff.Changed[right].ID })
+	return diff
+}
+
+func diffManifestEmote(previous manifestEmote, current manifestEmote) (emoteChange, bool) {
+	change := emoteChange{ID: current.ID, Code: current.Code}
+	if previous.Code != current.Code {
+		change.PreviousCode = previous.Code
+		change.Changes = append(change.Changes, fmt.Sprintf("renamed from %s", previous.Code))
+	}
+
+	previousFiles := make(map[string]manifestFile, len(previous.Files))
+	for _, file := range previous.Files {
+		previousFiles[file.Size] = file
+	}
+	currentSizes := make(map[string]bool, len(current.Files))
+	var added, replaced, removed []string
+	for _, file := range current.Files {
+		currentSizes[file.Size] = true
+		previousFile, known := previousFiles[file.Size]
+		switch {
+		case !known:
+			added = append(added, file.Size)
+		case manifestFileChanged(previousFile, file):
+			replaced = append(replaced, file.Size)
+		}
+	}
+	for _, file := range previous.Files {
+		if !currentSizes[file.Size] {
+			removed = append(removed, file.Size)
+		}
+	}
+	if len(added) > 0 {
+		change.Changes = append(change.Changes, "sizes added: "+strings.Join(added, ", "))
+	}
+	if len(replaced) > 0 {
+		change.Changes = append(change.Changes, "images changed: "+strings.Join(replaced, ", "))
+	}
+	if len(removed) > 0 {
+		change.Changes = append(change.Changes, "sizes removed: "+strings.Join(removed, ", "))
+	}
+	return change, len(change.Changes) > 0
+}
+
+func manifestFileChanged(previous manifestFile, current manifestFile) bool {
+	if previous.SHA256 != "" && current.SHA256 != "" {
+		return previous.SHA256 != current.SHA256
+	}
+	return previous.URL != current.URL || previous.Bytes != current.Bytes
+}
+
+func loadManifestPath(manifestPath string) (*channelManifest, error) {
+	if info, err := os.Stat(manifestPath); err == nil && info.IsDir() {
+		manifestPath = filepath.Join(manifestPath, manifestFileName)
+	}
+	manifestBytes, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &channelManifest{}
+	if err := json.Unmarshal(manifestBytes, manifest); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", manifestPath, err)
+	}
+	return manifest, nil
+}
+
+func newDiffFlagSet(jsonOutput *bool) *flag.FlagSet {
+	flagSet := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flagSet.BoolVar(jsonOutput, "json", false, "print the added, removed and changed emotes as JSON")
+	return flagSet
+}
+
+func runDiffCommand(arguments []string) int {
+	var jsonOutput bool
+	flagSet := newDiffFlagSet(&jsonOutput)
+	if err := flagSet.Parse(arguments); err != nil {
+		return exitCodeForParseError(err)
+	}
+	positional := flagSet.Args()
+	if len(positional) != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: twe-dlp diff [--json] <old manifest or channel folder> <new manifest or channel folder>")
+		return 2
+	}
+
+	manifests := make([]*channelManifest, len(positional))
+	for index, manifestPath := range positional {
+		manifest, err := loadManifestPath(manifestPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		manifests[index] = manifest
+	}
+	previous, current := manifests[0], manifests[1]
+	if previous.ChannelID != "" && current.ChannelID != "" && (previous.ChannelID != current.ChannelID || previous.Provider != current.Provider) {
+		fmt.Fprintf(os.Stderr, "Warning: comparing different channels (%s %s and %s %s)\n", previous.Provider, previous.ChannelID, current.Provider, current.ChannelID)
+	}
+	diff := diffManifests(previous, current)
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diff); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+	} else {
+		for _, emote := range diff.Added {
+			fmt.Printf("+ %s (%s)\n", emote.Code, emote.ID)
+		}
+		for _, emote := range diff.Removed {
+			fmt.Printf("- %s (%s)\n", emote.Code, emote.ID)
+		}
+		for _, change := range diff.Changed {
+			fmt.Printf("~ %s (%s): %s\n", change.Code, change.ID, strings.Join(change.Changes, "; "))
+		}
+		fmt.Printf("%d added, %d removed, %d changed\n", len(diff.Added), len(diff.Removed), len(diff.Changed))
+	}
+	if diff.empty() {
+		return 0
+	}
+	return 1
+}
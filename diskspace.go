@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	sizeEstimateWorkers = 8
+	sizeEstimateSample  = 40
+	largeDownloadEmotes = 200
+)
+
+var byteSizeUnits = []struct {
+	Suffix     string
+	Multiplier int64
+}{
+	{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+func parseByteSize(value string) (int64, error) {
+	trimmed := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(trimmed, unit.Suffix) {
+			trimmed = strings.TrimSpace(strings.TrimSuffix(trimmed, unit.Suffix))
+			multiplier = unit.Multiplier
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size %q (examples: 500M, 2G, 1048576)", value)
+	}
+	return int64(number * float64(multiplier)), nil
+}
+
+func formatByteSize(size int64) string {
+	for _, unit := range byteSizeUnits[:4] {
+		if size >= unit.Multiplier {
+			return fmt.Sprintf("%.1f %s", float64(size)/float64(unit.Multiplier), unit.Suffix)
+		}
+	}
+	return fmt.Sprintf("%d B", size)
+}
+
+func estimateDownloadSize(httpClient *http.Client, provider emoteProvider, sizes []string, emoteMap map[string]EmoteData, previousFiles map[string]manifestFile) (int64, int) {
+	imageURLs := make(chan string)
+	var totalLock sync.Mutex
+	var totalBytes int64
+	unknownCount := 0
+
+	var workers sync.WaitGroup
+	for worker := 0; worker < sizeEstimateWorkers; worker++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for imageURL := range imageURLs {
+				contentLength := headContentLength(httpClient, imageURL)
+				totalLock.Lock()
+				if contentLength < 0 {
+					unknownCount++
+				} else {
+					totalBytes += contentLength
+				}
+				totalLock.Unlock()
+			}
+		}()
+	}
+
+	for _, emoteData := range emoteMap {
+		for _, sizeValue := range sizes {
+			imageURL := provider.ImageURL(emoteData, sizeValue)
+			if _, downloaded := previousFiles[imageURL]; downloaded {
+				continue
+			}
+			imageURLs <- imageURL
+		}
+	}
+	close(imageURLs)
+	workers.Wait()
+
+	return totalBytes, unknownCount
+}
+
+func headContentLength(httpClient *http.Client, imageURL string) int64 {
+	response, err := headImage(httpClient, imageURL)
+	if err != nil {
+		return -1
+	}
+	if response.StatusCode != http.StatusOK {
+		return -1
+	}
+	return response.ContentLength
+}
+
+func sampleEmoteSize(httpClient *http.Client, provider emoteProvider, sizes []string, emoteMap map[string]EmoteData) int64 {
+	if len(emoteMap) == 0 || len(sizes) == 0 {
+		return 0
+	}
+	emoteIdentifiers := make([]string, 0, len(emoteMap))
+	for emoteIdentifier := range emoteMap {
+		emoteIdentifiers = append(emoteIdentifiers, emoteIdentifier)
+	}
+	sort.Strings(emoteIdentifiers)
+
+	step := max(1, len(emoteIdentifiers)/sizeEstimateSample)
+	sample := make(map[string]EmoteData, sizeEstimateSample)
+	for index := 0; index < len(emoteIdentifiers) && len(sample) < sizeEstimateSample; index += step {
+		sample[emoteIdentifiers[index]] = emoteMap[emoteIdentifiers[index]]
+	}
+	sampledBytes, unknownCount := estimateDownloadSize(httpClient, provider, sizes, sample, nil)
+	if knownFiles := len(sample)*len(sizes) - unknownCount; knownFiles > 0 {
+		return sampledBytes / int64(knownFiles) * int64(len(sizes))
+	}
+
+	totals := loadDownloadStats().totals()
+	if totals.Files > 0 {
+		return totals.Bytes / int64(totals.Files) * int64(len(sizes))
+	}
+	return 0
+}
+
+func checkDiskSpace(httpClient *http.Client, provider emoteProvider, emoteMap map[string]EmoteData, previousFiles map[string]manifestFile, outputRoot string, options downloadOptions, logFunc func(string)) error {
+	logFunc("Estimating download size...")
+	estimatedBytes, unknownCount := estimateDownloadSize(httpClient, provider, downloadSizes(provider, options), emoteMap, previousFiles)
+	if unknownCount > 0 {
+		logFunc(fmt.Sprintf("Estimated download size: at least %s (%d files without a size)", formatByteSize(estimatedBytes), unknownCount))
+	} else {
+		logFunc(fmt.Sprintf("Estimated download size: %s", formatByteSize(estimatedBytes)))
+	}
+
+	if options.MaxTotalSize > 0 && estimatedBytes > options.MaxTotalSize {
+		return fmt.Errorf("estimated download size %s exceeds --max-total-size %s", formatByteSize(estimatedBytes), formatByteSize(options.MaxTotalSize))
+	}
+
+	availableBytes, err := availableDiskSpace(outputRoot)
+	if err != nil {
+		logFunc(fmt.Sprintf("[warn] cannot determine free disk space: %v", err))
+		return nil
+	}
+	if estimatedBytes > availableBytes {
+		return fmt.Errorf("not enough disk space in %s: need %s, %s available", outputRoot, formatByteSize(estimatedBytes), formatByteSize(availableBytes))
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var getDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+func availableDiskSpace(path string) (int64, error) {
+	pathPointer, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var availableBytes uint64
+	result, _, callError := getDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(pathPointer)), uintptr(unsafe.Pointer(&availableBytes)), 0, 0)
+	if result == 0 {
+		return 0, callError
+	}
+	return int64(availableBytes), nil
+}
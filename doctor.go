@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const defaultDoctorChannel = "xqc"
+
+type doctorReport struct {
+	failures int
+	warnings int
+}
+
+func (report *doctorReport) check(status string, step string, detail string, strategyLines ...string) {
+	switch status {
+	case "fail":
+		report.failures++
+	case "warn":
+		report.warnings++
+	}
+	fmt.Printf("%-6s %s: %s\n", "["+status+"]", step, detail)
+	for _, line := range strategyLines {
+		fmt.Printf("         %s\n", line)
+	}
+}
+
+func strategyStatus(primaryMatched bool, anyMatched bool) string {
+	switch {
+	case primaryMatched:
+		return "ok"
+	case anyMatched:
+		return "warn"
+	}
+	return "fail"
+}
+
+func checkChannelResolvers(report *doctorReport, httpClient *http.Client, channelIdentifier string) string {
+	if twitchIDPattern.MatchString(channelIdentifier) {
+		report.check("ok", "channel search", fmt.Sprintf("skipped, %s is already a channel ID", channelIdentifier))
+		return channelIdentifier
+	}
+
+	lines := make([]string, 0, len(channelResolvers))
+	channelID := ""
+	usedResolver := ""
+	primaryMatched := false
+	for index, resolver := range channelResolvers {
+		resolvedID, err := resolver.Resolve(httpClient, channelIdentifier)
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("%s: %v", resolver.Name, err))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", resolver.Name, resolvedID))
+		if index == 0 {
+			primaryMatched = true
+		}
+		if channelID == "" {
+			channelID, usedResolver = resolvedID, resolver.Name
+		}
+	}
+
+	detail := fmt.Sprintf("no resolver found %q", channelIdentifier)
+	if channelID != "" {
+		detail = fmt.Sprintf("%q is channel %s via %s", channelIdentifier, channelID, usedResolver)
+	}
+	report.check(strategyStatus(primaryMatched, channelID != ""), "channel search", detail, lines...)
+	return channelID
+}
+
+func checkDisplayName(report *doctorReport, document *goquery.Document) {
+	lines := make([]string, 0, len(displayNameStrategies))
+	displayName := ""
+	usedStrategy := ""
+	for _, strategy := range displayNameStrategies {
+		name := strategy.Extract(document)
+		if name == "" {
+			lines = append(lines, fmt.Sprintf("%s: no match", strategy.Name))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %q", strategy.Name, name))
+		if displayName == "" {
+			displayName, usedStrategy = name, strategy.Name
+		}
+	}
+
+	status := strategyStatus(displayNameStrategies[0].Extract(document) != "", displayName != "")
+	detail := "no selector matched"
+	if displayName != "" {
+		detail = fmt.Sprintf("%q via %s", displayName, usedStrategy)
+	}
+	report.check(status, "display name", detail, lines...)
+}
+
+func checkEmoteImages(report *doctorReport, document *goquery.Document) []*goquery.Selection {
+	lines := make([]string, 0, len(emoteImageStrategies))
+	emoteSelections := make([]*goquery.Selection, 0)
+	seenEmotes := make(map[string]bool)
+	primaryMatched := false
+	for index, strategy := range emoteImageStrategies {
+		matched, valid := 0, 0
+		document.Find(strategy.Selector).Each(func(_ int, selection *goquery.Selection) {
+			matched++
+			imageSource, _ := selection.Attr(strategy.Attribute)
+			emoteIdentifier, _, isValid := parseEmoteImageURL(imageSource)
+			if !isValid {
+				return
+			}
+			valid++
+			if !seenEmotes[emoteIdentifier] {
+				seenEmotes[emoteIdentifier] = true
+				emoteSelections = append(emoteSelections, selection)
+			}
+		})
+		if index == 0 {
+			primaryMatched = valid > 0
+		}
+		lines = append(lines, fmt.Sprintf("%s (%s): %d elements, %d emote URLs", strategy.Name, strategy.Selector, matched, valid))
+	}
+
+	detail := fmt.Sprintf("%d emotes found", len(emoteSelections))
+	if len(emoteSelections) == 0 {
+		detail = "no selector found emote images"
+	}
+	report.check(strategyStatus(primaryMatched, len(emoteSelections) > 0), "emote images", detail, lines...)
+	return emoteSelections
+}
+
+func checkEmoteCodes(report *doctorReport, emoteSelections []*goquery.Selection) {
+	if len(emoteSelections) == 0 {
+		report.check("fail", "emote codes", "skipped, no emote images were found")
+		return
+	}
+
+	lines := make([]string, 0, len(emoteCodeStrategies))
+	resolvedCodes := 0
+	for _, selection := range emoteSelections {
+		if code, _ := extractEmoteCode(selection); code != "" {
+			resolvedCodes++
+		}
+	}
+	primaryMatched := false
+	for index, strategy := range emoteCodeStrategies {
+		matched := 0
+		example := ""
+		for _, selection := range emoteSelections {
+			if code := strategy.Extract(selection); code != "" {
+				matched++
+				if example == "" {
+					example = code
+				}
+			}
+		}
+		if index == 0 {
+			primaryMatched = matched == len(emoteSelections)
+		}
+		line := fmt.Sprintf("%s: %d of %d emotes", strategy.Name, matched, len(emoteSelections))
+		if example != "" {
+			line += fmt.Sprintf(", e.g. %q", example)
+		}
+		lines = append(lines, line)
+	}
+
+	detail := fmt.Sprintf("%d of %d emotes have a code", resolvedCodes, len(emoteSelections))
+	if resolvedCodes < len(emoteSelections) {
+		detail += ", the rest would be named by ID"
+	}
+	report.check(strategyStatus(primaryMatched, resolvedCodes > 0), "emote codes", detail, lines...)
+}
+
+func checkEmoteDownload(report *doctorReport, httpClient *http.Client, emoteSelections []*goquery.Selection) {
+	if len(emoteSelections) == 0 {
+		report.check("fail", "image download", "skipped, no emote images were found")
+		return
+	}
+
+	var emoteData EmoteData
+	for _, strategy := range emoteImageStrategies {
+		if imageSource, exists := emoteSelections[0].Attr(strategy.Attribute); exists {
+			if _, data, valid := parseEmoteImageURL(imageSource); valid {
+				emoteData = data
+				break
+			}
+		}
+	}
+	provider := twitchProvider{}
+	imageURL := provider.ImageURL(emoteData, provider.Sizes()[0])
+
+	response, err := httpClient.Get(imageURL)
+	if err != nil {
+		report.check("fail", "image download", err.Error())
+		return
+	}
+	response.Body.Close()
+	contentType := response.Header.Get("Content-Type")
+	if response.StatusCode != http.StatusOK || !strings.HasPrefix(contentType, "image/") {
+		report.check("fail", "image download", fmt.Sprintf("%s returned %s (%s)", imageURL, response.Status, contentType))
+		return
+	}
+	report.check("ok", "image download", fmt.Sprintf("%s (%s)", imageURL, contentType))
+}
+
+func runDoctorCommand(arguments []string) int {
+	var options downloadOptions
+
+	flagSet := newCommandFlagSet("doctor", &options)
+	positional, err := parseCommandLine(flagSet, &options, arguments)
+	if err != nil {
+		return exitCodeForParseError(err)
+	}
+	if len(positional) > 1 {
+		fmt.Fprintln(os.Stderr, "Usage: twe-dlp doctor [<channel>]")
+		return 2
+	}
+	channelIdentifier := defaultDoctorChannel
+	if len(positional) == 1 {
+		channelIdentifier = strings.TrimSpace(positional[0])
+	}
+	options.NoCache = true
+	httpClient := createHTTPClient(options)
+
+	report := &doctorReport{}
+	channelID := checkChannelResolvers(report, httpClient, channelIdentifier)
+	if channelID == "" {
+		return 1
+	}
+
+	channelURL := fmt.Sprintf("%s/channels/%s", twitchemotesBaseURL, channelID)
+	document, response, err := fetchDocument(httpClient, channelURL)
+	if err != nil {
+		report.check("fail", "channel page", err.Error())
+		return 1
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		report.check("fail", "channel page", fmt.Sprintf("%s returned %s", channelURL, response.Status))
+		return 1
+	}
+	report.check("ok", "channel page", channelURL)
+
+	checkDisplayName(report, document)
+	emoteSelections := checkEmoteImages(report, document)
+	checkEmoteCodes(report, emoteSelections)
+	checkEmoteDownload(report, httpClient, emoteSelections)
+
+	switch {
+	case report.failures > 0:
+		fmt.Printf("%d checks failed, %d warnings\n", report.failures, report.warnings)
+		return 1
+	case report.warnings > 0:
+		fmt.Printf("All checks passed with %d warnings (fallback selectors were used)\n", report.warnings)
+	default:
+		fmt.Println("All checks passed")
+	}
+	return 0
+}
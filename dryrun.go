@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func planChannelDownload(provider emoteProvider, channel *ChannelData, options downloadOptions, logFunc func(string)) error {
+	store := outputStorage(options)
+	outputRoot := channelOutputRoot(provider, channel, options)
+	previousManifest, err := loadManifest(store, outputRoot)
+	if err != nil {
+		logFunc(fmt.Sprintf("[error] ignoring previous manifest: %v", err))
+		previousManifest = &channelManifest{}
+	}
+	previousFiles := previousManifest.filesByURL()
+	previousEmotes := previousManifest.emotesByID()
+	folderNames := newNameAllocator()
+	for _, previousEmote := range previousManifest.Emotes {
+		folderNames.reserve(previousEmote.Folder, previousEmote.ID)
+	}
+
+	logFunc(fmt.Sprintf("Channel ID: %s", channel.ID))
+	if channel.DisplayName != "" {
+		logFunc(fmt.Sprintf("Channel Name: %s", channel.DisplayName))
+	}
+	if isLocalStorage(store) {
+		logFunc(fmt.Sprintf("Output Folder: %s", outputRoot))
+	} else {
+		logFunc(fmt.Sprintf("Output Folder: %s in %s", filepath.ToSlash(outputRoot), store))
+	}
+
+	emoteMap := filterNewerThan(filterEmotes(channel.Emotes, options), previousEmotes, options.NewerThan)
+	logFunc(fmt.Sprintf("Found %d emotes", len(channel.Emotes)))
+	if sources := emoteCodeSources(channel.Emotes); sources != "" {
+		logFunc("Emote codes from: " + sources)
+	}
+	if len(emoteMap) != len(channel.Emotes) {
+		logFunc(fmt.Sprintf("Selected %d of %d emotes", len(emoteMap), len(channel.Emotes)))
+	}
+	emoteIdentifiers := make([]string, 0, len(emoteMap))
+	for emoteIdentifier := range emoteMap {
+		emoteIdentifiers = append(emoteIdentifiers, emoteIdentifier)
+	}
+	sort.Strings(emoteIdentifiers)
+
+	fetches, revalidations, writes := 0, 0, 0
+	for _, emoteIdentifier := range emoteIdentifiers {
+		emoteData := emoteMap[emoteIdentifier]
+		safeEmoteCode := previousEmotes[emoteIdentifier].Folder
+		if safeEmoteCode == "" {
+			baseName := makeSafeName(emoteData.EmoteCode, options.KeepUnicode)
+			var conflict string
+			safeEmoteCode, conflict = folderNames.allocate(baseName, emoteIdentifier)
+			if safeEmoteCode != baseName {
+				logFunc(fmt.Sprintf("[warn] emote %s (%s) maps to folder %s already used by emote %s, saving to %s", emoteData.EmoteCode, emoteIdentifier, baseName, conflict, safeEmoteCode))
+			}
+		}
+
+		for _, sizeValue := range downloadSizes(provider, options) {
+			imageURL := provider.ImageURL(emoteData, sizeValue)
+			if previousFile, hasPrevious := previousFiles[imageURL]; hasPrevious && store.Exists(filepath.Join(outputRoot, previousFile.Path)) {
+				revalidations++
+				logFunc(fmt.Sprintf("[revalidate] %s -> %s (already downloaded)", imageURL, previousFile.Path))
+				continue
+			}
+			filePath := filepath.ToSlash(filepath.Join(safeEmoteCode, fmt.Sprintf("%s_%s.*", safeEmoteCode, sizeValue)))
+			if options.Layout == layoutFlat {
+				filePath = fmt.Sprintf("%s_%s.*", safeEmoteCode, sizeValue)
+			}
+			if options.Layout == layoutCAS {
+				if relativePath, err := filepath.Rel(outputRoot, filepath.Join(objectsRoot(options), "<sha256>.*")); err == nil {
+					filePath = filepath.ToSlash(relativePath)
+				}
+			}
+			fetches++
+			writes++
+			logFunc(fmt.Sprintf("[fetch] %s -> %s", imageURL, filePath))
+		}
+		if options.Largest != "" {
+			largestPath := filepath.ToSlash(filepath.Join(safeEmoteCode, safeEmoteCode+".*"))
+			if options.Layout == layoutFlat || options.Layout == layoutCAS {
+				largestPath = safeEmoteCode + ".*"
+			}
+			writes++
+			logFunc(fmt.Sprintf("[write] %s (--largest %s)", largestPath, options.Largest))
+		}
+	}
+
+	if options.ChannelImages {
+		previous := previousManifest.Metadata
+		if previous == nil {
+			previous = &channelMetadata{}
+		}
+		for _, image := range []struct{ name, url, previousURL string }{
+			{channelAvatarName, channel.ProfileImageURL, previous.ProfileImageURL},
+			{channelBannerName, channel.BannerURL, previous.BannerURL},
+		} {
+			if image.url == "" || image.url == image.previousURL {
+				continue
+			}
+			fetches++
+			writes++
+			logFunc(fmt.Sprintf("[fetch] %s -> %s.*", image.url, image.name))
+		}
+	}
+
+	outputFiles := []string{manifestFileName, reportJSONFileName, reportTextFileName}
+	if options.ManifestFormat == manifestFormatCSV {
+		outputFiles = append(outputFiles, manifestCSVFileName)
+	}
+	if options.Gallery {
+		outputFiles = append(outputFiles, galleryFileName)
+	}
+	if options.Markdown {
+		outputFiles = append(outputFiles, markdownFileName)
+	}
+	if options.Checksums == checksumSHA256 {
+		outputFiles = append(outputFiles, sha256SumsFileName)
+	}
+	for _, fileName := range outputFiles {
+		writes++
+		logFunc(fmt.Sprintf("[write] %s", fileName))
+	}
+
+	var extras []string
+	padded := options.Pad != (image.Point{})
+	switch {
+	case options.Trim && padded:
+		extras = append(extras, "trimmed and padded copies")
+	case options.Trim:
+		extras = append(extras, "trimmed copies")
+	case padded:
+		extras = append(extras, "padded copies")
+	}
+	if options.ExtractFrames {
+		extras = append(extras, "GIF frames")
+	}
+	if options.ConvertAnimated != "" {
+		extras = append(extras, options.ConvertAnimated+" conversions")
+	}
+	if options.ZipPerEmote {
+		extras = append(extras, "per-emote zips")
+	}
+	summary := fmt.Sprintf("Dry run: %d URLs would be fetched, %d revalidated and %d files written", fetches, revalidations, writes)
+	if len(extras) > 0 {
+		summary += " (plus " + strings.Join(extras, ", ") + ")"
+	}
+	logFunc(summary + "; nothing was written")
+	return nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	emoteMatchExact = iota
+	emoteMatchCode
+	emoteMatchCodeFold
+	emoteMatchSubstring
+	emoteMatchFuzzy
+	emoteMatchNone
+)
+
+var twitchEmoteIDPattern = regexp.MustCompile(`^(?:\d+|emotesv2_[0-9a-f]+)$`)
+
+type emoteMatch struct {
+	Provider        emoteProvider
+	Channel         *ChannelData
+	EmoteIdentifier string
+	Rank            int
+	Distance        int
+}
+
+func findEmoteInChannel(channel *ChannelData, query string) (string, int, int) {
+	if _, exists := channel.Emotes[query]; exists {
+		return query, emoteMatchExact, 0
+	}
+
+	emoteIdentifiers := make([]string, 0, len(channel.Emotes))
+	for emoteIdentifier := range channel.Emotes {
+		emoteIdentifiers = append(emoteIdentifiers, emoteIdentifier)
+	}
+	sort.Strings(emoteIdentifiers)
+
+	bestIdentifier := ""
+	bestRank := emoteMatchNone
+	bestDistance := 0
+	lowerQuery := strings.ToLower(query)
+	maximumDistance := len(query) / 3
+	if maximumDistance < 2 {
+		maximumDistance = 2
+	}
+
+	for _, emoteIdentifier := range emoteIdentifiers {
+		emoteCode := channel.Emotes[emoteIdentifier].EmoteCode
+		lowerCode := strings.ToLower(emoteCode)
+
+		rank := emoteMatchNone
+		distance := 0
+		switch {
+		case emoteCode == query:
+			rank = emoteMatchCode
+		case lowerCode == lowerQuery:
+			rank = emoteMatchCodeFold
+		case strings.Contains(lowerCode, lowerQuery):
+			rank = emoteMatchSubstring
+			distance = len(lowerCode) - len(lowerQuery)
+		default:
+			distance = editDistance(lowerCode, lowerQuery)
+			if distance <= maximumDistance {
+				rank = emoteMatchFuzzy
+			}
+		}
+
+		if rank < bestRank || rank == bestRank && rank != emoteMatchNone && distance < bestDistance {
+			bestIdentifier = emoteIdentifier
+			bestRank = rank
+			bestDistance = distance
+		}
+	}
+
+	return bestIdentifier, bestRank, bestDistance
+}
+
+func editDistance(left string, right string) int {
+	leftRunes := []rune(left)
+	rightRunes := []rune(right)
+	previousRow := make([]int, len(rightRunes)+1)
+	currentRow := make([]int, len(rightRunes)+1)
+	for column := range previousRow {
+		previousRow[column] = column
+	}
+
+	for row := 1; row <= len(leftRunes); row++ {
+		currentRow[0] = row
+		for column := 1; column <= len(rightRunes); column++ {
+			cost := 1
+			if leftRunes[row-1] == rightRunes[column-1] {
+				cost = 0
+			}
+			currentRow[column] = min(previousRow[column]+1, currentRow[column-1]+1, previousRow[column-1]+cost)
+		}
+		previousRow, currentRow = currentRow, previousRow
+	}
+
+	return previousRow[len(rightRunes)]
+}
+
+func newEmoteFlagSet(options *downloadOptions, channelInputs *stringListFlag) *flag.FlagSet {
+	flagSet := newCommandFlagSet("emote", options)
+	flagSet.Var(channelInputs, "channel", "channel to look the emote up in (repeatable, accepts provider prefixes)")
+	return flagSet
+}
+
+func runEmoteCommand(arguments []string) int {
+	var options downloadOptions
+	var channelInputs stringListFlag
+
+	flagSet := newEmoteFlagSet(&options, &channelInputs)
+	positional, err := parseCommandLine(flagSet, &options, arguments)
+	if err != nil {
+		return exitCodeForParseError(err)
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: twe-dlp emote [--channel <name>]... <id-or-code>")
+		return 2
+	}
+	httpClient := createHTTPClient(options)
+	query := strings.TrimSpace(positional[0])
+
+	logFunc := func(line string) {
+		fmt.Println(logTimestamp(options.Timestamps) + line)
+		options.Log.write(line)
+	}
+
+	if len(channelInputs) == 0 {
+		if !twitchEmoteIDPattern.MatchString(query) {
+			fmt.Fprintln(os.Stderr, "Looking up an emote by code needs at least one --channel.")
+			return 2
+		}
+		looseChannel := &ChannelData{
+			ID:          "emotes",
+			DisplayName: "emotes",
+			Emotes: map[string]EmoteData{
+				query: {
+					BaseURL:    fmt.Sprintf("%s/%s/default", twitchEmoteCDNBaseURL, query),
+					FormatType: "default",
+					EmoteCode:  query,
+				},
+			},
+		}
+		if err := downloadChannelData(httpClient, twitchProvider{}, looseChannel, options, logFunc); err != nil {
+			fmt.Fprintf(os.Stderr, "Error downloading emote: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	var bestMatch *emoteMatch
+	for _, channelInput := range channelInputs {
+		provider, providerIdentifier, err := selectProvider(channelInput, options.Provider)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			continue
+		}
+		channelID, err := provider.ResolveChannelID(httpClient, providerIdentifier)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error resolving channel %s: %v\n", channelInput, err)
+			continue
+		}
+		channel, err := provider.FetchChannel(httpClient, channelID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error fetching channel %s: %v\n", channelInput, err)
+			continue
+		}
+
+		emoteIdentifier, rank, distance := findEmoteInChannel(channel, query)
+		if rank == emoteMatchNone {
+			continue
+		}
+		if bestMatch == nil || rank < bestMatch.Rank || rank == bestMatch.Rank && distance < bestMatch.Distance {
+			bestMatch = &emoteMatch{
+				Provider:        provider,
+				Channel:         channel,
+				EmoteIdentifier: emoteIdentifier,
+				Rank:            rank,
+				Distance:        distance,
+			}
+		}
+	}
+
+	if bestMatch == nil {
+		fmt.Fprintf(os.Stderr, "No emote matching %q found.\n", query)
+		return 1
+	}
+
+	emoteData := bestMatch.Channel.Emotes[bestMatch.EmoteIdentifier]
+	if bestMatch.Rank > emoteMatchCode {
+		logFunc(fmt.Sprintf("Closest match for %q: %s (%s) on %s", query, emoteData.EmoteCode, bestMatch.EmoteIdentifier, bestMatch.Provider.Name()))
+	}
+
+	bestMatch.Channel.Emotes = map[string]EmoteData{
+		bestMatch.EmoteIdentifier: emoteData,
+	}
+	if err := downloadChannelData(httpClient, bestMatch.Provider, bestMatch.Channel, options, logFunc); err != nil {
+		fmt.Fprintf(os.Stderr, "Error downloading emote: %v\n", err)
+		return 1
+	}
+	return 0
+}
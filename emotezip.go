@@ -0,0 +1,51 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+const emoteZipExtension = ".zip"
+
+func writeEmoteZip(store storage, outputRoot string, emoteRecord *manifestEmote, logFunc func(string)) {
+	if len(emoteRecord.Files) == 0 || emoteRecord.Folder == "" {
+		return
+	}
+	zipPath := emoteRecord.Folder + emoteZipExtension
+
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	modified := time.Now()
+	for _, file := range emoteRecord.Files {
+		imageBytes, err := store.ReadFile(filepath.Join(outputRoot, filepath.FromSlash(file.Path)))
+		if err != nil {
+			logFunc(fmt.Sprintf("[error] cannot write %s: %v", zipPath, err))
+			return
+		}
+		entryWriter, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     fmt.Sprintf("%s_%s%s", emoteRecord.Folder, file.Size, path.Ext(file.Path)),
+			Method:   zip.Store,
+			Modified: modified,
+		})
+		if err == nil {
+			_, err = entryWriter.Write(imageBytes)
+		}
+		if err != nil {
+			logFunc(fmt.Sprintf("[error] cannot write %s: %v", zipPath, err))
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		logFunc(fmt.Sprintf("[error] cannot write %s: %v", zipPath, err))
+		return
+	}
+	if err := store.WriteFile(filepath.Join(outputRoot, zipPath), buffer.Bytes()); err != nil {
+		logFunc(fmt.Sprintf("[error] cannot write %s: %v", zipPath, err))
+		return
+	}
+	emoteRecord.Zip = zipPath
+}
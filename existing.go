@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	existingMerge     = "merge"
+	existingOverwrite = "overwrite"
+
+	existingExampleCodes = 5
+)
+
+var errExistingAborted = errors.New("left the existing archive untouched")
+
+type existingSummary struct {
+	Archived  int
+	Listed    bool
+	New       []string
+	Changed   []string
+	Unchanged int
+	Unlisted  []string
+	Deletable bool
+}
+
+func summarizeExisting(provider emoteProvider, channel *ChannelData, previousManifest *channelManifest, listed bool, options downloadOptions) existingSummary {
+	summary := existingSummary{Archived: len(previousManifest.Emotes), Listed: listed}
+	if !listed {
+		return summary
+	}
+	previousFiles := previousManifest.filesByURL()
+	previousEmotes := previousManifest.emotesByID()
+	emoteMap := filterEmotes(channel.Emotes, options)
+	for emoteIdentifier, emoteData := range emoteMap {
+		if _, archived := previousEmotes[emoteIdentifier]; !archived {
+			summary.New = append(summary.New, emoteData.EmoteCode)
+			continue
+		}
+		changed := false
+		for _, sizeValue := range downloadSizes(provider, options) {
+			if _, downloaded := previousFiles[provider.ImageURL(emoteData, sizeValue)]; !downloaded {
+				changed = true
+				break
+			}
+		}
+		if changed {
+			summary.Changed = append(summary.Changed, emoteData.EmoteCode)
+		} else {
+			summary.Unchanged++
+		}
+	}
+	for _, previousEmote := range previousManifest.Emotes {
+		if _, stillListed := channel.Emotes[previousEmote.ID]; !stillListed {
+			summary.Unlisted = append(summary.Unlisted, previousEmote.Code)
+		}
+	}
+	sort.Strings(summary.New)
+	sort.Strings(summary.Changed)
+	sort.Strings(summary.Unlisted)
+	return summary
+}
+
+func existingExamples(codes []string) string {
+	if len(codes) <= existingExampleCodes {
+		return strings.Join(codes, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(codes[:existingExampleCodes], ", "), len(codes)-existingExampleCodes)
+}
+
+func (summary existingSummary) lines(outputRoot string) []string {
+	lines := []string{fmt.Sprintf("%s already holds an archive of %d emotes:", outputRoot, summary.Archived)}
+	if !summary.Listed {
+		return append(lines, "  ? the emote list is read while downloading, so changes are not known in advance")
+	}
+	if len(summary.New) > 0 {
+		lines = append(lines, fmt.Sprintf("  + %d new: %s", len(summary.New), existingExamples(summary.New)))
+	}
+	if len(summary.Changed) > 0 {
+		lines = append(lines, fmt.Sprintf("  ~ %d with new or changed images: %s", len(summary.Changed), existingExamples(summary.Changed)))
+	}
+	lines = append(lines, fmt.Sprintf("  = %d unchanged", summary.Unchanged))
+	if len(summary.Unlisted) > 0 {
+		note := "kept by --merge, deleted by --overwrite"
+		if !summary.Deletable {
+			note = "kept, --overwrite cannot delete from remote storage"
+		}
+		lines = append(lines, fmt.Sprintf("  - %d no longer listed (%s): %s", len(summary.Unlisted), note, existingExamples(summary.Unlisted)))
+	}
+	return lines
+}
+
+func existingModeFlag(options *downloadOptions, mode string) func(string) error {
+	return func(string) error {
+		if options.ExistingMode != "" && options.ExistingMode != mode {
+			return errors.New("--merge and --overwrite cannot be combined")
+		}
+		options.ExistingMode = mode
+		return nil
+	}
+}
+
+func confirmExistingArchive(mode string, input io.Reader, output io.Writer, interactive bool) func(string, existingSummary) (string, error) {
+	var mutex sync.Mutex
+	reader := bufio.NewReader(input)
+	return func(outputRoot string, summary existingSummary) (string, error) {
+		if mode != "" {
+			return mode, nil
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		if !interactive {
+			fmt.Fprintf(os.Stderr, "Warning: %s already exists, merging into it (pass --merge to silence this or --overwrite to replace it)\n", outputRoot)
+			return existingMerge, nil
+		}
+		for _, line := range summary.lines(outputRoot) {
+			fmt.Fprintln(output, line)
+		}
+		for {
+			fmt.Fprint(output, "[m]erge, [o]verwrite or [a]bort? ")
+			answer, err := reader.ReadString('\n')
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "m", existingMerge:
+				return existingMerge, nil
+			case "o", existingOverwrite:
+				return existingOverwrite, nil
+			case "a", "abort":
+				return "", errExistingAborted
+			}
+			if err != nil {
+				return "", errExistingAborted
+			}
+		}
+	}
+}
+
+func prepareExistingArchive(store storage, provider emoteProvider, channel *ChannelData, outputRoot string, listed bool, options downloadOptions, logFunc func(string)) (bool, error) {
+	if options.ConfirmExisting == nil {
+		return false, nil
+	}
+	previousManifest, err := loadManifest(store, outputRoot)
+	if err != nil || len(previousManifest.Emotes) == 0 {
+		return false, nil
+	}
+	summary := summarizeExisting(provider, channel, previousManifest, listed, options)
+	summary.Deletable = isLocalStorage(store)
+	mode, err := options.ConfirmExisting(outputRoot, summary)
+	if err != nil || mode != existingOverwrite {
+		return false, err
+	}
+	if isLocalStorage(store) {
+		if err := os.RemoveAll(outputRoot); err != nil {
+			return false, fmt.Errorf("cannot remove existing archive %s: %w", outputRoot, err)
+		}
+		logFunc(fmt.Sprintf("[overwrite] removed existing archive %s", filepath.Clean(outputRoot)))
+	}
+	return true, nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	streamDeckIconSize      = 72
+	streamDeckRetinaSize    = 144
+	streamDeckDefaultFolder = "streamdeck"
+
+	chatterinoPackFileName = "emotes.json"
+)
+
+type chatterinoPackEmote struct {
+	ID       string            `json:"id"`
+	Code     string            `json:"code"`
+	Animated bool              `json:"animated"`
+	URLs     map[string]string `json:"urls"`
+}
+
+type chatterinoPack struct {
+	Name      string                `json:"name"`
+	Provider  string                `json:"provider,omitempty"`
+	ChannelID string                `json:"channel_id,omitempty"`
+	UpdatedAt time.Time             `json:"updated_at"`
+	Emotes    []chatterinoPackEmote `json:"emotes"`
+}
+
+type exportTarget struct {
+	Name          string
+	Description   string
+	DefaultFolder string
+	Export        func(channelRoot string, manifest *channelManifest, exportRoot string, durable bool) (int, error)
+}
+
+var exportTargets = []exportTarget{
+	{"streamdeck", "72x72 and 144x144 PNG icons for the Elgato Stream Deck", streamDeckDefaultFolder, exportStreamDeck},
+	{"obs", "browser-source overlay that rains, cycles or tiles the emotes, configured by config.json", "obs", exportOBSOverlay},
+	{"chatterino", "self-contained emote pack with an emotes.json index for Chatterino local emote plugins", "chatterino", exportChatterinoPack},
+}
+
+func exportTargetNames() []string {
+	names := make([]string, 0, len(exportTargets))
+	for _, target := range exportTargets {
+		names = append(names, target.Name)
+	}
+	return names
+}
+
+func lookupExportTarget(name string) (exportTarget, error) {
+	for _, target := range exportTargets {
+		if target.Name == name {
+			return target, nil
+		}
+	}
+	return exportTarget{}, fmt.Errorf("unknown export target %q (available: %s)", name, strings.Join(exportTargetNames(), ", "))
+}
+
+func newExportFlagSet(outputDir *string, durable *bool) *flag.FlagSet {
+	flagSet := flag.NewFlagSet("export", flag.ContinueOnError)
+	flagSet.StringVar(outputDir, "output", "", "folder to export into (default: a folder named after the target inside each channel folder)")
+	flagSet.BoolVar(durable, "durable", false, "fsync exported files and their directories before moving on")
+	return flagSet
+}
+
+func exportImageFrame(channelRoot string, emote manifestEmote) (*image.NRGBA, error) {
+	var lastErr error
+	for index := len(emote.Files) - 1; index >= 0; index-- {
+		frames, _, err := decodeImageFrames(localStorage{}, filepath.Join(channelRoot, filepath.FromSlash(emote.Files[index].Path)))
+		if err == nil && len(frames) > 0 {
+			return frames[0].Image, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no downloaded files")
+	}
+	return nil, lastErr
+}
+
+func encodePNGFile(path string, frame image.Image, durable bool) error {
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, frame); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, buffer.Bytes(), durable)
+}
+
+func exportStreamDeck(channelRoot string, manifest *channelManifest, exportRoot string, durable bool) (int, error) {
+	if err := os.MkdirAll(exportRoot, 0o755); err != nil {
+		return 0, err
+	}
+
+	emotes := append([]manifestEmote(nil), manifest.Emotes...)
+	sort.SliceStable(emotes, func(left, right int) bool {
+		return strings.ToLower(emotes[left].Code) < strings.ToLower(emotes[right].Code)
+	})
+
+	exported := 0
+	for _, emote := range emotes {
+		frame, err := exportImageFrame(channelRoot, emote)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[skip] %s (%v)\n", emote.Code, err)
+			continue
+		}
+		trimmed := frame
+		if bounds := opaqueBounds(frame); !bounds.Empty() {
+			trimmed = cropNRGBA(frame, bounds)
+		}
+
+		baseName := emote.Folder
+		if baseName == "" {
+			baseName = emote.ID
+		}
+		icons := []struct {
+			Name string
+			Size int
+		}{
+			{baseName + ".png", streamDeckIconSize},
+			{baseName + "@2x.png", streamDeckRetinaSize},
+		}
+		for _, icon := range icons {
+			iconImage := fitNRGBA(trimmed, image.Pt(icon.Size, icon.Size), true)
+			if err := encodePNGFile(filepath.Join(exportRoot, icon.Name), iconImage, durable); err != nil {
+				return exported, err
+			}
+		}
+		exported++
+	}
+	return exported, nil
+}
+
+func exportChatterinoPack(channelRoot string, manifest *channelManifest, exportRoot string, durable bool) (int, error) {
+	pack := chatterinoPack{
+		Name:      manifest.ChannelName,
+		Provider:  manifest.Provider,
+		ChannelID: manifest.ChannelID,
+		UpdatedAt: manifest.UpdatedAt,
+		Emotes:    make([]chatterinoPackEmote, 0, len(manifest.Emotes)),
+	}
+	if pack.Name == "" {
+		pack.Name = manifest.ChannelID
+	}
+
+	providerName := manifest.Provider
+	if providerName == "" {
+		providerName = defaultProviderName
+	}
+	var sizes []string
+	if provider, err := lookupProvider(providerName); err == nil {
+		sizes = provider.Sizes()
+	}
+
+	for _, emote := range manifest.Emotes {
+		packEmote := chatterinoPackEmote{ID: emote.ID, Code: emote.Code, URLs: make(map[string]string)}
+		for index, file := range emote.Files {
+			imageBytes, err := os.ReadFile(filepath.Join(channelRoot, filepath.FromSlash(file.Path)))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[skip] %s (%v)\n", file.Path, err)
+				continue
+			}
+			if sizeIndex := slices.Index(sizes, file.Size); sizeIndex >= 0 {
+				index = sizeIndex
+			}
+			scale := fmt.Sprintf("%dx", index+1)
+			imagePath := path.Join("images", emote.ID, scale+filepath.Ext(file.Path))
+			outputPath := filepath.Join(exportRoot, filepath.FromSlash(imagePath))
+			if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
+				return len(pack.Emotes), err
+			}
+			if err := writeFileAtomic(outputPath, imageBytes, durable); err != nil {
+				return len(pack.Emotes), err
+			}
+			packEmote.URLs[scale] = imagePath
+			if file.ContentType == "image/gif" || strings.HasSuffix(file.Path, ".gif") {
+				packEmote.Animated = true
+			}
+		}
+		if len(packEmote.URLs) > 0 {
+			pack.Emotes = append(pack.Emotes, packEmote)
+		}
+	}
+
+	packBytes, err := json.MarshalIndent(pack, "", "  ")
+	if err != nil {
+		return len(pack.Emotes), err
+	}
+	return len(pack.Emotes), writeFileAtomic(filepath.Join(exportRoot, chatterinoPackFileName), append(packBytes, '\n'), durable)
+}
+
+func runExportCommand(arguments []string) int {
+	if len(arguments) == 0 || strings.HasPrefix(arguments[0], "-") {
+		fmt.Fprintf(os.Stderr, "Usage: twe-dlp export <%s> [--output DIR] <channel folder>...\n", strings.Join(exportTargetNames(), "|"))
+		return 2
+	}
+	target, err := lookupExportTarget(arguments[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	var outputDir string
+	var durable bool
+	flagSet := newExportFlagSet(&outputDir, &durable)
+	if err := flagSet.Parse(arguments[1:]); err != nil {
+		return exitCodeForParseError(err)
+	}
+	positional := flagSet.Args()
+	if len(positional) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: twe-dlp export %s [--output DIR] <channel folder>...\n", target.Name)
+		return 2
+	}
+
+	exitCode := 0
+	for _, channelRoot := range positional {
+		if _, err := os.Stat(filepath.Join(channelRoot, manifestFileName)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s has no %s; download the channel first\n", channelRoot, manifestFileName)
+			exitCode = 1
+			continue
+		}
+		manifest, err := loadManifest(localStorage{}, channelRoot)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exitCode = 1
+			continue
+		}
+
+		exportRoot := filepath.Join(channelRoot, target.DefaultFolder)
+		if outputDir != "" {
+			exportRoot = outputDir
+			if len(positional) > 1 {
+				exportRoot = filepath.Join(outputDir, filepath.Base(filepath.Clean(channelRoot)))
+			}
+		}
+		exported, err := target.Export(channelRoot, manifest, exportRoot, durable)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", channelRoot, err)
+			exitCode = 1
+			continue
+		}
+		fmt.Printf("Exported %d emotes to %s\n", exported, exportRoot)
+	}
+	return exitCode
+}
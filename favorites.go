@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const favoritesFileName = "favorites"
+
+func favoriteIndex(favorites []string, channelIdentifier string) int {
+	for index, favorite := range favorites {
+		if strings.EqualFold(favorite, channelIdentifier) {
+			return index
+		}
+	}
+	return -1
+}
+
+func addFavorite(favorites []string, channelIdentifier string) ([]string, bool) {
+	if favoriteIndex(favorites, channelIdentifier) >= 0 {
+		return favorites, false
+	}
+	return append(favorites, channelIdentifier), true
+}
+
+func removeFavorite(favorites []string, channelIdentifier string) ([]string, bool) {
+	index := favoriteIndex(favorites, channelIdentifier)
+	if index < 0 {
+		return favorites, false
+	}
+	return append(favorites[:index:index], favorites[index+1:]...), true
+}
+
+func runFavoritesCommand(arguments []string) int {
+	if len(arguments) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: twe-dlp fav add|remove <channel>... | twe-dlp fav list")
+		return 2
+	}
+
+	favorites := loadLines(favoritesFileName)
+	action, channelIdentifiers := arguments[0], arguments[1:]
+	switch action {
+	case "list":
+		for _, favorite := range favorites {
+			fmt.Println(favorite)
+		}
+		return 0
+	case "add", "remove":
+		if len(channelIdentifiers) == 0 {
+			fmt.Fprintf(os.Stderr, "Usage: twe-dlp fav %s <channel>...\n", action)
+			return 2
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown fav action %q (available: add, remove, list)\n", action)
+		return 2
+	}
+
+	for _, channelIdentifier := range channelIdentifiers {
+		channelIdentifier = strings.TrimSpace(channelIdentifier)
+		if channelIdentifier == "" {
+			continue
+		}
+		changed := false
+		if action == "add" {
+			favorites, changed = addFavorite(favorites, channelIdentifier)
+		} else {
+			favorites, changed = removeFavorite(favorites, channelIdentifier)
+		}
+		switch {
+		case changed && action == "add":
+			fmt.Printf("Added %s to favorites\n", channelIdentifier)
+		case changed:
+			fmt.Printf("Removed %s from favorites\n", channelIdentifier)
+		case action == "add":
+			fmt.Printf("%s is already a favorite\n", channelIdentifier)
+		default:
+			fmt.Printf("%s is not a favorite\n", channelIdentifier)
+		}
+	}
+
+	if err := saveLines(favoritesFileName, favorites); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving favorites: %v\n", err)
+		return 1
+	}
+	return 0
+}
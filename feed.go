@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	changesFileName   = "changes.json"
+	feedMaxEntries    = 50
+	feedThumbnailSize = 56
+	atomContentType   = "application/atom+xml; charset=utf-8"
+)
+
+type changeEntry struct {
+	Time    time.Time      `json:"time"`
+	Added   []webhookEmote `json:"added,omitempty"`
+	Removed []webhookEmote `json:"removed,omitempty"`
+}
+
+type channelChanges struct {
+	Provider    string        `json:"provider"`
+	ChannelID   string        `json:"channel_id"`
+	ChannelName string        `json:"channel_name,omitempty"`
+	Removed     []string      `json:"removed,omitempty"`
+	Entries     []changeEntry `json:"entries"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Content atomContent `xml:"content"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  string      `xml:"author>name"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type feedIndexEntry struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+func loadChannelChanges(store storage, outputRoot string) (*channelChanges, error) {
+	changesBytes, err := store.ReadFile(filepath.Join(outputRoot, changesFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	changes := &channelChanges{}
+	if err := json.Unmarshal(changesBytes, changes); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", changesFileName, err)
+	}
+	return changes, nil
+}
+
+func recordChannelChanges(store storage, outputRoot string, previous *channelManifest, current *channelManifest, listed map[string]EmoteData) (*changeEntry, error) {
+	changes, err := loadChannelChanges(store, outputRoot)
+	if err != nil {
+		return nil, err
+	}
+	baseline := changes == nil
+	if baseline {
+		changes = &channelChanges{Entries: []changeEntry{}}
+	}
+	changes.Provider, changes.ChannelID, changes.ChannelName = current.Provider, current.ChannelID, current.ChannelName
+
+	entry := changeEntry{Time: time.Now().UTC()}
+	for _, emote := range diffManifests(previous, current).Added {
+		if len(emote.Files) > 0 {
+			entry.Added = append(entry.Added, newWebhookEmote(emote))
+		}
+	}
+	var stillRemoved []string
+	currentEmotes := current.emotesByID()
+	for _, emoteID := range changes.Removed {
+		if _, relisted := listed[emoteID]; relisted {
+			if emote, known := currentEmotes[emoteID]; known {
+				entry.Added = append(entry.Added, newWebhookEmote(emote))
+			}
+			continue
+		}
+		stillRemoved = append(stillRemoved, emoteID)
+	}
+	for _, emote := range current.Emotes {
+		if _, stillListed := listed[emote.ID]; stillListed || slices.Contains(stillRemoved, emote.ID) {
+			continue
+		}
+		stillRemoved = append(stillRemoved, emote.ID)
+		entry.Removed = append(entry.Removed, newWebhookEmote(emote))
+	}
+	changed := baseline || !slices.Equal(changes.Removed, stillRemoved)
+	changes.Removed = stillRemoved
+
+	var recorded *changeEntry
+	if !baseline && (len(entry.Added) > 0 || len(entry.Removed) > 0) {
+		changes.Entries = append([]changeEntry{entry}, changes.Entries...)
+		if len(changes.Entries) > feedMaxEntries {
+			changes.Entries = changes.Entries[:feedMaxEntries]
+		}
+		recorded = &entry
+		changed = true
+	}
+	if !changed {
+		return nil, nil
+	}
+	changesBytes, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return recorded, store.WriteFile(filepath.Join(outputRoot, changesFileName), append(changesBytes, '\n'))
+}
+
+func (entry changeEntry) title(channelName string) string {
+	var parts []string
+	if len(entry.Added) > 0 {
+		parts = append(parts, fmt.Sprintf("%d added", len(entry.Added)))
+	}
+	if len(entry.Removed) > 0 {
+		parts = append(parts, fmt.Sprintf("%d removed", len(entry.Removed)))
+	}
+	return fmt.Sprintf("%s: emotes %s", channelName, strings.Join(parts, ", "))
+}
+
+func (entry changeEntry) html() string {
+	var builder strings.Builder
+	for _, section := range []struct {
+		heading string
+		emotes  []webhookEmote
+	}{{"Added", entry.Added}, {"Removed", entry.Removed}} {
+		if len(section.emotes) == 0 {
+			continue
+		}
+		fmt.Fprintf(&builder, "<h3>%s</h3><ul>", section.heading)
+		for _, emote := range section.emotes {
+			builder.WriteString("<li>")
+			if emote.ImageURL != "" {
+				fmt.Fprintf(&builder, `<img src="%s" alt="%s" height="%d"> `, html.EscapeString(emote.ImageURL), html.EscapeString(emote.Code), feedThumbnailSize)
+			}
+			fmt.Fprintf(&builder, "%s (%s)</li>", html.EscapeString(emote.Code), html.EscapeString(emote.ID))
+		}
+		builder.WriteString("</ul>")
+	}
+	return builder.String()
+}
+
+func buildAtomFeed(changes *channelChanges, selfURL string) atomFeed {
+	channelName := changes.ChannelName
+	if channelName == "" {
+		channelName = changes.ChannelID
+	}
+	feedID := fmt.Sprintf("urn:twe-dlp:%s:%s", changes.Provider, changes.ChannelID)
+	feed := atomFeed{
+		ID:      feedID,
+		Title:   fmt.Sprintf("Emote changes in %s (%s)", channelName, changes.Provider),
+		Updated: time.Unix(0, 0).UTC().Format(time.RFC3339),
+		Author:  "twe-dlp",
+		Links:   []atomLink{{Href: selfURL, Rel: "self"}},
+	}
+	for _, entry := range changes.Entries {
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:      fmt.Sprintf("%s:%d", feedID, entry.Time.UnixNano()),
+			Title:   entry.title(channelName),
+			Updated: entry.Time.Format(time.RFC3339),
+			Content: atomContent{Type: "html", Body: entry.html()},
+		})
+	}
+	if len(changes.Entries) > 0 {
+		feed.Updated = changes.Entries[0].Time.Format(time.RFC3339)
+	}
+	return feed
+}
+
+func requestBaseURL(request *http.Request) string {
+	scheme := "http"
+	if request.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + request.Host
+}
+
+func feedHandlers(store storage, channelRoots func() []string) (http.HandlerFunc, http.HandlerFunc) {
+	listFeeds := func(writer http.ResponseWriter, request *http.Request) {
+		entries := []feedIndexEntry{}
+		for _, channelRoot := range channelRoots() {
+			name := filepath.Base(channelRoot)
+			entries = append(entries, feedIndexEntry{Name: name, URL: requestBaseURL(request) + "/feeds/" + name + ".atom"})
+		}
+		writeJSON(writer, http.StatusOK, entries)
+	}
+	serveFeed := func(writer http.ResponseWriter, request *http.Request) {
+		name := strings.TrimSuffix(request.PathValue("name"), ".atom")
+		roots := channelRoots()
+		index := slices.IndexFunc(roots, func(channelRoot string) bool { return filepath.Base(channelRoot) == name })
+		if index < 0 {
+			writeJSONError(writer, http.StatusNotFound, errors.New("feed not found"))
+			return
+		}
+		changes, err := loadChannelChanges(store, roots[index])
+		if err != nil {
+			writeJSONError(writer, http.StatusInternalServerError, err)
+			return
+		}
+		if changes == nil {
+			writeJSONError(writer, http.StatusNotFound, errors.New("no changes recorded for this channel yet"))
+			return
+		}
+		feedBytes, err := xml.MarshalIndent(buildAtomFeed(changes, requestBaseURL(request)+request.URL.Path), "", "  ")
+		if err != nil {
+			writeJSONError(writer, http.StatusInternalServerError, err)
+			return
+		}
+		writer.Header().Set("Content-Type", atomContentType)
+		writer.Write([]byte(xml.Header))
+		writer.Write(append(feedBytes, '\n'))
+	}
+	return listFeeds, serveFeed
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var regexOnlyCharacters = regexp.MustCompile(`[.+()|^$\\{}]`)
+
+func compileEmoteFilter(pattern string) (*regexp.Regexp, error) {
+	if strings.ContainsAny(pattern, "*?") && !regexOnlyCharacters.MatchString(pattern) {
+		globExpression := regexp.QuoteMeta(pattern)
+		globExpression = strings.ReplaceAll(globExpression, `\*`, ".*")
+		globExpression = strings.ReplaceAll(globExpression, `\?`, ".")
+		globExpression = strings.ReplaceAll(globExpression, `\[`, "[")
+		globExpression = strings.ReplaceAll(globExpression, `\]`, "]")
+		pattern = "^" + globExpression + "$"
+	}
+
+	expression, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", pattern, err)
+	}
+	return expression, nil
+}
+
+func splitCommaList(value string) []string {
+	parts := strings.Split(value, ",")
+	items := make([]string, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			items = append(items, part)
+		}
+	}
+	return items
+}
+
+func matchesEmoteList(list []string, emoteIdentifier string, emoteData EmoteData) bool {
+	for _, entry := range list {
+		if strings.EqualFold(entry, emoteData.EmoteCode) || entry == emoteIdentifier {
+			return true
+		}
+	}
+	return false
+}
+
+func filterEmotes(emotes map[string]EmoteData, options downloadOptions) map[string]EmoteData {
+	if len(options.Only) == 0 && len(options.Exclude) == 0 && len(options.Filters) == 0 && options.Top <= 0 {
+		return emotes
+	}
+
+	filtered := make(map[string]EmoteData, len(emotes))
+	for emoteIdentifier, emoteData := range emotes {
+		if emoteSelected(emoteIdentifier, emoteData, options) {
+			filtered[emoteIdentifier] = emoteData
+		}
+	}
+	if options.Top > 0 {
+		filtered = mostUsedEmotes(filtered, options.Top)
+	}
+	return filtered
+}
+
+func hasUsageCounts(emotes map[string]EmoteData) bool {
+	for _, emoteData := range emotes {
+		if emoteData.Uses > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func mostUsedEmotes(emotes map[string]EmoteData, count int) map[string]EmoteData {
+	if len(emotes) <= count {
+		return emotes
+	}
+	emoteIdentifiers := make([]string, 0, len(emotes))
+	for emoteIdentifier := range emotes {
+		emoteIdentifiers = append(emoteIdentifiers, emoteIdentifier)
+	}
+	sort.Slice(emoteIdentifiers, func(left, right int) bool {
+		leftEmote, rightEmote := emotes[emoteIdentifiers[left]], emotes[emoteIdentifiers[right]]
+		if leftEmote.Uses != rightEmote.Uses {
+			return leftEmote.Uses > rightEmote.Uses
+		}
+		return emoteIdentifiers[left] < emoteIdentifiers[right]
+	})
+
+	top := make(map[string]EmoteData, count)
+	for _, emoteIdentifier := range emoteIdentifiers[:count] {
+		top[emoteIdentifier] = emotes[emoteIdentifier]
+	}
+	return top
+}
+
+func emoteSelected(emoteIdentifier string, emoteData EmoteData, options downloadOptions) bool {
+	if len(options.Only) > 0 && !matchesEmoteList(options.Only, emoteIdentifier, emoteData) {
+		return false
+	}
+	if matchesEmoteList(options.Exclude, emoteIdentifier, emoteData) {
+		return false
+	}
+	if len(options.Filters) > 0 && !matchesEmoteFilters(options.Filters, emoteData.EmoteCode) {
+		return false
+	}
+	return true
+}
+
+func matchesEmoteFilters(filters []*regexp.Regexp, emoteCode string) bool {
+	for _, filter := range filters {
+		if filter.MatchString(emoteCode) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseNewerThan(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.DateOnly, time.RFC3339, time.DateTime} {
+		if date, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return date, nil
+		}
+	}
+	if days, isDays := strings.CutSuffix(value, "d"); isDays {
+		if count, err := strconv.Atoi(days); err == nil && count >= 0 {
+			return now.AddDate(0, 0, -count), nil
+		}
+	}
+	if duration, err := time.ParseDuration(value); err == nil && duration >= 0 {
+		return now.Add(-duration), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --newer-than %q, expected a date like 2024-01-01 or an age like 30d", value)
+}
+
+func parseEmoteDate(value string) time.Time {
+	for _, layout := range []string{time.RFC3339Nano, time.DateTime, time.DateOnly} {
+		if date, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+			return date.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+func emoteAddedAt(emoteIdentifier string, emoteData EmoteData, previousEmotes map[string]manifestEmote, now time.Time) time.Time {
+	if !emoteData.CreatedAt.IsZero() {
+		return emoteData.CreatedAt
+	}
+	if previousEmote, seen := previousEmotes[emoteIdentifier]; seen {
+		return previousEmote.AddedAt
+	}
+	return now
+}
+
+func emoteNewerThan(emoteIdentifier string, emoteData EmoteData, previousEmotes map[string]manifestEmote, since time.Time) bool {
+	return since.IsZero() || emoteAddedAt(emoteIdentifier, emoteData, previousEmotes, time.Now().UTC()).After(since)
+}
+
+func filterNewerThan(emotes map[string]EmoteData, previousEmotes map[string]manifestEmote, since time.Time) map[string]EmoteData {
+	if since.IsZero() {
+		return emotes
+	}
+	filtered := make(map[string]EmoteData, len(emotes))
+	for emoteIdentifier, emoteData := range emotes {
+		if emoteNewerThan(emoteIdentifier, emoteData, previousEmotes, since) {
+			filtered[emoteIdentifier] = emoteData
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	fixtureBodySuffix = ".body"
+	fixtureMetaSuffix = ".json"
+	fixtureNameLength = 80
+)
+
+var fixtureUnsafePattern = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+var fixtureHeaders = []string{"Content-Type", "ETag", "Last-Modified", "Cache-Control", "Retry-After"}
+
+type fixtureRecord struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Status     int         `json:"status"`
+	Header     http.Header `json:"header,omitempty"`
+	RecordedAt time.Time   `json:"recorded_at"`
+}
+
+type fixtureRecorder struct {
+	base      http.RoundTripper
+	directory string
+}
+
+func fixtureName(request *http.Request) (string, error) {
+	hash := sha256.New()
+	io.WriteString(hash, request.Method+" "+request.URL.String()+"\n")
+	if request.Body != nil && request.Body != http.NoBody {
+		requestBody, err := io.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		request.Body = io.NopCloser(bytes.NewReader(requestBody))
+		hash.Write(requestBody)
+	}
+
+	readable := fixtureUnsafePattern.ReplaceAllString(request.URL.Hostname()+request.URL.Path, "_")
+	if len(readable) > fixtureNameLength {
+		readable = readable[:fixtureNameLength]
+	}
+	return strings.Trim(readable, "_") + "_" + hex.EncodeToString(hash.Sum(nil))[:16], nil
+}
+
+func (recorder *fixtureRecorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	request = request.Clone(request.Context())
+	name, err := fixtureName(request)
+	if err != nil {
+		return nil, err
+	}
+	response, err := recorder.base.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	response.Body = io.NopCloser(bytes.NewReader(responseBody))
+	if err != nil {
+		return response, nil
+	}
+
+	record := fixtureRecord{
+		Method:     request.Method,
+		URL:        request.URL.String(),
+		Status:     response.StatusCode,
+		Header:     make(http.Header),
+		RecordedAt: time.Now().UTC(),
+	}
+	for _, header := range fixtureHeaders {
+		if value := response.Header.Get(header); value != "" {
+			record.Header.Set(header, value)
+		}
+	}
+	recordBytes, err := json.MarshalIndent(record, "", "  ")
+	if err == nil && os.MkdirAll(recorder.directory, 0o755) == nil {
+		basePath := filepath.Join(recorder.directory, name)
+		if writeFileAtomic(basePath+fixtureBodySuffix, responseBody, false) == nil {
+			writeFileAtomic(basePath+fixtureMetaSuffix, append(recordBytes, '\n'), false)
+		}
+	}
+	return response, nil
+}
+
+type fixtureReplayer struct {
+	directory string
+}
+
+func (replayer *fixtureReplayer) RoundTrip(request *http.Request) (*http.Response, error) {
+	request = request.Clone(request.Context())
+	name, err := fixtureName(request)
+	if err != nil {
+		return nil, err
+	}
+	basePath := filepath.Join(replayer.directory, name)
+	recordBytes, err := os.ReadFile(basePath + fixtureMetaSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("offline: %s %s was not recorded in %s", request.Method, request.URL, replayer.directory)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record fixtureRecord
+	if err := json.Unmarshal(recordBytes, &record); err != nil {
+		return nil, fmt.Errorf("offline: %s: %w", basePath+fixtureMetaSuffix, err)
+	}
+	responseBody, err := os.ReadFile(basePath + fixtureBodySuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	header := record.Header
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", record.Status, http.StatusText(record.Status)),
+		StatusCode:    record.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(responseBody)),
+		ContentLength: int64(len(responseBody)),
+		Request:       request,
+	}, nil
+}
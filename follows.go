@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const followsPageSize = 100
+
+var (
+	twitchValidateURL = "https://id.twitch.tv/oauth2/validate"
+	twitchFollowedURL = "https://api.twitch.tv/helix/channels/followed"
+)
+
+type followsOptions struct {
+	Token   string
+	Restart bool
+}
+
+type twitchTokenInfo struct {
+	ClientID string `json:"client_id"`
+	Login    string `json:"login"`
+	UserID   string `json:"user_id"`
+}
+
+type followedChannel struct {
+	ID    string `json:"id"`
+	Login string `json:"login"`
+}
+
+type followsProgress struct {
+	User      string            `json:"user"`
+	StartedAt time.Time         `json:"started_at"`
+	Completed map[string]string `json:"completed"`
+	Failed    map[string]string `json:"failed,omitempty"`
+}
+
+func validateTwitchToken(httpClient *http.Client, token string) (*twitchTokenInfo, error) {
+	request, err := http.NewRequest(http.MethodGet, twitchValidateURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "OAuth "+token)
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusUnauthorized {
+		return nil, errors.New("the Twitch token is invalid or expired")
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token validation failed with status %s", response.Status)
+	}
+	var info twitchTokenInfo
+	if err := json.NewDecoder(response.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	if info.ClientID == "" || info.UserID == "" {
+		return nil, errors.New("the Twitch token is not a user access token")
+	}
+	return &info, nil
+}
+
+func fetchFollowedChannels(httpClient *http.Client, token string, info *twitchTokenInfo) ([]followedChannel, error) {
+	var channels []followedChannel
+	cursor := ""
+	for {
+		query := url.Values{"user_id": {info.UserID}, "first": {fmt.Sprint(followsPageSize)}}
+		if cursor != "" {
+			query.Set("after", cursor)
+		}
+		request, err := http.NewRequest(http.MethodGet, twitchFollowedURL+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Authorization", "Bearer "+token)
+		request.Header.Set("Client-Id", info.ClientID)
+		response, err := httpClient.Do(request)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Data []struct {
+				BroadcasterID    string `json:"broadcaster_id"`
+				BroadcasterLogin string `json:"broadcaster_login"`
+			} `json:"data"`
+			Pagination struct {
+				Cursor string `json:"cursor"`
+			} `json:"pagination"`
+			Message string `json:"message"`
+		}
+		err = json.NewDecoder(response.Body).Decode(&page)
+		response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			if page.Message != "" {
+				return nil, fmt.Errorf("fetching followed channels failed with status %s: %s", response.Status, page.Message)
+			}
+			return nil, fmt.Errorf("fetching followed channels failed with status %s", response.Status)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, followed := range page.Data {
+			channels = append(channels, followedChannel{ID: followed.BroadcasterID, Login: followed.BroadcasterLogin})
+		}
+		if page.Pagination.Cursor == "" || len(page.Data) == 0 {
+			return channels, nil
+		}
+		cursor = page.Pagination.Cursor
+	}
+}
+
+func followsProgressPath(outputDir string, user string) string {
+	return filepath.Join(outputDir, ".follows-"+strings.ToLower(user)+".json")
+}
+
+func loadFollowsProgress(path string, user string) *followsProgress {
+	progress := &followsProgress{User: user, StartedAt: time.Now(), Completed: make(map[string]string)}
+	progressBytes, err := os.ReadFile(path)
+	if err != nil {
+		return progress
+	}
+	var saved followsProgress
+	if err := json.Unmarshal(progressBytes, &saved); err != nil || !strings.EqualFold(saved.User, user) {
+		return progress
+	}
+	if saved.Completed == nil {
+		saved.Completed = make(map[string]string)
+	}
+	saved.Failed = nil
+	return &saved
+}
+
+func saveFollowsProgress(path string, progress *followsProgress) error {
+	progressBytes, err := json.MarshalIndent(progress, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(progressBytes, '\n'), false)
+}
+
+func newFollowsFlagSet(options *downloadOptions, follows *followsOptions) *flag.FlagSet {
+	flagSet := newCommandFlagSet("follows", options)
+	flagSet.StringVar(&follows.Token, "token", os.Getenv("TWITCH_OAUTH_TOKEN"), "Twitch user access token with the user:read:follows scope (default: the token saved by \"twe-dlp auth login twitch\")")
+	flagSet.BoolVar(&follows.Restart, "restart", false, "ignore the saved progress and download every followed channel again")
+	return flagSet
+}
+
+func runFollowsCommand(arguments []string) int {
+	var options downloadOptions
+	var follows followsOptions
+
+	flagSet := newFollowsFlagSet(&options, &follows)
+	positional, err := parseCommandLine(flagSet, &options, arguments)
+	if err != nil {
+		return exitCodeForParseError(err)
+	}
+	if len(positional) != 1 || strings.TrimSpace(positional[0]) == "" {
+		fmt.Fprintln(os.Stderr, "Usage: twe-dlp follows [--token <token>] [--restart] <user>")
+		return 2
+	}
+	user := strings.TrimSpace(positional[0])
+	token := strings.TrimPrefix(strings.TrimSpace(follows.Token), "oauth:")
+	if token == "" {
+		token = lookupCredential("twitch")
+	}
+	if token == "" {
+		fmt.Fprintln(os.Stderr, "Error: follows needs a Twitch token; pass --token or run \"twe-dlp auth login twitch\"")
+		return 2
+	}
+	httpClient := createHTTPClient(options)
+
+	info, err := validateTwitchToken(httpClient, token)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if !strings.EqualFold(info.Login, user) {
+		fmt.Fprintf(os.Stderr, "Error: the token belongs to %s, not %s\n", info.Login, user)
+		return 1
+	}
+	channels, err := fetchFollowedChannels(httpClient, token, info)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(channels) == 0 {
+		fmt.Printf("%s does not follow any channels\n", info.Login)
+		return 0
+	}
+
+	progressPath := followsProgressPath(options.OutputDir, info.Login)
+	progress := loadFollowsProgress(progressPath, info.Login)
+	if follows.Restart {
+		progress = &followsProgress{User: info.Login, StartedAt: time.Now(), Completed: make(map[string]string)}
+	}
+
+	logins := make(map[string]string, len(channels))
+	pending := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		if _, done := progress.Completed[channel.ID]; done {
+			continue
+		}
+		channelIdentifier := "twitch:" + channel.ID
+		logins[channelIdentifier] = channel.Login
+		pending = append(pending, channelIdentifier)
+	}
+	skipped := len(channels) - len(pending)
+	if skipped > 0 {
+		fmt.Printf("%s follows %d channels; %d already archived, resuming with %d\n", info.Login, len(channels), skipped, len(pending))
+	} else {
+		fmt.Printf("%s follows %d channels\n", info.Login, len(channels))
+	}
+	if len(pending) == 0 {
+		fmt.Printf("Every followed channel is archived; use --restart to download them again\n")
+		return 0
+	}
+
+	var progressLock sync.Mutex
+	options.ChannelDone = func(channelIdentifier string, err error) {
+		progressLock.Lock()
+		defer progressLock.Unlock()
+		channelID := strings.TrimPrefix(channelIdentifier, "twitch:")
+		if err != nil {
+			if progress.Failed == nil {
+				progress.Failed = make(map[string]string)
+			}
+			progress.Failed[channelID] = logins[channelIdentifier]
+		} else {
+			delete(progress.Failed, channelID)
+			progress.Completed[channelID] = logins[channelIdentifier]
+		}
+		if err := saveFollowsProgress(progressPath, progress); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: cannot save %s: %v\n", progressPath, err)
+		}
+	}
+	return runBatchMode(httpClient, pending, options)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const galleryFileName = "index.html"
+
+//go:embed web/gallery.html
+var galleryTemplateSource string
+
+var galleryTemplate = template.Must(template.New("gallery").Parse(galleryTemplateSource))
+
+type galleryEmote struct {
+	ID      string
+	Code    string
+	Preview *manifestFile
+	Files   []manifestFile
+}
+
+type galleryPage struct {
+	Title     string
+	Provider  string
+	ChannelID string
+	Avatar    string
+	UpdatedAt string
+	Emotes    []galleryEmote
+}
+
+func writeGallery(store storage, outputRoot string, manifest *channelManifest) error {
+	page := galleryPage{
+		Title:     manifest.ChannelName,
+		Provider:  manifest.Provider,
+		ChannelID: manifest.ChannelID,
+		UpdatedAt: manifest.UpdatedAt.Local().Format(time.DateTime),
+		Emotes:    make([]galleryEmote, 0, len(manifest.Emotes)),
+	}
+	if page.Title == "" {
+		page.Title = manifest.ChannelID
+	}
+	if manifest.Metadata != nil {
+		page.Avatar = manifest.Metadata.ProfileImage
+	}
+
+	for _, emote := range manifest.Emotes {
+		if len(emote.Files) == 0 {
+			continue
+		}
+		page.Emotes = append(page.Emotes, galleryEmote{
+			ID:      emote.ID,
+			Code:    emote.Code,
+			Preview: &emote.Files[len(emote.Files)-1],
+			Files:   emote.Files,
+		})
+	}
+	sort.SliceStable(page.Emotes, func(left, right int) bool {
+		return strings.ToLower(page.Emotes[left].Code) < strings.ToLower(page.Emotes[right].Code)
+	})
+
+	var buffer bytes.Buffer
+	if err := galleryTemplate.Execute(&buffer, page); err != nil {
+		return err
+	}
+	return store.WriteFile(filepath.Join(outputRoot, galleryFileName), buffer.Bytes())
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	compressedGIFSuffix = "_compressed.gif"
+	gifMinPaletteBits   = 3
+)
+
+type gifAnalysis struct {
+	Frames          int
+	DuplicateFrames int
+	Colors          int
+	Bytes           int64
+}
+
+type gifRecompression struct {
+	Data  []byte
+	Steps []string
+	Fits  bool
+}
+
+func analyzeGIF(animation *gif.GIF, size int64) gifAnalysis {
+	colors := make(map[color.RGBA]bool)
+	for _, frame := range animation.Image {
+		used := make([]bool, len(frame.Palette))
+		for _, index := range frame.Pix {
+			if int(index) < len(used) {
+				used[index] = true
+			}
+		}
+		for index, isUsed := range used {
+			if isUsed {
+				red, green, blue, alpha := frame.Palette[index].RGBA()
+				colors[color.RGBA{uint8(red >> 8), uint8(green >> 8), uint8(blue >> 8), uint8(alpha >> 8)}] = true
+			}
+		}
+	}
+	return gifAnalysis{
+		Frames:          len(animation.Image),
+		DuplicateFrames: len(animation.Image) - len(dedupeGIFFrames(animation).Image),
+		Colors:          len(colors),
+		Bytes:           size,
+	}
+}
+
+func gifFramesEqual(left *image.Paletted, right *image.Paletted) bool {
+	if left.Rect != right.Rect || len(left.Palette) != len(right.Palette) || !bytes.Equal(left.Pix, right.Pix) {
+		return false
+	}
+	for index := range left.Palette {
+		if left.Palette[index] != right.Palette[index] {
+			return false
+		}
+	}
+	return true
+}
+
+func dedupeGIFFrames(animation *gif.GIF) *gif.GIF {
+	deduped := &gif.GIF{
+		LoopCount:       animation.LoopCount,
+		Config:          animation.Config,
+		BackgroundIndex: animation.BackgroundIndex,
+	}
+	for index, frame := range animation.Image {
+		disposal := byte(0)
+		if index < len(animation.Disposal) {
+			disposal = animation.Disposal[index]
+		}
+		last := len(deduped.Image) - 1
+		if last >= 0 && deduped.Disposal[last] <= gif.DisposalNone && disposal <= gif.DisposalNone && gifFramesEqual(deduped.Image[last], frame) {
+			deduped.Delay[last] += animation.Delay[index]
+			continue
+		}
+		deduped.Image = append(deduped.Image, frame)
+		deduped.Delay = append(deduped.Delay, animation.Delay[index])
+		deduped.Disposal = append(deduped.Disposal, disposal)
+	}
+	return deduped
+}
+
+func reduceGIFPalette(animation *gif.GIF, bits uint) *gif.GIF {
+	mask := uint8(0xff << (8 - bits))
+	reduced := *animation
+	reduced.Image = make([]*image.Paletted, len(animation.Image))
+	for frameIndex, frame := range animation.Image {
+		var palette color.Palette
+		positions := make(map[color.RGBA]uint8)
+		remap := make([]uint8, len(frame.Palette))
+		for index, paletteColor := range frame.Palette {
+			red, green, blue, alpha := paletteColor.RGBA()
+			key := color.RGBA{uint8(red>>8) & mask, uint8(green>>8) & mask, uint8(blue>>8) & mask, uint8(alpha >> 8)}
+			if key.A == 0 {
+				key = color.RGBA{}
+			} else {
+				key.A = 0xff
+			}
+			position, exists := positions[key]
+			if !exists {
+				position = uint8(len(palette))
+				positions[key] = position
+				palette = append(palette, key)
+			}
+			remap[index] = position
+		}
+
+		reducedFrame := image.NewPaletted(frame.Rect, palette)
+		for pixelIndex, index := range frame.Pix {
+			if int(index) < len(remap) {
+				reducedFrame.Pix[pixelIndex] = remap[index]
+			}
+		}
+		reduced.Image[frameIndex] = reducedFrame
+	}
+	return &reduced
+}
+
+func encodeGIF(animation *gif.GIF) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := gif.EncodeAll(&buffer, animation); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func recompressGIF(animation *gif.GIF, original []byte, limit int64) (gifRecompression, error) {
+	best := gifRecompression{Data: original}
+	deduped := dedupeGIFFrames(animation)
+	steps := []string{}
+	if len(deduped.Image) < len(animation.Image) {
+		steps = append(steps, fmt.Sprintf("%d duplicate frames merged", len(animation.Image)-len(deduped.Image)))
+	}
+
+	candidate := deduped
+	for bits := uint(8); bits >= gifMinPaletteBits; bits-- {
+		candidateSteps := steps
+		if bits < 8 {
+			candidate = reduceGIFPalette(deduped, bits)
+			candidateSteps = append(slices.Clone(steps), fmt.Sprintf("%d-bit color", bits))
+		}
+		encoded, err := encodeGIF(candidate)
+		if err != nil {
+			return best, err
+		}
+		if len(encoded) < len(best.Data) {
+			best = gifRecompression{Data: encoded, Steps: candidateSteps}
+		}
+		if int64(len(best.Data)) <= limit {
+			best.Fits = true
+			return best, nil
+		}
+	}
+	return best, nil
+}
+
+func newGIFFlagSet(limit *string) *flag.FlagSet {
+	flagSet := flag.NewFlagSet("gif", flag.ContinueOnError)
+	flagSet.StringVar(limit, "limit", "", "write <emote>_compressed.gif for GIFs larger than this size (e.g. 256K), merging duplicate frames and reducing colors until it fits")
+	return flagSet
+}
+
+func collectGIFFiles(paths []string) []string {
+	var files []string
+	for _, path := range paths {
+		filepath.WalkDir(path, func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return nil
+			}
+			if strings.EqualFold(filepath.Ext(filePath), ".gif") && !strings.HasSuffix(filePath, compressedGIFSuffix) {
+				files = append(files, filePath)
+			}
+			return nil
+		})
+	}
+	return files
+}
+
+func runGIFCommand(arguments []string) int {
+	var limitValue string
+	flagSet := newGIFFlagSet(&limitValue)
+	if err := flagSet.Parse(arguments); err != nil {
+		return exitCodeForParseError(err)
+	}
+	if flagSet.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: twe-dlp gif [--limit SIZE] <channel folder|file.gif>...")
+		return 2
+	}
+	var limit int64
+	if limitValue != "" {
+		var err error
+		if limit, err = parseByteSize(limitValue); err != nil || limit <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: invalid --limit %q\n", limitValue)
+			return 2
+		}
+	}
+
+	exitCode := 0
+	var totalBefore, totalAfter int64
+	for _, filePath := range collectGIFFiles(flagSet.Args()) {
+		data, err := os.ReadFile(filePath)
+		if err == nil {
+			var animation *gif.GIF
+			if animation, err = gif.DecodeAll(bytes.NewReader(data)); err == nil {
+				analysis := analyzeGIF(animation, int64(len(data)))
+				line := fmt.Sprintf("%s: %d frames (%d duplicate), %d colors, %s", filePath, analysis.Frames, analysis.DuplicateFrames, analysis.Colors, formatByteSize(analysis.Bytes))
+				if limit > 0 && analysis.Bytes > limit {
+					var result gifRecompression
+					if result, err = recompressGIF(animation, data, limit); err == nil {
+						compressedPath := strings.TrimSuffix(filePath, filepath.Ext(filePath)) + compressedGIFSuffix
+						if err = writeFileAtomic(compressedPath, result.Data, false); err == nil {
+							status := "fits"
+							if !result.Fits {
+								status = "still over the limit"
+								exitCode = 1
+							}
+							steps := strings.Join(result.Steps, ", ")
+							if steps == "" {
+								steps = "re-encoded"
+							}
+							line += fmt.Sprintf(" -> %s (%s), %s", formatByteSize(int64(len(result.Data))), steps, status)
+							totalBefore += analysis.Bytes
+							totalAfter += int64(len(result.Data))
+						}
+					}
+				} else if limit > 0 {
+					line += ", fits"
+				}
+				if err == nil {
+					fmt.Println(line)
+					continue
+				}
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", filePath, err)
+		exitCode = 1
+	}
+	if totalBefore > 0 {
+		fmt.Printf("Recompressed %s to %s\n", formatByteSize(totalBefore), formatByteSize(totalAfter))
+	}
+	return exitCode
+}
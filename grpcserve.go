@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	twedlpv1 "github.com/odesaur/twe-dlp/proto/twedlp/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+//go:generate protoc --proto_path=proto --go_out=proto --go_opt=paths=source_relative --go-grpc_out=proto --go-grpc_opt=paths=source_relative twedlp/v1/jobs.proto
+
+const grpcWatchInterval = 250 * time.Millisecond
+
+type grpcJobService struct {
+	twedlpv1.UnimplementedJobServiceServer
+	server *downloadServer
+}
+
+var grpcJobStatuses = map[string]twedlpv1.JobStatus{
+	jobStatusQueued:  twedlpv1.JobStatus_JOB_STATUS_QUEUED,
+	jobStatusRunning: twedlpv1.JobStatus_JOB_STATUS_RUNNING,
+	jobStatusDone:    twedlpv1.JobStatus_JOB_STATUS_DONE,
+	jobStatusFailed:  twedlpv1.JobStatus_JOB_STATUS_FAILED,
+}
+
+func grpcTimestamp(value *time.Time) *timestamppb.Timestamp {
+	if value == nil || value.IsZero() {
+		return nil
+	}
+	return timestamppb.New(*value)
+}
+
+func (job downloadJob) proto() *twedlpv1.Job {
+	return &twedlpv1.Job{
+		Id:         job.ID,
+		Channel:    job.Channel,
+		Only:       job.Only,
+		Exclude:    job.Exclude,
+		Status:     grpcJobStatuses[job.Status],
+		Error:      job.Error,
+		CreatedAt:  grpcTimestamp(&job.CreatedAt),
+		StartedAt:  grpcTimestamp(job.StartedAt),
+		FinishedAt: grpcTimestamp(job.FinishedAt),
+		OutputDir:  job.OutputDir,
+	}
+}
+
+func (job downloadJob) progressProto() *twedlpv1.Progress {
+	if job.Status != jobStatusRunning {
+		return nil
+	}
+	progress := &twedlpv1.Progress{
+		EmotesDone:     int32(job.progress.EmotesDone),
+		EmotesTotal:    int32(job.progress.EmotesTotal),
+		Bytes:          job.progress.ChannelBytes,
+		BytesPerSecond: job.progress.channelSpeed(),
+		EtaSeconds:     -1,
+		Emote:          job.progress.EmoteCode,
+	}
+	if eta, known := job.progress.remaining(); known {
+		progress.EtaSeconds = int64(eta.Round(time.Second) / time.Second)
+	}
+	return progress
+}
+
+func (service grpcJobService) CreateJob(ctx context.Context, request *twedlpv1.CreateJobRequest) (*twedlpv1.Job, error) {
+	channel := strings.TrimSpace(request.Channel)
+	if channel == "" {
+		return nil, status.Error(codes.InvalidArgument, "channel is required")
+	}
+	if _, _, err := selectProvider(channel, service.server.options.Provider); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	job := service.server.enqueue(downloadRequest{Channel: channel, Only: request.Only, Exclude: request.Exclude})
+	return job.proto(), nil
+}
+
+func (service grpcJobService) GetJob(ctx context.Context, request *twedlpv1.GetJobRequest) (*twedlpv1.Job, error) {
+	job, exists := service.server.snapshot(request.Id)
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "job %q not found", request.Id)
+	}
+	return job.proto(), nil
+}
+
+func (service grpcJobService) ListJobs(ctx context.Context, request *twedlpv1.ListJobsRequest) (*twedlpv1.ListJobsResponse, error) {
+	response := &twedlpv1.ListJobsResponse{}
+	for _, job := range service.server.list() {
+		response.Jobs = append(response.Jobs, job.proto())
+	}
+	return response, nil
+}
+
+func (service grpcJobService) WatchJob(request *twedlpv1.WatchJobRequest, stream grpc.ServerStreamingServer[twedlpv1.JobEvent]) error {
+	ticker := time.NewTicker(grpcWatchInterval)
+	defer ticker.Stop()
+	var previous *twedlpv1.JobEvent
+	sentLines := 0
+	for {
+		job, exists := service.server.snapshot(request.Id)
+		if !exists {
+			return status.Errorf(codes.NotFound, "job %q not found", request.Id)
+		}
+		event := &twedlpv1.JobEvent{Job: job.proto(), Progress: job.progressProto()}
+		newLines := min(job.logCount-sentLines, len(job.Logs))
+		if newLines > 0 {
+			event.LogLines = job.Logs[len(job.Logs)-newLines:]
+		}
+		sentLines = job.logCount
+		if previous == nil || len(event.LogLines) > 0 || !proto.Equal(event.Job, previous.Job) || !proto.Equal(event.Progress, previous.Progress) {
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+			previous = event
+		}
+		if job.Status == jobStatusDone || job.Status == jobStatusFailed {
+			return nil
+		}
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (server *downloadServer) serveGRPC(listenAddress string) error {
+	listener, err := net.Listen("tcp", listenAddress)
+	if err != nil {
+		return err
+	}
+	grpcServer := grpc.NewServer()
+	twedlpv1.RegisterJobServiceServer(grpcServer, grpcJobService{server: server})
+	reflection.Register(grpcServer)
+	return grpcServer.Serve(listener)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	historyFileName   = "history"
+	historyMaxEntries = 100
+)
+
+func configDir() (string, error) {
+	configRoot, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configRoot, "twe-dlp"), nil
+}
+
+func loadLines(fileName string) []string {
+	directory, err := configDir()
+	if err != nil {
+		return nil
+	}
+	contents, err := os.ReadFile(filepath.Join(directory, fileName))
+	if err != nil {
+		return nil
+	}
+
+	lines := make([]string, 0)
+	for _, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func saveLines(fileName string, lines []string) error {
+	directory, err := configDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return err
+	}
+	contents := strings.Join(lines, "\n")
+	if contents != "" {
+		contents += "\n"
+	}
+	return writeFileAtomic(filepath.Join(directory, fileName), []byte(contents), false)
+}
+
+func addHistoryEntry(history []string, entry string) []string {
+	updated := make([]string, 0, len(history)+1)
+	for _, existing := range history {
+		if existing != entry {
+			updated = append(updated, existing)
+		}
+	}
+	updated = append(updated, entry)
+	if len(updated) > historyMaxEntries {
+		updated = updated[len(updated)-historyMaxEntries:]
+	}
+	return updated
+}
+
+func historySuggestions(history []string) []string {
+	suggestions := make([]string, 0, len(history))
+	for index := len(history) - 1; index >= 0; index-- {
+		suggestions = append(suggestions, history[index])
+	}
+	return suggestions
+}
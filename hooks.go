@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const hookPlaceholder = "{}"
+
+func hookQuote(argument string) string {
+	if runtime.GOOS == "windows" {
+		return `"` + strings.ReplaceAll(argument, `"`, `""`) + `"`
+	}
+	return "'" + strings.ReplaceAll(argument, "'", `'\''`) + "'"
+}
+
+func expandHookCommand(command string, paths []string) string {
+	quoted := make([]string, 0, len(paths))
+	for _, path := range paths {
+		quoted = append(quoted, hookQuote(path))
+	}
+	arguments := strings.Join(quoted, " ")
+	if strings.Contains(command, hookPlaceholder) {
+		return strings.ReplaceAll(command, hookPlaceholder, arguments)
+	}
+	return command + " " + arguments
+}
+
+func runHook(name string, command string, paths []string, environment []string, logFunc func(string)) {
+	expanded := expandHookCommand(command, paths)
+	var shell *exec.Cmd
+	if runtime.GOOS == "windows" {
+		shell = exec.Command("cmd", "/C", expanded)
+	} else {
+		shell = exec.Command("sh", "-c", expanded)
+	}
+	shell.Env = append(os.Environ(), environment...)
+	output, err := shell.CombinedOutput()
+	for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		if line != "" {
+			logFunc(fmt.Sprintf("[%s] %s", name, line))
+		}
+	}
+	if err != nil {
+		logFunc(fmt.Sprintf("[error] %s hook failed: %v", name, err))
+	}
+}
+
+func runEmoteHook(outputRoot string, channel *ChannelData, emote manifestEmote, emoteReport reportEmote, options downloadOptions, logFunc func(string)) {
+	if options.ExecAfterEmote == "" {
+		return
+	}
+	var paths []string
+	for _, image := range emoteReport.Images {
+		if image.Status == imageStatusDownloaded && image.File != "" {
+			paths = append(paths, filepath.Join(outputRoot, filepath.FromSlash(image.File)))
+		}
+	}
+	if len(paths) == 0 {
+		return
+	}
+	runHook("exec-after-emote", options.ExecAfterEmote, paths, []string{
+		"TWE_DLP_CHANNEL_ID=" + channel.ID,
+		"TWE_DLP_CHANNEL_NAME=" + channel.DisplayName,
+		"TWE_DLP_CHANNEL_FOLDER=" + outputRoot,
+		"TWE_DLP_EMOTE_ID=" + emote.ID,
+		"TWE_DLP_EMOTE_CODE=" + emote.Code,
+	}, logFunc)
+}
+
+func runChannelHook(outputRoot string, provider emoteProvider, channel *ChannelData, options downloadOptions, logFunc func(string)) {
+	if options.ExecAfterChannel == "" {
+		return
+	}
+	runHook("exec-after-channel", options.ExecAfterChannel, []string{outputRoot}, []string{
+		"TWE_DLP_PROVIDER=" + provider.Name(),
+		"TWE_DLP_CHANNEL_ID=" + channel.ID,
+		"TWE_DLP_CHANNEL_NAME=" + channel.DisplayName,
+		"TWE_DLP_CHANNEL_FOLDER=" + outputRoot,
+	}, logFunc)
+}
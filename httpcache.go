@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultCacheTTL = 10 * time.Minute
+
+var cacheableContentTypes = map[string]bool{
+	"text/html":        true,
+	"application/json": true,
+}
+
+type cacheTransport struct {
+	base       http.RoundTripper
+	directory  string
+	defaultTTL time.Duration
+}
+
+func defaultCacheDir() string {
+	cacheRoot, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(cacheRoot, "twe-dlp", "http")
+}
+
+func (transport *cacheTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if !isCacheableRequest(request) {
+		return transport.base.RoundTrip(request)
+	}
+
+	entryPath := filepath.Join(transport.directory, cacheKey(request))
+	cached, storedAt := transport.load(entryPath, request)
+	if cached != nil {
+		if time.Since(storedAt) < transport.freshness(cached) {
+			return cached, nil
+		}
+
+		etag := cached.Header.Get("ETag")
+		lastModified := cached.Header.Get("Last-Modified")
+		if etag != "" || lastModified != "" {
+			request = request.Clone(request.Context())
+			if etag != "" {
+				request.Header.Set("If-None-Match", etag)
+			}
+			if lastModified != "" {
+				request.Header.Set("If-Modified-Since", lastModified)
+			}
+		}
+	}
+
+	response, err := transport.base.RoundTrip(request)
+	if err != nil {
+		if cached != nil {
+			cached.Body.Close()
+		}
+		return nil, err
+	}
+
+	if response.StatusCode == http.StatusNotModified && cached != nil {
+		response.Body.Close()
+		now := time.Now()
+		os.Chtimes(entryPath, now, now)
+		return cached, nil
+	}
+	if cached != nil {
+		cached.Body.Close()
+	}
+
+	if response.StatusCode != http.StatusOK || !isCacheableResponse(response) {
+		return response, nil
+	}
+	return transport.store(entryPath, response)
+}
+
+func (transport *cacheTransport) load(entryPath string, request *http.Request) (*http.Response, time.Time) {
+	entryInfo, err := os.Stat(entryPath)
+	if err != nil {
+		return nil, time.Time{}
+	}
+	entryBytes, err := os.ReadFile(entryPath)
+	if err != nil {
+		return nil, time.Time{}
+	}
+	response, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(entryBytes)), request)
+	if err != nil {
+		return nil, time.Time{}
+	}
+	return response, entryInfo.ModTime()
+}
+
+func (transport *cacheTransport) store(entryPath string, response *http.Response) (*http.Response, error) {
+	bodyBytes, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+
+	entryBytes, err := httputil.DumpResponse(response, true)
+	response.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+	if err != nil {
+		return response, nil
+	}
+	if err := os.MkdirAll(transport.directory, 0o755); err == nil {
+		writeFileAtomic(entryPath, entryBytes, false)
+	}
+	return response, nil
+}
+
+func (transport *cacheTransport) freshness(response *http.Response) time.Duration {
+	cacheControl := parseCacheControl(response.Header.Get("Cache-Control"))
+	if _, noCache := cacheControl["no-cache"]; noCache {
+		return 0
+	}
+	if maxAge, hasMaxAge := cacheControl["max-age"]; hasMaxAge {
+		seconds, err := strconv.Atoi(maxAge)
+		if err != nil {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if expires := response.Header.Get("Expires"); expires != "" {
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil {
+			return 0
+		}
+		responseDate, err := http.ParseTime(response.Header.Get("Date"))
+		if err != nil {
+			return 0
+		}
+		return expiresAt.Sub(responseDate)
+	}
+	return transport.defaultTTL
+}
+
+func isCacheableRequest(request *http.Request) bool {
+	if request.Method != http.MethodGet {
+		return false
+	}
+	for _, headerName := range []string{"Range", "If-None-Match", "If-Modified-Since"} {
+		if request.Header.Get(headerName) != "" {
+			return false
+		}
+	}
+	_, noStore := parseCacheControl(request.Header.Get("Cache-Control"))["no-store"]
+	return !noStore
+}
+
+func isCacheableResponse(response *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if err != nil || !cacheableContentTypes[mediaType] {
+		return false
+	}
+	_, noStore := parseCacheControl(response.Header.Get("Cache-Control"))["no-store"]
+	return !noStore
+}
+
+func parseCacheControl(value string) map[string]string {
+	directives := make(map[string]string)
+	for _, directive := range strings.Split(value, ",") {
+		name, directiveValue, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if name == "" {
+			continue
+		}
+		directives[strings.ToLower(name)] = strings.Trim(directiveValue, `"`)
+	}
+	return directives
+}
+
+func cacheKey(request *http.Request) string {
+	hash := sha256.New()
+	io.WriteString(hash, request.Method+" "+request.URL.String())
+	for _, headerName := range []string{"Authorization", "Cookie", "Accept-Language"} {
+		io.WriteString(hash, "\n"+headerName+": "+strings.Join(request.Header.Values(headerName), ", "))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	jobsFileName    = "jobs.db"
+	jobsLockTimeout = time.Second
+)
+
+var jobsBucket = []byte("jobs")
+
+type jobStore struct {
+	db *bolt.DB
+}
+
+func defaultJobsPath() string {
+	directory, err := configDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(directory, jobsFileName)
+}
+
+func openJobStore(path string) (*jobStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: jobsLockTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w (is another server using it?)", path, err)
+	}
+	err = db.Update(func(transaction *bolt.Tx) error {
+		_, err := transaction.CreateBucketIfNotExists(jobsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &jobStore{db: db}, nil
+}
+
+func jobKey(jobID string) ([]byte, error) {
+	number, err := strconv.ParseUint(jobID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid job ID %q", jobID)
+	}
+	return binary.BigEndian.AppendUint64(nil, number), nil
+}
+
+func (store *jobStore) save(job downloadJob) error {
+	key, err := jobKey(job.ID)
+	if err != nil {
+		return err
+	}
+	jobBytes, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return store.db.Update(func(transaction *bolt.Tx) error {
+		return transaction.Bucket(jobsBucket).Put(key, jobBytes)
+	})
+}
+
+func (store *jobStore) load() ([]*downloadJob, error) {
+	var jobs []*downloadJob
+	err := store.db.View(func(transaction *bolt.Tx) error {
+		return transaction.Bucket(jobsBucket).ForEach(func(key []byte, value []byte) error {
+			job := &downloadJob{}
+			if err := json.Unmarshal(value, job); err != nil {
+				return fmt.Errorf("cannot parse job %d: %w", binary.BigEndian.Uint64(key), err)
+			}
+			jobs = append(jobs, job)
+			return nil
+		})
+	})
+	return jobs, err
+}
+
+func (store *jobStore) Close() error {
+	return store.db.Close()
+}
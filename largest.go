@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+const (
+	largestModeSymlink = "symlink"
+	largestModeCopy    = "copy"
+)
+
+var largestModes = []string{largestModeSymlink, largestModeCopy}
+
+func largestImagePath(emoteRecord manifestEmote, largest manifestFile) string {
+	fileName := emoteRecord.Folder + path.Ext(largest.Path)
+	if path.Dir(largest.Path) == emoteRecord.Folder {
+		return path.Join(emoteRecord.Folder, fileName)
+	}
+	return fileName
+}
+
+func writeLargestImage(store storage, outputRoot string, emoteRecord *manifestEmote, mode string, logFunc func(string)) {
+	if mode == "" || len(emoteRecord.Files) == 0 || emoteRecord.Folder == "" {
+		return
+	}
+	largest := emoteRecord.Files[len(emoteRecord.Files)-1]
+	largestPath := largestImagePath(*emoteRecord, largest)
+	for _, file := range emoteRecord.Files {
+		if file.Path == largestPath {
+			logFunc(fmt.Sprintf("[warn] cannot write %s, an image already uses that name", largestPath))
+			return
+		}
+	}
+	sourcePath := filepath.Join(outputRoot, filepath.FromSlash(largest.Path))
+	outputPath := filepath.Join(outputRoot, filepath.FromSlash(largestPath))
+
+	var err error
+	if mode == largestModeSymlink && isLocalStorage(store) {
+		err = symlinkLargestImage(sourcePath, outputPath)
+		if err != nil && !errors.Is(err, os.ErrExist) {
+			logFunc(fmt.Sprintf("[warn] cannot symlink %s (%v), copying instead", largestPath, err))
+			err = copyLargestImage(store, sourcePath, outputPath, largest.SHA256)
+		}
+	} else {
+		err = copyLargestImage(store, sourcePath, outputPath, largest.SHA256)
+	}
+	if err != nil {
+		logFunc(fmt.Sprintf("[error] cannot write %s: %v", largestPath, err))
+		return
+	}
+	emoteRecord.Largest = largestPath
+}
+
+func symlinkLargestImage(sourcePath string, outputPath string) error {
+	target, err := filepath.Rel(filepath.Dir(outputPath), sourcePath)
+	if err != nil {
+		return err
+	}
+	if existing, err := os.Readlink(outputPath); err == nil && existing == target {
+		return nil
+	}
+	if err := os.Remove(outputPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return os.Symlink(target, outputPath)
+}
+
+func copyLargestImage(store storage, sourcePath string, outputPath string, digest string) error {
+	if isLocalStorage(store) {
+		if info, err := os.Lstat(outputPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			os.Remove(outputPath)
+		} else if existing, err := hashFile(outputPath); err == nil && digest != "" && existing == digest {
+			return nil
+		}
+	}
+	imageBytes, err := store.ReadFile(sourcePath)
+	if err != nil {
+		return err
+	}
+	return store.WriteFile(outputPath, imageBytes)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	layoutFolders = "folders"
+	layoutFlat    = "flat"
+	layoutCAS     = "cas"
+
+	objectsFolderName = "objects"
+)
+
+var layouts = []string{layoutFolders, layoutFlat, layoutCAS}
+
+func objectsRoot(options downloadOptions) string {
+	return filepath.Join(options.OutputDir, objectsFolderName)
+}
+
+func objectFileName(digest string, fileExtension string) string {
+	return digest + "." + fileExtension
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func isObjectPath(filePath string, digest string) bool {
+	return digest != "" && path.Base(path.Dir(filePath)) == objectsFolderName && strings.TrimSuffix(path.Base(filePath), path.Ext(filePath)) == digest
+}
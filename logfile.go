@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	timestampsClock    = "clock"
+	timestampsRelative = "relative"
+)
+
+var timestampModes = []string{timestampsClock, timestampsRelative}
+
+var processStartedAt = time.Now()
+
+var logTimestampPattern = regexp.MustCompile(`^(?:\d{2}:\d{2}:\d{2}\.\d{3}|\+\d+\.\d{3}s) `)
+
+type timestampsFlag struct {
+	mode *string
+}
+
+type logFile struct {
+	lock sync.Mutex
+	file *os.File
+}
+
+func openLogFile(path string) (*logFile, error) {
+	if directory := filepath.Dir(path); directory != "." {
+		if err := os.MkdirAll(directory, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	writer := &logFile{file: file}
+	writer.write("=== " + strings.Join(os.Args, " "))
+	return writer, nil
+}
+
+func (writer *logFile) write(line string) {
+	if writer == nil {
+		return
+	}
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+	fmt.Fprintf(writer.file, "%s %s\n", time.Now().Format(time.RFC3339), line)
+}
+
+func (timestamps timestampsFlag) String() string {
+	if timestamps.mode == nil {
+		return ""
+	}
+	return *timestamps.mode
+}
+
+func (timestamps timestampsFlag) Set(value string) error {
+	switch value {
+	case "true":
+		*timestamps.mode = timestampsClock
+	case "false":
+		*timestamps.mode = ""
+	default:
+		if !slices.Contains(timestampModes, value) {
+			return fmt.Errorf("unknown timestamps mode %q (available: %s)", value, strings.Join(timestampModes, ", "))
+		}
+		*timestamps.mode = value
+	}
+	return nil
+}
+
+func (timestampsFlag) IsBoolFlag() bool {
+	return true
+}
+
+func logTimestamp(mode string) string {
+	switch mode {
+	case timestampsClock:
+		return time.Now().Format("15:04:05.000") + " "
+	case timestampsRelative:
+		return fmt.Sprintf("+%.3fs ", time.Since(processStartedAt).Seconds())
+	}
+	return ""
+}
+
+func stripLogTimestamp(line string) string {
+	return logTimestampPattern.ReplaceAllString(line, "")
+}
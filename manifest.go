@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	manifestFileName    = "manifest.json"
+	manifestCSVFileName = "manifest.csv"
+
+	manifestFormatJSON = "json"
+	manifestFormatCSV  = "csv"
+)
+
+var manifestFormats = []string{manifestFormatJSON, manifestFormatCSV}
+
+type channelManifest struct {
+	Provider    string           `json:"provider,omitempty"`
+	ChannelID   string           `json:"channel_id"`
+	ChannelName string           `json:"channel_name,omitempty"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	Metadata    *channelMetadata `json:"metadata,omitempty"`
+	Emotes      []manifestEmote  `json:"emotes"`
+}
+
+type manifestEmote struct {
+	ID      string         `json:"id"`
+	Code    string         `json:"code"`
+	Folder  string         `json:"folder"`
+	Uses    int64          `json:"uses,omitempty"`
+	AddedAt time.Time      `json:"added_at,omitzero"`
+	Largest string         `json:"largest,omitempty"`
+	Zip     string         `json:"zip,omitempty"`
+	Files   []manifestFile `json:"files"`
+}
+
+type manifestFile struct {
+	Size         string `json:"size"`
+	URL          string `json:"url"`
+	SourceURL    string `json:"source_url,omitempty"`
+	Variant      string `json:"variant,omitempty"`
+	Path         string `json:"path"`
+	Converted    string `json:"converted,omitempty"`
+	Normalized   string `json:"normalized,omitempty"`
+	ContentType  string `json:"content_type,omitempty"`
+	Bytes        int64  `json:"bytes"`
+	SHA256       string `json:"sha256,omitempty"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+func loadManifest(store storage, outputRoot string) (*channelManifest, error) {
+	manifestPath := filepath.Join(outputRoot, manifestFileName)
+	manifestBytes, err := store.ReadFile(manifestPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &channelManifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &channelManifest{}
+	if err := json.Unmarshal(manifestBytes, manifest); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", manifestPath, err)
+	}
+	return manifest, nil
+}
+
+func saveManifest(store storage, outputRoot string, manifest *channelManifest) error {
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	manifestPath := filepath.Join(outputRoot, manifestFileName)
+	return store.WriteFile(manifestPath, append(manifestBytes, '\n'))
+}
+
+func saveManifestCSV(store storage, outputRoot string, manifest *channelManifest) error {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	writer.Write([]string{"provider", "channel", "channel_id", "code", "id", "size", "url", "path", "bytes", "sha256"})
+	for _, emote := range manifest.Emotes {
+		for _, file := range emote.Files {
+			fileURL := file.URL
+			if file.SourceURL != "" {
+				fileURL = file.SourceURL
+			}
+			writer.Write([]string{
+				manifest.Provider,
+				manifest.ChannelName,
+				manifest.ChannelID,
+				emote.Code,
+				emote.ID,
+				file.Size,
+				fileURL,
+				file.Path,
+				strconv.FormatInt(file.Bytes, 10),
+				file.SHA256,
+			})
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return store.WriteFile(filepath.Join(outputRoot, manifestCSVFileName), buffer.Bytes())
+}
+
+func (manifest *channelManifest) filesByURL() map[string]manifestFile {
+	files := make(map[string]manifestFile)
+	for _, emote := range manifest.Emotes {
+		for _, file := range emote.Files {
+			files[file.URL] = file
+		}
+	}
+	return files
+}
+
+func (manifest *channelManifest) emotesByID() map[string]manifestEmote {
+	emotes := make(map[string]manifestEmote, len(manifest.Emotes))
+	for _, emote := range manifest.Emotes {
+		emotes[emote.ID] = emote
+	}
+	return emotes
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const markdownFileName = "README.md"
+
+func markdownPath(path string) string {
+	segments := strings.Split(path, "/")
+	for index, segment := range segments {
+		segments[index] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func markdownText(text string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "|", "\\|", "`", "\\`", "*", "\\*", "_", "\\_", "[", "\\[", "]", "\\]", "<", "&lt;", ">", "&gt;", "\n", " ")
+	return replacer.Replace(text)
+}
+
+func markdownCode(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+	if strings.Contains(text, "`") {
+		return "`` " + text + " ``"
+	}
+	return "`" + text + "`"
+}
+
+func writeMarkdownTable(store storage, outputRoot string, manifest *channelManifest) error {
+	title := manifest.ChannelName
+	if title == "" {
+		title = manifest.ChannelID
+	}
+
+	emotes := make([]manifestEmote, 0, len(manifest.Emotes))
+	for _, emote := range manifest.Emotes {
+		if len(emote.Files) > 0 {
+			emotes = append(emotes, emote)
+		}
+	}
+	sort.SliceStable(emotes, func(left, right int) bool {
+		return strings.ToLower(emotes[left].Code) < strings.ToLower(emotes[right].Code)
+	})
+
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "# %s emotes\n\n", markdownText(title))
+	fmt.Fprintf(&buffer, "%d emotes from %s (channel %s), updated %s.\n\n", len(emotes), manifest.Provider, manifest.ChannelID, manifest.UpdatedAt.UTC().Format(time.DateOnly))
+	buffer.WriteString("| Emote | Code | ID | Sizes |\n| --- | --- | --- | --- |\n")
+	for _, emote := range emotes {
+		code := markdownText(emote.Code)
+		sizeLinks := make([]string, 0, len(emote.Files))
+		for _, file := range emote.Files {
+			sizeLinks = append(sizeLinks, fmt.Sprintf("[%s](%s)", markdownText(file.Size), markdownPath(file.Path)))
+		}
+		fmt.Fprintf(&buffer, "| ![%s](%s) | %s | %s | %s |\n", code, markdownPath(emote.Files[0].Path), markdownCode(emote.Code), emote.ID, strings.Join(sizeLinks, " "))
+	}
+
+	return store.WriteFile(filepath.Join(outputRoot, markdownFileName), buffer.Bytes())
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+const (
+	channelAvatarName = "avatar"
+	channelBannerName = "banner"
+)
+
+type channelMetadata struct {
+	DisplayName     string    `json:"display_name,omitempty"`
+	ID              string    `json:"id"`
+	ProfileImageURL string    `json:"profile_image_url,omitempty"`
+	BannerURL       string    `json:"banner_url,omitempty"`
+	FollowerEmotes  *bool     `json:"follower_emotes,omitempty"`
+	ScrapedAt       time.Time `json:"scraped_at"`
+	ProfileImage    string    `json:"profile_image,omitempty"`
+	Banner          string    `json:"banner,omitempty"`
+}
+
+func newChannelMetadata(channel *ChannelData) *channelMetadata {
+	return &channelMetadata{
+		DisplayName:     channel.DisplayName,
+		ID:              channel.ID,
+		ProfileImageURL: channel.ProfileImageURL,
+		BannerURL:       channel.BannerURL,
+		FollowerEmotes:  channel.FollowerEmotes,
+		ScrapedAt:       time.Now().UTC(),
+	}
+}
+
+func downloadChannelImages(httpClient *http.Client, store storage, outputRoot string, metadata *channelMetadata, previous *channelMetadata, download bool, logFunc func(string)) {
+	if previous == nil {
+		previous = &channelMetadata{}
+	}
+	metadata.ProfileImage = downloadChannelImage(httpClient, store, outputRoot, channelAvatarName, metadata.ProfileImageURL, previous.ProfileImageURL, previous.ProfileImage, download, logFunc)
+	metadata.Banner = downloadChannelImage(httpClient, store, outputRoot, channelBannerName, metadata.BannerURL, previous.BannerURL, previous.Banner, download, logFunc)
+}
+
+func downloadChannelImage(httpClient *http.Client, store storage, outputRoot string, name string, imageURL string, previousURL string, previousPath string, download bool, logFunc func(string)) string {
+	keptPath := ""
+	if previousPath != "" && store.Exists(filepath.Join(outputRoot, previousPath)) {
+		keptPath = previousPath
+	}
+	if !download || imageURL == "" || (keptPath != "" && imageURL == previousURL) {
+		return keptPath
+	}
+
+	response, _, err := requestImage(httpClient, []imageVariant{{URL: imageURL}}, manifestFile{}, false, 0, "")
+	if err == nil {
+		var imageBytes []byte
+		contentType := response.Header.Get("Content-Type")
+		if imageBytes, err = readImage(response, nil); err == nil {
+			imagePath := name + "." + determineFileExtension(contentType)
+			if err = store.WriteFile(filepath.Join(outputRoot, imagePath), imageBytes); err == nil {
+				logFunc(fmt.Sprintf("[ok] %s", imagePath))
+				return imagePath
+			}
+		}
+	}
+	logFunc(fmt.Sprintf("[skip] %s (%v)", imageURL, err))
+	return keptPath
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var requestDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	Counts []uint64
+	Count  uint64
+	Sum    float64
+}
+
+type metricsRegistry struct {
+	lock     sync.Mutex
+	counters map[string]map[string]float64
+	requests map[string]*histogram
+}
+
+var appMetrics = newMetricsRegistry()
+
+var counterHelp = map[string]string{
+	"twe_dlp_channel_downloads_total": "Channel downloads by provider and result.",
+	"twe_dlp_files_downloaded_total":  "Image files downloaded by provider.",
+	"twe_dlp_bytes_downloaded_total":  "Image bytes downloaded by provider.",
+	"twe_dlp_http_requests_total":     "HTTP requests by host and status code.",
+}
+
+func newMetricsRegistry() *metricsRegistry {
+	return &metricsRegistry{
+		counters: make(map[string]map[string]float64),
+		requests: make(map[string]*histogram),
+	}
+}
+
+func formatLabels(pairs ...string) string {
+	labels := make([]string, 0, len(pairs)/2)
+	for index := 0; index+1 < len(pairs); index += 2 {
+		labels = append(labels, fmt.Sprintf("%s=%s", pairs[index], strconv.Quote(pairs[index+1])))
+	}
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+func (registry *metricsRegistry) add(name string, value float64, labelPairs ...string) {
+	labels := formatLabels(labelPairs...)
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	if registry.counters[name] == nil {
+		registry.counters[name] = make(map[string]float64)
+	}
+	registry.counters[name][labels] += value
+}
+
+func (registry *metricsRegistry) recordChannel(providerName string, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	registry.add("twe_dlp_channel_downloads_total", 1, "provider", providerName, "result", result)
+}
+
+func (registry *metricsRegistry) recordFile(providerName string, bytesWritten int64) {
+	registry.add("twe_dlp_files_downloaded_total", 1, "provider", providerName)
+	registry.add("twe_dlp_bytes_downloaded_total", float64(bytesWritten), "provider", providerName)
+}
+
+func (registry *metricsRegistry) recordRequest(host string, statusCode string, duration time.Duration) {
+	registry.add("twe_dlp_http_requests_total", 1, "host", host, "code", statusCode)
+
+	labels := formatLabels("host", host)
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	requestHistogram := registry.requests[labels]
+	if requestHistogram == nil {
+		requestHistogram = &histogram{Counts: make([]uint64, len(requestDurationBuckets))}
+		registry.requests[labels] = requestHistogram
+	}
+	seconds := duration.Seconds()
+	for index, bucket := range requestDurationBuckets {
+		if seconds <= bucket {
+			requestHistogram.Counts[index]++
+		}
+	}
+	requestHistogram.Count++
+	requestHistogram.Sum += seconds
+}
+
+func (registry *metricsRegistry) writeTo(writer io.Writer) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	names := make([]string, 0, len(counterHelp))
+	for name := range counterHelp {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(writer, "# HELP %s %s\n# TYPE %s counter\n", name, counterHelp[name], name)
+		for _, labels := range sortedKeys(registry.counters[name]) {
+			fmt.Fprintf(writer, "%s%s %s\n", name, labels, strconv.FormatFloat(registry.counters[name][labels], 'f', -1, 64))
+		}
+	}
+
+	const histogramName = "twe_dlp_http_request_duration_seconds"
+	fmt.Fprintf(writer, "# HELP %s HTTP request latency by host.\n# TYPE %s histogram\n", histogramName, histogramName)
+	for _, labels := range sortedKeys(registry.requests) {
+		requestHistogram := registry.requests[labels]
+		innerLabels := strings.TrimSuffix(strings.TrimPrefix(labels, "{"), "}")
+		for index, bucket := range requestDurationBuckets {
+			fmt.Fprintf(writer, "%s_bucket{%s,le=\"%s\"} %d\n", histogramName, innerLabels, strconv.FormatFloat(bucket, 'f', -1, 64), requestHistogram.Counts[index])
+		}
+		fmt.Fprintf(writer, "%s_bucket{%s,le=\"+Inf\"} %d\n", histogramName, innerLabels, requestHistogram.Count)
+		fmt.Fprintf(writer, "%s_sum%s %s\n", histogramName, labels, strconv.FormatFloat(requestHistogram.Sum, 'f', -1, 64))
+		fmt.Fprintf(writer, "%s_count%s %d\n", histogramName, labels, requestHistogram.Count)
+	}
+}
+
+func sortedKeys[Value any](values map[string]Value) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func handleMetrics(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	appMetrics.writeTo(writer)
+}
+
+type metricsTransport struct {
+	base http.RoundTripper
+}
+
+func (transport metricsTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	startedAt := time.Now()
+	response, err := transport.base.RoundTrip(request)
+	statusCode := "error"
+	if err == nil {
+		statusCode = strconv.Itoa(response.StatusCode)
+	}
+	appMetrics.recordRequest(request.URL.Hostname(), statusCode, time.Since(startedAt))
+	return response, err
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func parsePadSize(value string) (image.Point, error) {
+	widthText, heightText, found := strings.Cut(strings.ToLower(strings.TrimSpace(value)), "x")
+	if !found {
+		heightText = widthText
+	}
+	width, widthErr := strconv.Atoi(widthText)
+	height, heightErr := strconv.Atoi(heightText)
+	if widthErr != nil || heightErr != nil || width < 1 || height < 1 {
+		return image.Point{}, fmt.Errorf("invalid size %q (use WIDTHxHEIGHT, e.g. 112x112)", value)
+	}
+	return image.Point{X: width, Y: height}, nil
+}
+
+func opaqueBounds(frame *image.NRGBA) image.Rectangle {
+	bounds := image.Rectangle{}
+	for y := frame.Rect.Min.Y; y < frame.Rect.Max.Y; y++ {
+		for x := frame.Rect.Min.X; x < frame.Rect.Max.X; x++ {
+			if frame.Pix[frame.PixOffset(x, y)+3] == 0 {
+				continue
+			}
+			bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+		}
+	}
+	return bounds
+}
+
+func scaleNRGBA(source *image.NRGBA, width int, height int) *image.NRGBA {
+	scaled := image.NewNRGBA(image.Rect(0, 0, width, height))
+	sourceWidth := source.Rect.Dx()
+	sourceHeight := source.Rect.Dy()
+	scaleX := float64(sourceWidth) / float64(width)
+	scaleY := float64(sourceHeight) / float64(height)
+
+	for y := 0; y < height; y++ {
+		top := float64(y) * scaleY
+		bottom := top + scaleY
+		for x := 0; x < width; x++ {
+			left := float64(x) * scaleX
+			right := left + scaleX
+
+			var red, green, blue, alpha, area float64
+			for sourceY := int(top); sourceY < sourceHeight && float64(sourceY) < bottom; sourceY++ {
+				coverageY := min(bottom, float64(sourceY+1)) - max(top, float64(sourceY))
+				for sourceX := int(left); sourceX < sourceWidth && float64(sourceX) < right; sourceX++ {
+					coverage := coverageY * (min(right, float64(sourceX+1)) - max(left, float64(sourceX)))
+					if coverage <= 0 {
+						continue
+					}
+					offset := source.PixOffset(source.Rect.Min.X+sourceX, source.Rect.Min.Y+sourceY)
+					pixelAlpha := float64(source.Pix[offset+3]) * coverage
+					red += float64(source.Pix[offset]) * pixelAlpha
+					green += float64(source.Pix[offset+1]) * pixelAlpha
+					blue += float64(source.Pix[offset+2]) * pixelAlpha
+					alpha += pixelAlpha
+					area += coverage
+				}
+			}
+			if alpha == 0 || area == 0 {
+				continue
+			}
+			offset := scaled.PixOffset(x, y)
+			scaled.Pix[offset] = uint8(red/alpha + 0.5)
+			scaled.Pix[offset+1] = uint8(green/alpha + 0.5)
+			scaled.Pix[offset+2] = uint8(blue/alpha + 0.5)
+			scaled.Pix[offset+3] = uint8(alpha/area + 0.5)
+		}
+	}
+	return scaled
+}
+
+func fitNRGBA(source *image.NRGBA, size image.Point, grow bool) *image.NRGBA {
+	width := source.Rect.Dx()
+	height := source.Rect.Dy()
+	if width > size.X || height > size.Y || (grow && width < size.X && height < size.Y) {
+		if width*size.Y > height*size.X {
+			width, height = size.X, max(1, height*size.X/width)
+		} else {
+			width, height = max(1, width*size.Y/height), size.Y
+		}
+		source = scaleNRGBA(source, width, height)
+	}
+
+	canvas := image.NewNRGBA(image.Rect(0, 0, size.X, size.Y))
+	offset := image.Pt((size.X-width)/2, (size.Y-height)/2)
+	draw.Draw(canvas, image.Rectangle{Min: offset, Max: offset.Add(image.Pt(width, height))}, source, source.Rect.Min, draw.Src)
+	return canvas
+}
+
+func cropNRGBA(source *image.NRGBA, bounds image.Rectangle) *image.NRGBA {
+	cropped := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(cropped, cropped.Rect, source, bounds.Min, draw.Src)
+	return cropped
+}
+
+func decodeImageFrames(store storage, sourcePath string) ([]animationFrame, int, error) {
+	sourceBytes, err := store.ReadFile(sourcePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	if strings.HasSuffix(sourcePath, ".gif") {
+		return decodeGIFFrames(bytes.NewReader(sourceBytes))
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(sourceBytes))
+	if err != nil {
+		return nil, 0, err
+	}
+	frame := image.NewNRGBA(image.Rect(0, 0, decoded.Bounds().Dx(), decoded.Bounds().Dy()))
+	draw.Draw(frame, frame.Rect, decoded, decoded.Bounds().Min, draw.Src)
+	return []animationFrame{{Image: frame}}, 0, nil
+}
+
+func normalizeFrames(frames []animationFrame, trim bool, pad image.Point) error {
+	if len(frames) == 0 {
+		return errors.New("image has no frames")
+	}
+	if trim {
+		bounds := image.Rectangle{}
+		for _, frame := range frames {
+			bounds = bounds.Union(opaqueBounds(frame.Image))
+		}
+		if !bounds.Empty() && bounds != frames[0].Image.Rect {
+			for index := range frames {
+				frames[index].Image = cropNRGBA(frames[index].Image, bounds)
+			}
+		}
+	}
+	if pad != (image.Point{}) {
+		for index := range frames {
+			frames[index].Image = fitNRGBA(frames[index].Image, pad, false)
+		}
+	}
+	return nil
+}
+
+func normalizeImageFile(store storage, outputRoot string, file *manifestFile, trim bool, pad image.Point, logFunc func(string)) {
+	if !trim && pad == (image.Point{}) {
+		return
+	}
+	normalizedPath := strings.TrimSuffix(file.Path, filepath.Ext(file.Path)) + "_normalized.png"
+	outputPath := filepath.Join(outputRoot, filepath.FromSlash(normalizedPath))
+	if file.Normalized == normalizedPath && store.Exists(outputPath) {
+		return
+	}
+
+	frames, loopCount, err := decodeImageFrames(store, filepath.Join(outputRoot, filepath.FromSlash(file.Path)))
+	if err == nil {
+		err = normalizeFrames(frames, trim, pad)
+	}
+	var buffer bytes.Buffer
+	if err == nil {
+		if len(frames) > 1 {
+			err = writeAPNG(&buffer, frames, loopCount)
+		} else {
+			err = png.Encode(&buffer, frames[0].Image)
+		}
+	}
+	if err == nil {
+		err = store.WriteFile(outputPath, buffer.Bytes())
+	}
+	if err != nil {
+		logFunc(fmt.Sprintf("[error] cannot normalize %s: %v", filepath.Base(file.Path), err))
+		return
+	}
+	file.Normalized = normalizedPath
+	logFunc(fmt.Sprintf("[ok] %s", filepath.Base(normalizedPath)))
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/png"
+	"os"
+)
+
+func isAnimatedPNG(data []byte) bool {
+	offset := len(pngSignature)
+	for offset+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[offset : offset+4]))
+		switch string(data[offset+4 : offset+8]) {
+		case "acTL":
+			return true
+		case "IDAT":
+			return false
+		}
+		offset += 12 + length
+	}
+	return false
+}
+
+func optimizePNG(data []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(data, pngSignature) || isAnimatedPNG(data) {
+		return data, false
+	}
+	decoded, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return data, false
+	}
+	var buffer bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buffer, decoded); err != nil || buffer.Len() >= len(data) {
+		return data, false
+	}
+	return buffer.Bytes(), true
+}
+
+func optimizePNGFile(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	optimized, smaller := optimizePNG(data)
+	if !smaller {
+		return 0, nil
+	}
+	if err := writeFileAtomic(path, optimized, false); err != nil {
+		return 0, err
+	}
+	return int64(len(data) - len(optimized)), nil
+}
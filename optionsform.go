@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	optionsFieldProvider = iota
+	optionsFieldSizes
+	optionsFieldTheme
+	optionsFieldOutput
+	optionsFieldCount
+)
+
+type optionsForm struct {
+	field      int
+	providers  []string
+	provider   int
+	sizes      []string
+	selected   map[string]bool
+	sizeCursor int
+	themes     []string
+	theme      int
+	output     textinput.Model
+	message    string
+}
+
+func newOptionsForm(options downloadOptions) *optionsForm {
+	output := textinput.New()
+	output.Prompt = ""
+	output.CharLimit = 512
+	output.SetValue(options.OutputDir)
+
+	themeName := options.Theme
+	if themeName == "" {
+		themeName = defaultThemeName
+	}
+	form := &optionsForm{
+		providers: providerNames(),
+		themes:    themeNames(),
+		output:    output,
+	}
+	form.provider = max(0, slices.Index(form.providers, strings.ToLower(options.Provider)))
+	form.theme = max(0, slices.Index(form.themes, strings.ToLower(themeName)))
+	form.resetSizes(options.Sizes)
+	return form
+}
+
+func (form *optionsForm) resetSizes(selectedSizes []string) {
+	provider := emoteProviders[form.providers[form.provider]]
+	form.sizes = provider.Sizes()
+	form.selected = make(map[string]bool, len(form.sizes))
+	form.sizeCursor = 0
+	for _, sizeValue := range downloadSizes(provider, downloadOptions{Sizes: selectedSizes}) {
+		form.selected[sizeValue] = true
+	}
+}
+
+func (form *optionsForm) setField(field int) {
+	form.field = (field + optionsFieldCount) % optionsFieldCount
+	if form.field == optionsFieldOutput {
+		form.output.Focus()
+	} else {
+		form.output.Blur()
+	}
+}
+
+func (form *optionsForm) change(step int) {
+	switch form.field {
+	case optionsFieldProvider:
+		form.provider = (form.provider + step + len(form.providers)) % len(form.providers)
+		form.resetSizes(nil)
+	case optionsFieldSizes:
+		form.sizeCursor = (form.sizeCursor + step + len(form.sizes)) % len(form.sizes)
+	case optionsFieldTheme:
+		form.theme = (form.theme + step + len(form.themes)) % len(form.themes)
+	}
+}
+
+func (form *optionsForm) selectedSizes() []string {
+	sizes := make([]string, 0, len(form.sizes))
+	for _, sizeValue := range form.sizes {
+		if form.selected[sizeValue] {
+			sizes = append(sizes, sizeValue)
+		}
+	}
+	return sizes
+}
+
+func (m model) updateOptionsForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	form := m.optionsForm
+	form.message = ""
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.closeOptionsForm()
+		return m, nil
+	case tea.KeyEnter:
+		m.applyOptionsForm()
+		return m, nil
+	case tea.KeyTab, tea.KeyDown:
+		form.setField(form.field + 1)
+		return m, nil
+	case tea.KeyShiftTab, tea.KeyUp:
+		form.setField(form.field - 1)
+		return m, nil
+	}
+
+	if form.field == optionsFieldOutput {
+		var cmd tea.Cmd
+		form.output, cmd = form.output.Update(msg)
+		return m, cmd
+	}
+	switch msg.String() {
+	case "left", "h":
+		form.change(-1)
+	case "right", "l":
+		form.change(1)
+	case " ", "x":
+		if form.field == optionsFieldSizes {
+			sizeValue := form.sizes[form.sizeCursor]
+			form.selected[sizeValue] = !form.selected[sizeValue]
+		}
+	}
+	return m, nil
+}
+
+func (m *model) openOptionsForm() {
+	m.setFavoritesFocus(false)
+	m.setReviewFocus(false)
+	m.optionsForm = newOptionsForm(m.options)
+	m.textInput.Blur()
+}
+
+func (m *model) closeOptionsForm() {
+	m.optionsForm = nil
+	m.textInput.Focus()
+}
+
+func (m *model) applyOptionsForm() {
+	form := m.optionsForm
+	sizes := form.selectedSizes()
+	if len(sizes) == 0 {
+		form.message = "Select at least one size."
+		return
+	}
+	outputDir := strings.TrimSpace(form.output.Value())
+	if outputDir == "" {
+		form.message = "The output directory cannot be empty."
+		return
+	}
+	palette, err := resolveTheme(form.themes[form.theme], loadConfig().Palette)
+	if err != nil {
+		form.message = err.Error()
+		return
+	}
+
+	m.options.Provider = form.providers[form.provider]
+	m.options.Sizes = nil
+	if len(sizes) < len(form.sizes) {
+		m.options.Sizes = sizes
+	}
+	m.options.Theme = form.themes[form.theme]
+	m.options.OutputDir = outputDir
+	m.applyPalette(palette)
+	configureProviders(m.options)
+	m.closeOptionsForm()
+	m.appendLogLine(fmt.Sprintf("Options: provider %s, sizes %s, theme %s, output %s", m.options.Provider, strings.Join(sizes, ","), m.options.Theme, m.options.OutputDir))
+}
+
+func (m model) renderOptionsForm() string {
+	form := m.optionsForm
+	var builder strings.Builder
+	builder.WriteString(m.styleHelpBoxTitle.Render("Options"))
+	builder.WriteString("\n")
+
+	rows := []struct {
+		label string
+		value string
+	}{
+		{"Provider", "‹ " + form.providers[form.provider] + " ›"},
+		{"Sizes", ""},
+		{"Theme", "‹ " + form.themes[form.theme] + " ›"},
+		{"Output", form.output.View()},
+	}
+	for index, sizeValue := range form.sizes {
+		box := "[ ]"
+		if form.selected[sizeValue] {
+			box = "[x]"
+		}
+		entry := box + " " + sizeValue
+		if form.field == optionsFieldSizes && index == form.sizeCursor {
+			entry = m.styleHelpBoxTitle.Render(entry)
+		}
+		if index > 0 {
+			rows[optionsFieldSizes].value += "  "
+		}
+		rows[optionsFieldSizes].value += entry
+	}
+
+	for field, row := range rows {
+		marker := "  "
+		label := fmt.Sprintf("%-9s", row.label)
+		if field == form.field {
+			marker = m.styleHelpBoxTitle.Render("› ")
+			label = m.styleHelpBoxTitle.Render(label)
+		}
+		builder.WriteString(marker + label + " " + m.styleHelpBoxBody.Render(row.value))
+		builder.WriteString("\n")
+	}
+	if form.message != "" {
+		builder.WriteString(m.styleLogError.Render(form.message))
+		builder.WriteString("\n")
+	}
+	builder.WriteString(m.styleFooter.Render("↑/↓ field • ←/→ change • Space toggle size • Enter apply • Esc cancel"))
+	return builder.String()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+const (
+	overlayFileName       = "index.html"
+	overlayConfigFileName = "config.json"
+)
+
+//go:embed web/overlay.html
+var overlayTemplateSource string
+
+var overlayTemplate = template.Must(template.New("overlay").Parse(overlayTemplateSource))
+
+type overlayConfig struct {
+	Mode       string `json:"mode"`
+	Size       int    `json:"size"`
+	Interval   int    `json:"interval"`
+	Duration   int    `json:"duration"`
+	Max        int    `json:"max"`
+	Codes      string `json:"codes"`
+	Background string `json:"background"`
+}
+
+var defaultOverlayConfig = overlayConfig{
+	Mode:       "rain",
+	Size:       56,
+	Interval:   400,
+	Duration:   6,
+	Max:        40,
+	Background: "transparent",
+}
+
+type overlayEmote struct {
+	Code  string `json:"code"`
+	Image string `json:"image"`
+}
+
+type overlayPage struct {
+	Title    string
+	Emotes   []overlayEmote
+	Defaults overlayConfig
+}
+
+func exportOBSOverlay(channelRoot string, manifest *channelManifest, exportRoot string, durable bool) (int, error) {
+	page := overlayPage{
+		Title:    manifest.ChannelName,
+		Emotes:   make([]overlayEmote, 0, len(manifest.Emotes)),
+		Defaults: defaultOverlayConfig,
+	}
+	if page.Title == "" {
+		page.Title = manifest.ChannelID
+	}
+
+	for _, emote := range manifest.Emotes {
+		if len(emote.Files) == 0 {
+			continue
+		}
+		file := emote.Files[len(emote.Files)-1]
+		imageBytes, err := os.ReadFile(filepath.Join(channelRoot, filepath.FromSlash(file.Path)))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[skip] %s (%v)\n", file.Path, err)
+			continue
+		}
+		imagePath := path.Join("images", emote.ID+filepath.Ext(file.Path))
+		outputPath := filepath.Join(exportRoot, filepath.FromSlash(imagePath))
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
+			return len(page.Emotes), err
+		}
+		if err := writeFileAtomic(outputPath, imageBytes, durable); err != nil {
+			return len(page.Emotes), err
+		}
+		page.Emotes = append(page.Emotes, overlayEmote{Code: emote.Code, Image: imagePath})
+	}
+
+	var buffer bytes.Buffer
+	if err := overlayTemplate.Execute(&buffer, page); err != nil {
+		return len(page.Emotes), err
+	}
+	if err := writeFileAtomic(filepath.Join(exportRoot, overlayFileName), buffer.Bytes(), durable); err != nil {
+		return len(page.Emotes), err
+	}
+
+	configPath := filepath.Join(exportRoot, overlayConfigFileName)
+	if _, err := os.Stat(configPath); !errors.Is(err, fs.ErrNotExist) {
+		return len(page.Emotes), nil
+	}
+	configBytes, err := json.MarshalIndent(defaultOverlayConfig, "", "  ")
+	if err != nil {
+		return len(page.Emotes), err
+	}
+	return len(page.Emotes), writeFileAtomic(configPath, append(configBytes, '\n'), durable)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	previewModeAuto    = "auto"
+	previewModeKitty   = "kitty"
+	previewModeITerm   = "iterm"
+	previewModeSixel   = "sixel"
+	previewModeOff     = "off"
+	previewCellWidth   = 2
+	previewSixelSize   = 18
+	previewWorkers     = 8
+	previewPlaceholder = "◻"
+)
+
+var previewModes = []string{previewModeAuto, previewModeKitty, previewModeITerm, previewModeSixel, previewModeOff}
+
+func detectPreviewMode() string {
+	terminal := os.Getenv("TERM")
+	terminalProgram := os.Getenv("TERM_PROGRAM")
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "" || terminal == "xterm-kitty" || terminalProgram == "ghostty":
+		return previewModeKitty
+	case terminalProgram == "iTerm.app" || terminalProgram == "WezTerm" || os.Getenv("LC_TERMINAL") == "iTerm2":
+		return previewModeITerm
+	case strings.Contains(terminal, "sixel") || terminal == "mlterm" || terminal == "foot" || strings.HasPrefix(terminal, "foot-"):
+		return previewModeSixel
+	}
+	return previewModeOff
+}
+
+func resolvePreviewMode(mode string) (string, error) {
+	for _, known := range previewModes {
+		if mode == known {
+			if mode == previewModeAuto {
+				return detectPreviewMode(), nil
+			}
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unknown preview mode %q (available: %s)", mode, strings.Join(previewModes, ", "))
+}
+
+func fetchEmotePreviews(httpClient *http.Client, provider emoteProvider, channel *ChannelData, mode string) map[string]string {
+	previews := make(map[string]string, len(channel.Emotes))
+	if mode == previewModeOff || len(provider.Sizes()) == 0 {
+		return previews
+	}
+
+	emoteIdentifiers := make(chan string)
+	var previewsLock sync.Mutex
+	var workers sync.WaitGroup
+	for worker := 0; worker < previewWorkers; worker++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for emoteIdentifier := range emoteIdentifiers {
+				imageURL := provider.ImageURL(channel.Emotes[emoteIdentifier], provider.Sizes()[0])
+				imageBytes, err := fetchPreviewImage(httpClient, imageURL)
+				if err != nil {
+					continue
+				}
+				rendered := renderPreview(mode, imageBytes)
+				previewsLock.Lock()
+				previews[emoteIdentifier] = rendered
+				previewsLock.Unlock()
+			}
+		}()
+	}
+
+	for emoteIdentifier := range channel.Emotes {
+		emoteIdentifiers <- emoteIdentifier
+	}
+	close(emoteIdentifiers)
+	workers.Wait()
+
+	return previews
+}
+
+func fetchPreviewImage(httpClient *http.Client, imageURL string) ([]byte, error) {
+	request, err := http.NewRequest("GET", imageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %s", response.Status)
+	}
+	return io.ReadAll(response.Body)
+}
+
+func renderPreview(mode string, imageBytes []byte) string {
+	switch mode {
+	case previewModeKitty:
+		return renderKittyPreview(imageBytes)
+	case previewModeITerm:
+		return renderITermPreview(imageBytes)
+	case previewModeSixel:
+		return renderSixelPreview(imageBytes)
+	}
+	return ""
+}
+
+func renderKittyPreview(imageBytes []byte) string {
+	decoded, format, err := image.Decode(bytes.NewReader(imageBytes))
+	if err != nil {
+		return ""
+	}
+
+	payload := imageBytes
+	if format != "png" {
+		var encoded bytes.Buffer
+		if err := png.Encode(&encoded, decoded); err != nil {
+			return ""
+		}
+		payload = encoded.Bytes()
+	}
+
+	encodedPayload := base64.StdEncoding.EncodeToString(payload)
+	var builder strings.Builder
+	for offset := 0; offset < len(encodedPayload); offset += 4096 {
+		chunkEnd := min(offset+4096, len(encodedPayload))
+		hasMore := 0
+		if chunkEnd < len(encodedPayload) {
+			hasMore = 1
+		}
+		if offset == 0 {
+			fmt.Fprintf(&builder, "\x1b_Gf=100,a=T,q=2,C=1,c=%d,r=1,m=%d;%s\x1b\\", previewCellWidth, hasMore, encodedPayload[offset:chunkEnd])
+		} else {
+			fmt.Fprintf(&builder, "\x1b_Gm=%d;%s\x1b\\", hasMore, encodedPayload[offset:chunkEnd])
+		}
+	}
+	return builder.String() + strings.Repeat(" ", previewCellWidth)
+}
+
+func renderITermPreview(imageBytes []byte) string {
+	encodedPayload := base64.StdEncoding.EncodeToString(imageBytes)
+	return fmt.Sprintf("\x1b]1337;File=inline=1;size=%d;width=%d;height=1;preserveAspectRatio=1:%s\a", len(imageBytes), previewCellWidth, encodedPayload)
+}
+
+func renderSixelPreview(imageBytes []byte) string {
+	decoded, _, err := image.Decode(bytes.NewReader(imageBytes))
+	if err != nil {
+		return ""
+	}
+
+	bounds := decoded.Bounds()
+	if bounds.Dx() == 0 || bounds.Dy() == 0 {
+		return ""
+	}
+	height := previewSixelSize
+	width := max(1, bounds.Dx()*height/bounds.Dy())
+
+	paletteIndexes := make([][]int, height)
+	usedColors := make(map[int]bool)
+	for row := 0; row < height; row++ {
+		paletteIndexes[row] = make([]int, width)
+		for column := 0; column < width; column++ {
+			sourceX := bounds.Min.X + column*bounds.Dx()/width
+			sourceY := bounds.Min.Y + row*bounds.Dy()/height
+			red, green, blue, alpha := decoded.At(sourceX, sourceY).RGBA()
+			if alpha < 0x8000 {
+				paletteIndexes[row][column] = -1
+				continue
+			}
+			colorIndex := int(red*5/0xffff)*36 + int(green*5/0xffff)*6 + int(blue*5/0xffff)
+			paletteIndexes[row][column] = colorIndex
+			usedColors[colorIndex] = true
+		}
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "\x1bP0;1;0q\"1;1;%d;%d", width, height)
+	for colorIndex := range usedColors {
+		fmt.Fprintf(&builder, "#%d;2;%d;%d;%d", colorIndex, colorIndex/36*20, colorIndex/6%6*20, colorIndex%6*20)
+	}
+
+	for bandStart := 0; bandStart < height; bandStart += 6 {
+		for colorIndex := range usedColors {
+			var bandBuilder strings.Builder
+			hasPixels := false
+			for column := 0; column < width; column++ {
+				sixel := 0
+				for bit := 0; bit < 6 && bandStart+bit < height; bit++ {
+					if paletteIndexes[bandStart+bit][column] == colorIndex {
+						sixel |= 1 << bit
+						hasPixels = true
+					}
+				}
+				bandBuilder.WriteByte(byte(63 + sixel))
+			}
+			if hasPixels {
+				fmt.Fprintf(&builder, "#%d%s$", colorIndex, bandBuilder.String())
+			}
+		}
+		if bandStart+6 < height {
+			builder.WriteString("-")
+		}
+	}
+	builder.WriteString("\x1b\\")
+
+	return builder.String()
+}
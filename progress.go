@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	progressReportInterval = 100 * time.Millisecond
+	plainProgressEvery     = 25
+
+	progressModeAuto  = "auto"
+	progressModePlain = "plain"
+	progressModeOff   = "off"
+)
+
+var progressModes = []string{progressModeAuto, progressModePlain, progressModeOff}
+
+type downloadProgress struct {
+	EmoteCode      string
+	Size           string
+	BytesDone      int64
+	BytesTotal     int64
+	BytesPerSecond float64
+
+	EmotesDone     int
+	EmotesTotal    int
+	ChannelBytes   int64
+	ChannelElapsed time.Duration
+}
+
+type channelProgress struct {
+	startedAt     time.Time
+	emotesDone    int
+	emotesTotal   int
+	finishedBytes int64
+	currentFile   string
+	currentBytes  int64
+	lastPercent   int
+	report        func(downloadProgress)
+}
+
+type statusLine struct {
+	lock   sync.Mutex
+	writer io.Writer
+	width  int
+	shown  bool
+}
+
+type progressReader struct {
+	reader     io.Reader
+	bytesDone  int64
+	bytesTotal int64
+	bytesRead  int64
+	startedAt  time.Time
+	lastReport time.Time
+	report     func(bytesDone int64, bytesTotal int64, bytesPerSecond float64)
+}
+
+func newProgressReader(reader io.Reader, startOffset int64, contentLength int64, report func(int64, int64, float64)) io.Reader {
+	if report == nil {
+		return reader
+	}
+	bytesTotal := int64(-1)
+	if contentLength >= 0 {
+		bytesTotal = startOffset + contentLength
+	}
+	return &progressReader{
+		reader:     reader,
+		bytesDone:  startOffset,
+		bytesTotal: bytesTotal,
+		startedAt:  time.Now(),
+		report:     report,
+	}
+}
+
+func (reader *progressReader) Read(buffer []byte) (int, error) {
+	bytesRead, err := reader.reader.Read(buffer)
+	reader.bytesDone += int64(bytesRead)
+	reader.bytesRead += int64(bytesRead)
+
+	now := time.Now()
+	if err != nil || now.Sub(reader.lastReport) >= progressReportInterval {
+		reader.lastReport = now
+		bytesPerSecond := 0.0
+		if elapsed := now.Sub(reader.startedAt).Seconds(); elapsed > 0 {
+			bytesPerSecond = float64(reader.bytesRead) / elapsed
+		}
+		reader.report(reader.bytesDone, reader.bytesTotal, bytesPerSecond)
+	}
+	return bytesRead, err
+}
+
+func newChannelProgress(report func(downloadProgress)) *channelProgress {
+	return &channelProgress{startedAt: time.Now(), lastPercent: -1, report: report}
+}
+
+func (tracker *channelProgress) update(progress downloadProgress) {
+	file := progress.EmoteCode + "\x00" + progress.Size
+	if file != tracker.currentFile {
+		tracker.finishedBytes += tracker.currentBytes
+		tracker.currentFile = file
+	}
+	tracker.currentBytes = progress.BytesDone
+	progress.EmotesDone = tracker.emotesDone
+	progress.EmotesTotal = tracker.emotesTotal
+	progress.ChannelBytes = tracker.finishedBytes + tracker.currentBytes
+	progress.ChannelElapsed = time.Since(tracker.startedAt)
+	if tracker.report != nil {
+		tracker.report(progress)
+	}
+}
+
+func (tracker *channelProgress) finishEmote(bytesDone int64) (string, bool) {
+	tracker.emotesDone++
+	elapsed := time.Since(tracker.startedAt)
+	var fields []string
+	if tracker.emotesTotal > 0 {
+		percent := tracker.emotesDone * 100 / tracker.emotesTotal
+		if percent == tracker.lastPercent && tracker.emotesDone < tracker.emotesTotal {
+			return "", false
+		}
+		tracker.lastPercent = percent
+		fields = append(fields, fmt.Sprintf("percent=%d", percent), fmt.Sprintf("emotes=%d/%d", tracker.emotesDone, tracker.emotesTotal))
+	} else {
+		if tracker.emotesDone%plainProgressEvery != 0 {
+			return "", false
+		}
+		fields = append(fields, fmt.Sprintf("emotes=%d", tracker.emotesDone))
+	}
+	speed := int64(0)
+	if elapsed > 0 {
+		speed = int64(float64(bytesDone) / elapsed.Seconds())
+	}
+	fields = append(fields, fmt.Sprintf("bytes=%d", bytesDone), fmt.Sprintf("speed=%d", speed), fmt.Sprintf("elapsed=%d", int64(elapsed.Seconds())))
+	if tracker.emotesTotal > tracker.emotesDone {
+		eta := float64(elapsed) * float64(tracker.emotesTotal-tracker.emotesDone) / float64(tracker.emotesDone)
+		fields = append(fields, fmt.Sprintf("eta=%d", int64(time.Duration(eta).Seconds())))
+	}
+	return "[progress] " + strings.Join(fields, " "), true
+}
+
+func (progress downloadProgress) channelSpeed() float64 {
+	if progress.ChannelElapsed <= 0 {
+		return 0
+	}
+	return float64(progress.ChannelBytes) / progress.ChannelElapsed.Seconds()
+}
+
+func (progress downloadProgress) remaining() (time.Duration, bool) {
+	if progress.EmotesDone == 0 || progress.EmotesTotal <= progress.EmotesDone {
+		return 0, false
+	}
+	left := float64(progress.EmotesTotal-progress.EmotesDone) / float64(progress.EmotesDone)
+	return time.Duration(float64(progress.ChannelElapsed) * left), true
+}
+
+func formatProgressStatus(progress downloadProgress) string {
+	transferText := formatByteSize(progress.BytesDone)
+	if progress.BytesTotal >= 0 {
+		transferText = fmt.Sprintf("%s / %s", transferText, formatByteSize(progress.BytesTotal))
+	}
+	parts := []string{fmt.Sprintf("%s %s  %s", progress.EmoteCode, progress.Size, transferText)}
+	if progress.EmotesTotal > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d emotes", progress.EmotesDone, progress.EmotesTotal))
+	}
+	parts = append(parts, fmt.Sprintf("%s at %s/s", formatByteSize(progress.ChannelBytes), formatByteSize(int64(progress.channelSpeed()))))
+	if eta, known := progress.remaining(); known {
+		parts = append(parts, "ETA "+formatETA(eta))
+	}
+	return strings.Join(parts, "  ")
+}
+
+func formatETA(eta time.Duration) string {
+	seconds := int64(eta.Round(time.Second) / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
+
+func (line *statusLine) show(text string) {
+	if line == nil {
+		return
+	}
+	line.lock.Lock()
+	defer line.lock.Unlock()
+	if runes := []rune(text); line.width > 1 && len(runes) >= line.width {
+		text = string(runes[:line.width-1])
+	}
+	fmt.Fprintf(line.writer, "\r\x1b[K%s", text)
+	line.shown = true
+}
+
+func (line *statusLine) clear() {
+	if line == nil {
+		return
+	}
+	line.lock.Lock()
+	defer line.lock.Unlock()
+	if line.shown {
+		fmt.Fprint(line.writer, "\r\x1b[K")
+		line.shown = false
+	}
+}
@@ -0,0 +1,705 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: twedlp/v1/jobs.proto
+
+package twedlpv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type JobStatus int32
+
+const (
+	JobStatus_JOB_STATUS_UNSPECIFIED JobStatus = 0
+	JobStatus_JOB_STATUS_QUEUED      JobStatus = 1
+	JobStatus_JOB_STATUS_RUNNING     JobStatus = 2
+	JobStatus_JOB_STATUS_DONE        JobStatus = 3
+	JobStatus_JOB_STATUS_FAILED      JobStatus = 4
+)
+
+// Enum value maps for JobStatus.
+var (
+	JobStatus_name = map[int32]string{
+		0: "JOB_STATUS_UNSPECIFIED",
+		1: "JOB_STATUS_QUEUED",
+		2: "JOB_STATUS_RUNNING",
+		3: "JOB_STATUS_DONE",
+		4: "JOB_STATUS_FAILED",
+	}
+	JobStatus_value = map[string]int32{
+		"JOB_STATUS_UNSPECIFIED": 0,
+		"JOB_STATUS_QUEUED":      1,
+		"JOB_STATUS_RUNNING":     2,
+		"JOB_STATUS_DONE":        3,
+		"JOB_STATUS_FAILED":      4,
+	}
+)
+
+func (x JobStatus) Enum() *JobStatus {
+	p := new(JobStatus)
+	*p = x
+	return p
+}
+
+func (x JobStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (JobStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_twedlp_v1_jobs_proto_enumTypes[0].Descriptor()
+}
+
+func (JobStatus) Type() protoreflect.EnumType {
+	return &file_twedlp_v1_jobs_proto_enumTypes[0]
+}
+
+func (x JobStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use JobStatus.Descriptor instead.
+func (JobStatus) EnumDescriptor() ([]byte, []int) {
+	return file_twedlp_v1_jobs_proto_rawDescGZIP(), []int{0}
+}
+
+type Job struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Id         string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Channel    string                 `protobuf:"bytes,2,opt,name=channel,proto3" json:"channel,omitempty"`
+	Only       []string               `protobuf:"bytes,3,rep,name=only,proto3" json:"only,omitempty"`
+	Exclude    []string               `protobuf:"bytes,4,rep,name=exclude,proto3" json:"exclude,omitempty"`
+	Status     JobStatus              `protobuf:"varint,5,opt,name=status,proto3,enum=twedlp.v1.JobStatus" json:"status,omitempty"`
+	Error      string                 `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	CreatedAt  *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	StartedAt  *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	FinishedAt *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"`
+	// Channel folder on the server, set once the job has finished.
+	OutputDir     string `protobuf:"bytes,10,opt,name=output_dir,json=outputDir,proto3" json:"output_dir,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Job) Reset() {
+	*x = Job{}
+	mi := &file_twedlp_v1_jobs_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Job) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Job) ProtoMessage() {}
+
+func (x *Job) ProtoReflect() protoreflect.Message {
+	mi := &file_twedlp_v1_jobs_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Job.ProtoReflect.Descriptor instead.
+func (*Job) Descriptor() ([]byte, []int) {
+	return file_twedlp_v1_jobs_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Job) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Job) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+func (x *Job) GetOnly() []string {
+	if x != nil {
+		return x.Only
+	}
+	return nil
+}
+
+func (x *Job) GetExclude() []string {
+	if x != nil {
+		return x.Exclude
+	}
+	return nil
+}
+
+func (x *Job) GetStatus() JobStatus {
+	if x != nil {
+		return x.Status
+	}
+	return JobStatus_JOB_STATUS_UNSPECIFIED
+}
+
+func (x *Job) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Job) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Job) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *Job) GetFinishedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return nil
+}
+
+func (x *Job) GetOutputDir() string {
+	if x != nil {
+		return x.OutputDir
+	}
+	return ""
+}
+
+type CreateJobRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Channel name, URL or provider:channel, as on the command line.
+	Channel string `protobuf:"bytes,1,opt,name=channel,proto3" json:"channel,omitempty"`
+	// Only download emotes with these codes or IDs.
+	Only []string `protobuf:"bytes,2,rep,name=only,proto3" json:"only,omitempty"`
+	// Skip emotes with these codes or IDs.
+	Exclude       []string `protobuf:"bytes,3,rep,name=exclude,proto3" json:"exclude,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateJobRequest) Reset() {
+	*x = CreateJobRequest{}
+	mi := &file_twedlp_v1_jobs_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateJobRequest) ProtoMessage() {}
+
+func (x *CreateJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_twedlp_v1_jobs_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateJobRequest.ProtoReflect.Descriptor instead.
+func (*CreateJobRequest) Descriptor() ([]byte, []int) {
+	return file_twedlp_v1_jobs_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CreateJobRequest) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+func (x *CreateJobRequest) GetOnly() []string {
+	if x != nil {
+		return x.Only
+	}
+	return nil
+}
+
+func (x *CreateJobRequest) GetExclude() []string {
+	if x != nil {
+		return x.Exclude
+	}
+	return nil
+}
+
+type GetJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetJobRequest) Reset() {
+	*x = GetJobRequest{}
+	mi := &file_twedlp_v1_jobs_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobRequest) ProtoMessage() {}
+
+func (x *GetJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_twedlp_v1_jobs_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobRequest.ProtoReflect.Descriptor instead.
+func (*GetJobRequest) Descriptor() ([]byte, []int) {
+	return file_twedlp_v1_jobs_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetJobRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type ListJobsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListJobsRequest) Reset() {
+	*x = ListJobsRequest{}
+	mi := &file_twedlp_v1_jobs_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsRequest) ProtoMessage() {}
+
+func (x *ListJobsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_twedlp_v1_jobs_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsRequest.ProtoReflect.Descriptor instead.
+func (*ListJobsRequest) Descriptor() ([]byte, []int) {
+	return file_twedlp_v1_jobs_proto_rawDescGZIP(), []int{3}
+}
+
+type ListJobsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Jobs          []*Job                 `protobuf:"bytes,1,rep,name=jobs,proto3" json:"jobs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListJobsResponse) Reset() {
+	*x = ListJobsResponse{}
+	mi := &file_twedlp_v1_jobs_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsResponse) ProtoMessage() {}
+
+func (x *ListJobsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_twedlp_v1_jobs_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsResponse.ProtoReflect.Descriptor instead.
+func (*ListJobsResponse) Descriptor() ([]byte, []int) {
+	return file_twedlp_v1_jobs_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListJobsResponse) GetJobs() []*Job {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
+type WatchJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchJobRequest) Reset() {
+	*x = WatchJobRequest{}
+	mi := &file_twedlp_v1_jobs_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchJobRequest) ProtoMessage() {}
+
+func (x *WatchJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_twedlp_v1_jobs_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchJobRequest.ProtoReflect.Descriptor instead.
+func (*WatchJobRequest) Descriptor() ([]byte, []int) {
+	return file_twedlp_v1_jobs_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *WatchJobRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type Progress struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	EmotesDone int32                  `protobuf:"varint,1,opt,name=emotes_done,json=emotesDone,proto3" json:"emotes_done,omitempty"`
+	// Zero while the emote listing is still being read.
+	EmotesTotal    int32   `protobuf:"varint,2,opt,name=emotes_total,json=emotesTotal,proto3" json:"emotes_total,omitempty"`
+	Bytes          int64   `protobuf:"varint,3,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	BytesPerSecond float64 `protobuf:"fixed64,4,opt,name=bytes_per_second,json=bytesPerSecond,proto3" json:"bytes_per_second,omitempty"`
+	// Estimated seconds left, or -1 when unknown.
+	EtaSeconds    int64  `protobuf:"varint,5,opt,name=eta_seconds,json=etaSeconds,proto3" json:"eta_seconds,omitempty"`
+	Emote         string `protobuf:"bytes,6,opt,name=emote,proto3" json:"emote,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Progress) Reset() {
+	*x = Progress{}
+	mi := &file_twedlp_v1_jobs_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Progress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Progress) ProtoMessage() {}
+
+func (x *Progress) ProtoReflect() protoreflect.Message {
+	mi := &file_twedlp_v1_jobs_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Progress.ProtoReflect.Descriptor instead.
+func (*Progress) Descriptor() ([]byte, []int) {
+	return file_twedlp_v1_jobs_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Progress) GetEmotesDone() int32 {
+	if x != nil {
+		return x.EmotesDone
+	}
+	return 0
+}
+
+func (x *Progress) GetEmotesTotal() int32 {
+	if x != nil {
+		return x.EmotesTotal
+	}
+	return 0
+}
+
+func (x *Progress) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *Progress) GetBytesPerSecond() float64 {
+	if x != nil {
+		return x.BytesPerSecond
+	}
+	return 0
+}
+
+func (x *Progress) GetEtaSeconds() int64 {
+	if x != nil {
+		return x.EtaSeconds
+	}
+	return 0
+}
+
+func (x *Progress) GetEmote() string {
+	if x != nil {
+		return x.Emote
+	}
+	return ""
+}
+
+type JobEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Job   *Job                   `protobuf:"bytes,1,opt,name=job,proto3" json:"job,omitempty"`
+	// Log lines written since the previous event.
+	LogLines      []string  `protobuf:"bytes,2,rep,name=log_lines,json=logLines,proto3" json:"log_lines,omitempty"`
+	Progress      *Progress `protobuf:"bytes,3,opt,name=progress,proto3" json:"progress,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobEvent) Reset() {
+	*x = JobEvent{}
+	mi := &file_twedlp_v1_jobs_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobEvent) ProtoMessage() {}
+
+func (x *JobEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_twedlp_v1_jobs_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobEvent.ProtoReflect.Descriptor instead.
+func (*JobEvent) Descriptor() ([]byte, []int) {
+	return file_twedlp_v1_jobs_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *JobEvent) GetJob() *Job {
+	if x != nil {
+		return x.Job
+	}
+	return nil
+}
+
+func (x *JobEvent) GetLogLines() []string {
+	if x != nil {
+		return x.LogLines
+	}
+	return nil
+}
+
+func (x *JobEvent) GetProgress() *Progress {
+	if x != nil {
+		return x.Progress
+	}
+	return nil
+}
+
+var File_twedlp_v1_jobs_proto protoreflect.FileDescriptor
+
+const file_twedlp_v1_jobs_proto_rawDesc = "" +
+	"\n" +
+	"\x14twedlp/v1/jobs.proto\x12\ttwedlp.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xf3\x02\n" +
+	"\x03Job\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\achannel\x18\x02 \x01(\tR\achannel\x12\x12\n" +
+	"\x04only\x18\x03 \x03(\tR\x04only\x12\x18\n" +
+	"\aexclude\x18\x04 \x03(\tR\aexclude\x12,\n" +
+	"\x06status\x18\x05 \x01(\x0e2\x14.twedlp.v1.JobStatusR\x06status\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\x129\n" +
+	"\n" +
+	"created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"started_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12;\n" +
+	"\vfinished_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"finishedAt\x12\x1d\n" +
+	"\n" +
+	"output_dir\x18\n" +
+	" \x01(\tR\toutputDir\"Z\n" +
+	"\x10CreateJobRequest\x12\x18\n" +
+	"\achannel\x18\x01 \x01(\tR\achannel\x12\x12\n" +
+	"\x04only\x18\x02 \x03(\tR\x04only\x12\x18\n" +
+	"\aexclude\x18\x03 \x03(\tR\aexclude\"\x1f\n" +
+	"\rGetJobRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x11\n" +
+	"\x0fListJobsRequest\"6\n" +
+	"\x10ListJobsResponse\x12\"\n" +
+	"\x04jobs\x18\x01 \x03(\v2\x0e.twedlp.v1.JobR\x04jobs\"!\n" +
+	"\x0fWatchJobRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\xc5\x01\n" +
+	"\bProgress\x12\x1f\n" +
+	"\vemotes_done\x18\x01 \x01(\x05R\n" +
+	"emotesDone\x12!\n" +
+	"\femotes_total\x18\x02 \x01(\x05R\vemotesTotal\x12\x14\n" +
+	"\x05bytes\x18\x03 \x01(\x03R\x05bytes\x12(\n" +
+	"\x10bytes_per_second\x18\x04 \x01(\x01R\x0ebytesPerSecond\x12\x1f\n" +
+	"\veta_seconds\x18\x05 \x01(\x03R\n" +
+	"etaSeconds\x12\x14\n" +
+	"\x05emote\x18\x06 \x01(\tR\x05emote\"z\n" +
+	"\bJobEvent\x12 \n" +
+	"\x03job\x18\x01 \x01(\v2\x0e.twedlp.v1.JobR\x03job\x12\x1b\n" +
+	"\tlog_lines\x18\x02 \x03(\tR\blogLines\x12/\n" +
+	"\bprogress\x18\x03 \x01(\v2\x13.twedlp.v1.ProgressR\bprogress*\x82\x01\n" +
+	"\tJobStatus\x12\x1a\n" +
+	"\x16JOB_STATUS_UNSPECIFIED\x10\x00\x12\x15\n" +
+	"\x11JOB_STATUS_QUEUED\x10\x01\x12\x16\n" +
+	"\x12JOB_STATUS_RUNNING\x10\x02\x12\x13\n" +
+	"\x0fJOB_STATUS_DONE\x10\x03\x12\x15\n" +
+	"\x11JOB_STATUS_FAILED\x10\x042\xfe\x01\n" +
+	"\n" +
+	"JobService\x128\n" +
+	"\tCreateJob\x12\x1b.twedlp.v1.CreateJobRequest\x1a\x0e.twedlp.v1.Job\x122\n" +
+	"\x06GetJob\x12\x18.twedlp.v1.GetJobRequest\x1a\x0e.twedlp.v1.Job\x12C\n" +
+	"\bListJobs\x12\x1a.twedlp.v1.ListJobsRequest\x1a\x1b.twedlp.v1.ListJobsResponse\x12=\n" +
+	"\bWatchJob\x12\x1a.twedlp.v1.WatchJobRequest\x1a\x13.twedlp.v1.JobEvent0\x01B5Z3github.com/odesaur/twe-dlp/proto/twedlp/v1;twedlpv1b\x06proto3"
+
+var (
+	file_twedlp_v1_jobs_proto_rawDescOnce sync.Once
+	file_twedlp_v1_jobs_proto_rawDescData []byte
+)
+
+func file_twedlp_v1_jobs_proto_rawDescGZIP() []byte {
+	file_twedlp_v1_jobs_proto_rawDescOnce.Do(func() {
+		file_twedlp_v1_jobs_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_twedlp_v1_jobs_proto_rawDesc), len(file_twedlp_v1_jobs_proto_rawDesc)))
+	})
+	return file_twedlp_v1_jobs_proto_rawDescData
+}
+
+var file_twedlp_v1_jobs_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_twedlp_v1_jobs_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_twedlp_v1_jobs_proto_goTypes = []any{
+	(JobStatus)(0),                // 0: twedlp.v1.JobStatus
+	(*Job)(nil),                   // 1: twedlp.v1.Job
+	(*CreateJobRequest)(nil),      // 2: twedlp.v1.CreateJobRequest
+	(*GetJobRequest)(nil),         // 3: twedlp.v1.GetJobRequest
+	(*ListJobsRequest)(nil),       // 4: twedlp.v1.ListJobsRequest
+	(*ListJobsResponse)(nil),      // 5: twedlp.v1.ListJobsResponse
+	(*WatchJobRequest)(nil),       // 6: twedlp.v1.WatchJobRequest
+	(*Progress)(nil),              // 7: twedlp.v1.Progress
+	(*JobEvent)(nil),              // 8: twedlp.v1.JobEvent
+	(*timestamppb.Timestamp)(nil), // 9: google.protobuf.Timestamp
+}
+var file_twedlp_v1_jobs_proto_depIdxs = []int32{
+	0,  // 0: twedlp.v1.Job.status:type_name -> twedlp.v1.JobStatus
+	9,  // 1: twedlp.v1.Job.created_at:type_name -> google.protobuf.Timestamp
+	9,  // 2: twedlp.v1.Job.started_at:type_name -> google.protobuf.Timestamp
+	9,  // 3: twedlp.v1.Job.finished_at:type_name -> google.protobuf.Timestamp
+	1,  // 4: twedlp.v1.ListJobsResponse.jobs:type_name -> twedlp.v1.Job
+	1,  // 5: twedlp.v1.JobEvent.job:type_name -> twedlp.v1.Job
+	7,  // 6: twedlp.v1.JobEvent.progress:type_name -> twedlp.v1.Progress
+	2,  // 7: twedlp.v1.JobService.CreateJob:input_type -> twedlp.v1.CreateJobRequest
+	3,  // 8: twedlp.v1.JobService.GetJob:input_type -> twedlp.v1.GetJobRequest
+	4,  // 9: twedlp.v1.JobService.ListJobs:input_type -> twedlp.v1.ListJobsRequest
+	6,  // 10: twedlp.v1.JobService.WatchJob:input_type -> twedlp.v1.WatchJobRequest
+	1,  // 11: twedlp.v1.JobService.CreateJob:output_type -> twedlp.v1.Job
+	1,  // 12: twedlp.v1.JobService.GetJob:output_type -> twedlp.v1.Job
+	5,  // 13: twedlp.v1.JobService.ListJobs:output_type -> twedlp.v1.ListJobsResponse
+	8,  // 14: twedlp.v1.JobService.WatchJob:output_type -> twedlp.v1.JobEvent
+	11, // [11:15] is the sub-list for method output_type
+	7,  // [7:11] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_twedlp_v1_jobs_proto_init() }
+func file_twedlp_v1_jobs_proto_init() {
+	if File_twedlp_v1_jobs_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_twedlp_v1_jobs_proto_rawDesc), len(file_twedlp_v1_jobs_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_twedlp_v1_jobs_proto_goTypes,
+		DependencyIndexes: file_twedlp_v1_jobs_proto_depIdxs,
+		EnumInfos:         file_twedlp_v1_jobs_proto_enumTypes,
+		MessageInfos:      file_twedlp_v1_jobs_proto_msgTypes,
+	}.Build()
+	File_twedlp_v1_jobs_proto = out.File
+	file_twedlp_v1_jobs_proto_goTypes = nil
+	file_twedlp_v1_jobs_proto_depIdxs = nil
+}
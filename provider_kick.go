@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	kickAPIBaseURL   = "https://kick.com"
+	kickFilesBaseURL = "https://files.kick.com/emotes"
+)
+
+var kickSlugPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+type kickChannelResponse struct {
+	ID   int64  `json:"id"`
+	Slug string `json:"slug"`
+	User struct {
+		Username   string `json:"username"`
+		ProfilePic string `json:"profile_pic"`
+	} `json:"user"`
+	BannerImage struct {
+		URL string `json:"url"`
+	} `json:"banner_image"`
+}
+
+type kickEmoteSet struct {
+	Emotes []kickEmote `json:"emotes"`
+}
+
+type kickEmote struct {
+	ID              int64  `json:"id"`
+	ChannelID       int64  `json:"channel_id"`
+	Name            string `json:"name"`
+	SubscribersOnly bool   `json:"subscribers_only"`
+	CreatedAt       string `json:"created_at"`
+}
+
+type kickProvider struct{}
+
+func (kickProvider) Name() string {
+	return "kick"
+}
+
+func (kickProvider) ResolveChannelID(httpClient *http.Client, channelIdentifier string) (string, error) {
+	slug := strings.ToLower(strings.TrimSpace(channelIdentifier))
+	if slug == "" {
+		return "", errors.New("empty channel identifier")
+	}
+	if !kickSlugPattern.MatchString(slug) {
+		return "", fmt.Errorf("invalid Kick channel name %q", channelIdentifier)
+	}
+	return slug, nil
+}
+
+func (provider kickProvider) FetchChannel(httpClient *http.Client, channelID string) (*ChannelData, error) {
+	var channel *ChannelData
+	err := provider.StreamChannel(httpClient, channelID, func(found *ChannelData) {
+		channel = found
+	}, func(emoteIdentifier string, emoteData EmoteData) {
+		channel.Emotes[emoteIdentifier] = emoteData
+	})
+	if err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+func (kickProvider) StreamChannel(httpClient *http.Client, channelID string, channelFound func(*ChannelData), emoteFound func(string, EmoteData)) error {
+	var channel kickChannelResponse
+	channelURL := fmt.Sprintf("%s/api/v2/channels/%s", kickAPIBaseURL, url.PathEscape(channelID))
+	if err := fetchJSON(httpClient, channelURL, &channel); err != nil {
+		return err
+	}
+
+	displayName := channel.User.Username
+	if displayName == "" {
+		displayName = channel.Slug
+	}
+	channelFound(&ChannelData{
+		ID:              fmt.Sprintf("%d", channel.ID),
+		DisplayName:     displayName,
+		ProfileImageURL: channel.User.ProfilePic,
+		BannerURL:       channel.BannerImage.URL,
+		Emotes:          make(map[string]EmoteData),
+	})
+
+	emotesURL := fmt.Sprintf("%s/emotes/%s", kickAPIBaseURL, url.PathEscape(channelID))
+	return fetchJSONStream(httpClient, emotesURL, func(decoder *json.Decoder) error {
+		if token, err := decoder.Token(); err != nil {
+			return err
+		} else if token != json.Delim('[') {
+			return fmt.Errorf("unexpected emote list token %v", token)
+		}
+		for decoder.More() {
+			var emoteSet kickEmoteSet
+			if err := decoder.Decode(&emoteSet); err != nil {
+				return err
+			}
+			for _, emote := range emoteSet.Emotes {
+				if emote.ChannelID != channel.ID {
+					continue
+				}
+				emoteIdentifier := fmt.Sprintf("%d", emote.ID)
+				emoteFound(emoteIdentifier, EmoteData{
+					BaseURL:   fmt.Sprintf("%s/%s", kickFilesBaseURL, emoteIdentifier),
+					EmoteCode: emote.Name,
+					CreatedAt: parseEmoteDate(emote.CreatedAt),
+				})
+			}
+		}
+		_, err := decoder.Token()
+		return err
+	})
+}
+
+func (kickProvider) Sizes() []string {
+	return []string{"fullsize"}
+}
+
+func (kickProvider) ImageURL(emoteData EmoteData, sizeValue string) string {
+	return fmt.Sprintf("%s/%s", emoteData.BaseURL, sizeValue)
+}
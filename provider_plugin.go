@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	pluginExecutablePrefix = "twe-dlp-provider-"
+	pluginFolderName       = "plugins"
+	pluginTimeout          = 2 * time.Minute
+	pluginSizePlaceholder  = "{size}"
+	pluginProtocolVersion  = 1
+)
+
+type pluginRequest struct {
+	Version   int    `json:"version"`
+	Method    string `json:"method"`
+	Channel   string `json:"channel,omitempty"`
+	ChannelID string `json:"channel_id,omitempty"`
+}
+
+type pluginEmote struct {
+	ID          string `json:"id"`
+	Code        string `json:"code"`
+	URLTemplate string `json:"url"`
+	Animated    bool   `json:"animated,omitempty"`
+	CreatedAt   string `json:"created_at,omitempty"`
+}
+
+type pluginResponse struct {
+	Error           string        `json:"error,omitempty"`
+	Name            string        `json:"name,omitempty"`
+	Sizes           []string      `json:"sizes,omitempty"`
+	ID              string        `json:"id,omitempty"`
+	DisplayName     string        `json:"display_name,omitempty"`
+	ProfileImageURL string        `json:"profile_image_url,omitempty"`
+	BannerURL       string        `json:"banner_url,omitempty"`
+	Emotes          []pluginEmote `json:"emotes,omitempty"`
+}
+
+type pluginProvider struct {
+	name     string
+	path     string
+	describe sync.Once
+	sizes    []string
+}
+
+func pluginDirectories() []string {
+	var directories []string
+	if directory, err := configDir(); err == nil {
+		directories = append(directories, filepath.Join(directory, pluginFolderName))
+	}
+	return append(directories, filepath.SplitList(os.Getenv("PATH"))...)
+}
+
+func loadProviderPlugins() {
+	for _, directory := range pluginDirectories() {
+		entries, err := os.ReadDir(directory)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			fileName := entry.Name()
+			if entry.IsDir() || !strings.HasPrefix(fileName, pluginExecutablePrefix) {
+				continue
+			}
+			if runtime.GOOS == "windows" {
+				fileName = strings.TrimSuffix(fileName, ".exe")
+			}
+			name := strings.ToLower(strings.TrimPrefix(fileName, pluginExecutablePrefix))
+			if name == "" {
+				continue
+			}
+			if _, exists := emoteProviders[name]; exists {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil || (runtime.GOOS != "windows" && info.Mode()&0o111 == 0) {
+				continue
+			}
+			emoteProviders[name] = &pluginProvider{name: name, path: filepath.Join(directory, entry.Name())}
+		}
+	}
+}
+
+func (provider *pluginProvider) call(request pluginRequest) (*pluginResponse, error) {
+	request.Version = pluginProtocolVersion
+	requestBytes, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+	command := exec.CommandContext(ctx, provider.path, request.Method)
+	command.Stdin = bytes.NewReader(requestBytes)
+	var stdout, stderr bytes.Buffer
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	runErr := command.Run()
+
+	var response pluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("provider plugin %s %s: %v: %s", provider.name, request.Method, runErr, strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("provider plugin %s %s returned invalid JSON: %w", provider.name, request.Method, err)
+	}
+	if response.Error != "" {
+		return nil, errors.New(response.Error)
+	}
+	if runErr != nil {
+		return nil, fmt.Errorf("provider plugin %s %s: %w", provider.name, request.Method, runErr)
+	}
+	return &response, nil
+}
+
+func (provider *pluginProvider) Name() string {
+	return provider.name
+}
+
+func (provider *pluginProvider) ResolveChannelID(httpClient *http.Client, channelIdentifier string) (string, error) {
+	channelIdentifier = strings.TrimSpace(channelIdentifier)
+	if channelIdentifier == "" {
+		return "", errors.New("empty channel identifier")
+	}
+	response, err := provider.call(pluginRequest{Method: "resolve", Channel: channelIdentifier})
+	if err != nil {
+		return "", err
+	}
+	if response.ID == "" {
+		return "", fmt.Errorf("provider plugin %s did not return a channel ID for %q", provider.name, channelIdentifier)
+	}
+	return response.ID, nil
+}
+
+func (provider *pluginProvider) FetchChannel(httpClient *http.Client, channelID string) (*ChannelData, error) {
+	response, err := provider.call(pluginRequest{Method: "fetch", ChannelID: channelID})
+	if err != nil {
+		return nil, err
+	}
+
+	channel := &ChannelData{
+		ID:              channelID,
+		DisplayName:     response.DisplayName,
+		ProfileImageURL: response.ProfileImageURL,
+		BannerURL:       response.BannerURL,
+		Emotes:          make(map[string]EmoteData, len(response.Emotes)),
+	}
+	for _, emote := range response.Emotes {
+		if emote.ID == "" || emote.URLTemplate == "" {
+			continue
+		}
+		formatType := "static"
+		if emote.Animated {
+			formatType = "animated"
+		}
+		code := emote.Code
+		if code == "" {
+			code = emote.ID
+		}
+		channel.Emotes[emote.ID] = EmoteData{
+			BaseURL:    emote.URLTemplate,
+			FormatType: formatType,
+			EmoteCode:  code,
+			CreatedAt:  parseEmoteDate(emote.CreatedAt),
+		}
+	}
+	return channel, nil
+}
+
+func (provider *pluginProvider) Sizes() []string {
+	provider.describe.Do(func() {
+		response, err := provider.call(pluginRequest{Method: "describe"})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		} else {
+			provider.sizes = response.Sizes
+		}
+		if len(provider.sizes) == 0 {
+			provider.sizes = []string{"1"}
+		}
+	})
+	return provider.sizes
+}
+
+func (provider *pluginProvider) ImageURL(emoteData EmoteData, sizeValue string) string {
+	return strings.ReplaceAll(emoteData.BaseURL, pluginSizePlaceholder, sizeValue)
+}
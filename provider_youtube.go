@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	youtubeBaseURL    = "https://www.youtube.com"
+	youtubeAPIBaseURL = "https://www.googleapis.com/youtube/v3"
+)
+
+var (
+	youtubeChannelIDPattern   = regexp.MustCompile(`^UC[A-Za-z0-9_-]{22}$`)
+	youtubeExternalIDPattern  = regexp.MustCompile(`"externalId":"(UC[A-Za-z0-9_-]{22})"`)
+	youtubeInitialDataPattern = regexp.MustCompile(`(?s)(?:var ytInitialData|window\["ytInitialData"\])\s*=\s*(\{.*?\});\s*</script>`)
+	youtubeImageSizePattern   = regexp.MustCompile(`=[^/]*$`)
+)
+
+type youtubeChannelListResponse struct {
+	Items []struct {
+		ID      string `json:"id"`
+		Snippet struct {
+			Title      string `json:"title"`
+			Thumbnails map[string]struct {
+				URL string `json:"url"`
+			} `json:"thumbnails"`
+		} `json:"snippet"`
+		BrandingSettings struct {
+			Image struct {
+				BannerExternalURL string `json:"bannerExternalUrl"`
+			} `json:"image"`
+		} `json:"brandingSettings"`
+	} `json:"items"`
+}
+
+type youtubeProvider struct {
+	oauthToken string
+}
+
+func (*youtubeProvider) Name() string {
+	return "youtube"
+}
+
+func (provider *youtubeProvider) token() string {
+	if provider.oauthToken != "" {
+		return provider.oauthToken
+	}
+	return lookupCredential(provider.Name())
+}
+
+func (provider *youtubeProvider) ResolveChannelID(httpClient *http.Client, channelIdentifier string) (string, error) {
+	channelIdentifier = strings.TrimSpace(channelIdentifier)
+	if channelIdentifier == "" {
+		return "", errors.New("empty channel identifier")
+	}
+	if youtubeChannelIDPattern.MatchString(channelIdentifier) {
+		return channelIdentifier, nil
+	}
+
+	handle := channelIdentifier
+	if !strings.HasPrefix(handle, "@") {
+		handle = "@" + handle
+	}
+
+	if provider.token() != "" {
+		channels, err := provider.listChannels(httpClient, url.Values{"forHandle": {handle}})
+		if err != nil {
+			return "", err
+		}
+		if len(channels.Items) == 0 {
+			return "", fmt.Errorf("could not resolve YouTube handle %q to a channel ID", handle)
+		}
+		return channels.Items[0].ID, nil
+	}
+
+	pageBytes, err := provider.fetchPage(httpClient, fmt.Sprintf("%s/%s", youtubeBaseURL, url.PathEscape(handle)))
+	if err != nil {
+		return "", err
+	}
+	match := youtubeExternalIDPattern.FindSubmatch(pageBytes)
+	if len(match) != 2 {
+		return "", fmt.Errorf("could not resolve YouTube handle %q to a channel ID", handle)
+	}
+	return string(match[1]), nil
+}
+
+func (provider *youtubeProvider) FetchChannel(httpClient *http.Client, channelID string) (*ChannelData, error) {
+	pageBytes, err := provider.fetchPage(httpClient, fmt.Sprintf("%s/channel/%s/membership", youtubeBaseURL, url.PathEscape(channelID)))
+	if err != nil {
+		return nil, err
+	}
+
+	match := youtubeInitialDataPattern.FindSubmatch(pageBytes)
+	if len(match) != 2 {
+		return nil, errors.New("could not find ytInitialData on the membership page")
+	}
+	var initialData any
+	if err := json.Unmarshal(match[1], &initialData); err != nil {
+		return nil, fmt.Errorf("cannot parse ytInitialData: %w", err)
+	}
+
+	channel := &ChannelData{
+		ID:     channelID,
+		Emotes: make(map[string]EmoteData),
+	}
+	collectYouTubeEmojis(initialData, channel)
+
+	if provider.token() != "" {
+		channels, err := provider.listChannels(httpClient, url.Values{"id": {channelID}})
+		if err == nil && len(channels.Items) > 0 {
+			item := channels.Items[0]
+			channel.DisplayName = item.Snippet.Title
+			for _, quality := range []string{"high", "medium", "default"} {
+				if thumbnail, exists := item.Snippet.Thumbnails[quality]; exists && thumbnail.URL != "" {
+					channel.ProfileImageURL = thumbnail.URL
+					break
+				}
+			}
+			if banner := item.BrandingSettings.Image.BannerExternalURL; banner != "" {
+				channel.BannerURL = banner
+			}
+		}
+	}
+
+	return channel, nil
+}
+
+func (*youtubeProvider) Sizes() []string {
+	return []string{"24", "48", "96"}
+}
+
+func (*youtubeProvider) ImageURL(emoteData EmoteData, sizeValue string) string {
+	return fmt.Sprintf("%s=w%s-h%s-c-k-nd", emoteData.BaseURL, sizeValue, sizeValue)
+}
+
+func (provider *youtubeProvider) fetchPage(httpClient *http.Client, pageURL string) ([]byte, error) {
+	request, err := http.NewRequest("GET", pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	request.AddCookie(&http.Cookie{Name: "CONSENT", Value: "YES+"})
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request to %s failed with status %s", pageURL, response.Status)
+	}
+	return io.ReadAll(response.Body)
+}
+
+func (provider *youtubeProvider) listChannels(httpClient *http.Client, query url.Values) (*youtubeChannelListResponse, error) {
+	query.Set("part", "snippet,brandingSettings")
+	requestURL := fmt.Sprintf("%s/channels?%s", youtubeAPIBaseURL, query.Encode())
+
+	request, err := http.NewRequest("GET", requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+provider.token())
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("YouTube API request failed with status %s", response.Status)
+	}
+
+	channels := &youtubeChannelListResponse{}
+	if err := json.NewDecoder(response.Body).Decode(channels); err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+func collectYouTubeEmojis(node any, channel *ChannelData) {
+	switch value := node.(type) {
+	case []any:
+		for _, child := range value {
+			collectYouTubeEmojis(child, channel)
+		}
+	case map[string]any:
+		if metadata, isMap := value["channelMetadataRenderer"].(map[string]any); isMap {
+			if title, isString := metadata["title"].(string); isString && channel.DisplayName == "" {
+				channel.DisplayName = title
+			}
+			if channel.ProfileImageURL == "" {
+				channel.ProfileImageURL = largestYouTubeThumbnail(metadata["avatar"])
+			}
+		}
+
+		emojiID, hasEmojiID := value["emojiId"].(string)
+		isCustom, _ := value["isCustomEmoji"].(bool)
+		if hasEmojiID && isCustom {
+			addYouTubeEmoji(emojiID, value, channel)
+			return
+		}
+
+		for _, child := range value {
+			collectYouTubeEmojis(child, channel)
+		}
+	}
+}
+
+func largestYouTubeThumbnail(node any) string {
+	image, _ := node.(map[string]any)
+	thumbnails, _ := image["thumbnails"].([]any)
+	if len(thumbnails) == 0 {
+		return ""
+	}
+	thumbnail, _ := thumbnails[len(thumbnails)-1].(map[string]any)
+	imageURL, _ := thumbnail["url"].(string)
+	if strings.HasPrefix(imageURL, "//") {
+		imageURL = "https:" + imageURL
+	}
+	return imageURL
+}
+
+func addYouTubeEmoji(emojiID string, emoji map[string]any, channel *ChannelData) {
+	emoteIdentifier := emojiID
+	if slashIndex := strings.LastIndexByte(emojiID, '/'); slashIndex >= 0 {
+		emoteIdentifier = emojiID[slashIndex+1:]
+	}
+	if _, exists := channel.Emotes[emoteIdentifier]; exists {
+		return
+	}
+
+	image, _ := emoji["image"].(map[string]any)
+	thumbnails, _ := image["thumbnails"].([]any)
+	if len(thumbnails) == 0 {
+		return
+	}
+	thumbnail, _ := thumbnails[0].(map[string]any)
+	imageURL, _ := thumbnail["url"].(string)
+	if imageURL == "" {
+		return
+	}
+	if strings.HasPrefix(imageURL, "//") {
+		imageURL = "https:" + imageURL
+	}
+
+	emoteCode := emoteIdentifier
+	if shortcuts, isList := emoji["shortcuts"].([]any); isList && len(shortcuts) > 0 {
+		if shortcut, isString := shortcuts[0].(string); isString && strings.Trim(shortcut, ":") != "" {
+			emoteCode = strings.Trim(shortcut, ":")
+		}
+	}
+
+	channel.Emotes[emoteIdentifier] = EmoteData{
+		BaseURL:   youtubeImageSizePattern.ReplaceAllString(imageURL, ""),
+		EmoteCode: emoteCode,
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultProviderName   = "twitch"
+	twitchEmoteCDNBaseURL = "https://static-cdn.jtvnw.net/emoticons/v2"
+)
+
+type ChannelData struct {
+	ID              string
+	DisplayName     string
+	ProfileImageURL string
+	BannerURL       string
+	FollowerEmotes  *bool
+	Emotes          map[string]EmoteData
+}
+
+type emoteProvider interface {
+	Name() string
+	ResolveChannelID(httpClient *http.Client, channelIdentifier string) (string, error)
+	FetchChannel(httpClient *http.Client, channelID string) (*ChannelData, error)
+	Sizes() []string
+	ImageURL(emoteData EmoteData, sizeValue string) string
+}
+
+type emoteStreamer interface {
+	StreamChannel(httpClient *http.Client, channelID string, channelFound func(*ChannelData), emoteFound func(string, EmoteData)) error
+}
+
+type imageVariant struct {
+	Name string
+	URL  string
+}
+
+type variantProvider interface {
+	ImageVariants(emoteData EmoteData, sizeValue string) []imageVariant
+}
+
+var youtubeEmoteProvider = &youtubeProvider{}
+
+var emoteProviders = map[string]emoteProvider{
+	"twitch":  twitchProvider{},
+	"kick":    kickProvider{},
+	"youtube": youtubeEmoteProvider,
+}
+
+func configureProviders(options downloadOptions) {
+	youtubeEmoteProvider.oauthToken = options.YouTubeToken
+}
+
+func lookupProvider(name string) (emoteProvider, error) {
+	provider, exists := emoteProviders[strings.ToLower(strings.TrimSpace(name))]
+	if !exists {
+		return nil, fmt.Errorf("unknown provider %q (available: %s)", name, strings.Join(providerNames(), ", "))
+	}
+	return provider, nil
+}
+
+func providerNames() []string {
+	names := make([]string, 0, len(emoteProviders))
+	for name := range emoteProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var channelURLProviders = map[string]string{
+	"twitch.tv":   "twitch",
+	"kick.com":    "kick",
+	"youtube.com": "youtube",
+}
+
+var twitchURLPrefixes = map[string]bool{"popout": true, "moderator": true}
+
+func parseChannelURL(channelInput string) (string, string, bool) {
+	channelInput = strings.TrimSpace(channelInput)
+	lowerInput := strings.ToLower(channelInput)
+	if !strings.HasPrefix(lowerInput, "http://") && !strings.HasPrefix(lowerInput, "https://") {
+		if !strings.Contains(channelInput, "/") {
+			return "", "", false
+		}
+		channelInput = "https://" + channelInput
+	}
+	channelURL, err := url.Parse(channelInput)
+	if err != nil {
+		return "", "", false
+	}
+	host := strings.ToLower(channelURL.Hostname())
+	for _, subdomain := range []string{"www.", "m.", "dashboard."} {
+		host = strings.TrimPrefix(host, subdomain)
+	}
+	providerName, known := channelURLProviders[host]
+	if !known {
+		return "", "", false
+	}
+
+	segments := strings.Split(strings.Trim(channelURL.Path, "/"), "/")
+	switch {
+	case providerName == "twitch" && twitchURLPrefixes[segments[0]] && len(segments) > 1:
+		segments = segments[1:]
+	case providerName == "youtube" && (segments[0] == "channel" || segments[0] == "c") && len(segments) > 1:
+		segments = segments[1:]
+	}
+	if segments[0] == "" {
+		return "", "", false
+	}
+	if providerName == "twitch" {
+		return providerName, strings.ToLower(segments[0]), true
+	}
+	return providerName, segments[0], true
+}
+
+func selectProvider(channelInput string, defaultProvider string) (emoteProvider, string, error) {
+	if emoteID, isCDNURL := parseTwitchCDNURL(channelInput); isCDNURL {
+		return &twitchCDNProvider{}, emoteID, nil
+	}
+	if providerName, channelName, isURL := parseChannelURL(channelInput); isURL {
+		return emoteProviders[providerName], channelName, nil
+	}
+
+	prefix, remainder, hasPrefix := strings.Cut(channelInput, ":")
+	if hasPrefix {
+		if provider, exists := emoteProviders[strings.ToLower(prefix)]; exists {
+			return provider, strings.TrimSpace(remainder), nil
+		}
+	}
+
+	provider, err := lookupProvider(defaultProvider)
+	if err != nil {
+		return nil, "", err
+	}
+	return provider, channelInput, nil
+}
+
+func downloadSizes(provider emoteProvider, options downloadOptions) []string {
+	if len(options.Sizes) == 0 {
+		return provider.Sizes()
+	}
+	sizes := make([]string, 0, len(options.Sizes))
+	for _, sizeValue := range provider.Sizes() {
+		if slices.Contains(options.Sizes, sizeValue) {
+			sizes = append(sizes, sizeValue)
+		}
+	}
+	if len(sizes) == 0 {
+		return provider.Sizes()
+	}
+	return sizes
+}
+
+func imageVariants(provider emoteProvider, emoteData EmoteData, sizeValue string) []imageVariant {
+	if variantSource, hasVariants := provider.(variantProvider); hasVariants {
+		if variants := variantSource.ImageVariants(emoteData, sizeValue); len(variants) > 0 {
+			return variants
+		}
+	}
+	return []imageVariant{{URL: provider.ImageURL(emoteData, sizeValue)}}
+}
+
+func fetchJSON(httpClient *http.Client, requestURL string, target any) error {
+	return fetchJSONStream(httpClient, requestURL, func(decoder *json.Decoder) error {
+		return decoder.Decode(target)
+	})
+}
+
+func fetchJSONStream(httpClient *http.Client, requestURL string, decode func(*json.Decoder) error) error {
+	request, err := http.NewRequest("GET", requestURL, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("request to %s failed with status %s", requestURL, response.Status)
+	}
+
+	return decode(json.NewDecoder(response.Body))
+}
+
+type twitchProvider struct{}
+
+func (twitchProvider) Name() string {
+	return "twitch"
+}
+
+func (twitchProvider) ResolveChannelID(httpClient *http.Client, channelIdentifier string) (string, error) {
+	return resolveChannelIdentifierToID(httpClient, channelIdentifier)
+}
+
+func (twitchProvider) FetchChannel(httpClient *http.Client, channelID string) (*ChannelData, error) {
+	channelURL := fmt.Sprintf("%s/channels/%s", twitchemotesBaseURL, channelID)
+
+	response, err := httpClient.Get(channelURL)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request failed with status %s", response.Status)
+	}
+	return parseChannelPage(channelID, response.Body)
+}
+
+func (twitchProvider) Sizes() []string {
+	return emoteSizeList
+}
+
+func (twitchProvider) ImageURL(emoteData EmoteData, sizeValue string) string {
+	return fmt.Sprintf("%s/light/%s", emoteData.BaseURL, sizeValue)
+}
+
+func (twitchProvider) ImageVariants(emoteData EmoteData, sizeValue string) []imageVariant {
+	baseURL := emoteData.BaseURL
+	formatType := emoteData.FormatType
+	if formatType == "" {
+		formatType = "default"
+	}
+	if !strings.HasSuffix(baseURL, "/"+formatType) {
+		return nil
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/"+formatType)
+
+	formatTypes := []string{formatType}
+	switch formatType {
+	case "animated":
+		formatTypes = append(formatTypes, "static")
+	case "static":
+		formatTypes = append(formatTypes, "animated")
+	default:
+		formatTypes = append(formatTypes, "static", "animated")
+	}
+
+	sizeValues := []string{sizeValue}
+	for index := len(emoteSizeList) - 1; index >= 0; index-- {
+		if emoteSizeList[index] < sizeValue {
+			sizeValues = append(sizeValues, emoteSizeList[index])
+		}
+	}
+
+	variants := make([]imageVariant, 0, len(sizeValues)*len(formatTypes)*2)
+	for _, candidateSize := range sizeValues {
+		for _, candidateFormat := range formatTypes {
+			for _, theme := range []string{"light", "dark"} {
+				variantName := fmt.Sprintf("%s/%s/%s", candidateFormat, theme, candidateSize)
+				variants = append(variants, imageVariant{
+					Name: variantName,
+					URL:  fmt.Sprintf("%s/%s", baseURL, variantName),
+				})
+			}
+		}
+	}
+	return variants
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	reportJSONFileName = "report.json"
+	reportTextFileName = "report.txt"
+
+	imageStatusDownloaded = "downloaded"
+	imageStatusUnchanged  = "unchanged"
+	imageStatusFailed     = "failed"
+
+	emoteStatusOK        = "ok"
+	emoteStatusUnchanged = "unchanged"
+	emoteStatusPartial   = "partial"
+	emoteStatusFailed    = "failed"
+	emoteStatusSkipped   = "skipped"
+)
+
+type reportImage struct {
+	Size    string `json:"size"`
+	Status  string `json:"status"`
+	File    string `json:"file,omitempty"`
+	Variant string `json:"variant,omitempty"`
+	Bytes   int64  `json:"bytes,omitempty"`
+	Saved   int64  `json:"saved_bytes,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type reportEmote struct {
+	ID       string        `json:"id"`
+	Code     string        `json:"code"`
+	Status   string        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Images   []reportImage `json:"images,omitempty"`
+}
+
+type reportFailure struct {
+	Emote  string `json:"emote"`
+	ID     string `json:"id"`
+	Size   string `json:"size,omitempty"`
+	Reason string `json:"reason"`
+}
+
+type reportCollision struct {
+	Folder     string `json:"folder"`
+	ID         string `json:"id"`
+	Code       string `json:"code"`
+	ConflictID string `json:"conflict_id"`
+	SavedTo    string `json:"saved_to"`
+}
+
+type runReport struct {
+	Provider    string            `json:"provider"`
+	ChannelID   string            `json:"channel_id"`
+	ChannelName string            `json:"channel_name,omitempty"`
+	StartedAt   time.Time         `json:"started_at"`
+	FinishedAt  time.Time         `json:"finished_at"`
+	Arguments   []string          `json:"arguments"`
+	Only        []string          `json:"only,omitempty"`
+	Exclude     []string          `json:"exclude,omitempty"`
+	Filters     []string          `json:"filters,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	Counts      map[string]int    `json:"counts"`
+	Emotes      []reportEmote     `json:"emotes"`
+	Failures    []reportFailure   `json:"failures"`
+	Collisions  []reportCollision `json:"collisions,omitempty"`
+	Optimized   int               `json:"optimized,omitempty"`
+	SavedBytes  int64             `json:"saved_bytes,omitempty"`
+}
+
+func newRunReport(provider emoteProvider, channel *ChannelData, options downloadOptions) *runReport {
+	report := &runReport{
+		Provider:    provider.Name(),
+		ChannelID:   channel.ID,
+		ChannelName: channel.DisplayName,
+		StartedAt:   time.Now().UTC(),
+		Arguments:   os.Args[1:],
+		Only:        options.Only,
+		Exclude:     options.Exclude,
+		Counts:      make(map[string]int),
+		Emotes:      []reportEmote{},
+		Failures:    []reportFailure{},
+	}
+	for _, filter := range options.Filters {
+		report.Filters = append(report.Filters, filter.String())
+	}
+	return report
+}
+
+func (emote *reportEmote) finish() {
+	failed, unchanged := 0, 0
+	for _, image := range emote.Images {
+		switch image.Status {
+		case imageStatusFailed:
+			failed++
+		case imageStatusUnchanged:
+			unchanged++
+		}
+	}
+	switch {
+	case emote.Error != "" || failed == len(emote.Images):
+		emote.Status = emoteStatusFailed
+	case failed > 0:
+		emote.Status = emoteStatusPartial
+	case unchanged == len(emote.Images):
+		emote.Status = emoteStatusUnchanged
+	default:
+		emote.Status = emoteStatusOK
+	}
+}
+
+func (report *runReport) addEmote(emote reportEmote) {
+	if emote.Status == "" {
+		emote.finish()
+	}
+	report.Emotes = append(report.Emotes, emote)
+	report.Counts[emote.Status]++
+	if emote.Error != "" {
+		report.Failures = append(report.Failures, reportFailure{Emote: emote.Code, ID: emote.ID, Reason: emote.Error})
+	}
+	for _, image := range emote.Images {
+		if image.Saved > 0 {
+			report.Optimized++
+			report.SavedBytes += image.Saved
+		}
+		if image.Status == imageStatusFailed {
+			report.Failures = append(report.Failures, reportFailure{Emote: emote.Code, ID: emote.ID, Size: image.Size, Reason: image.Error})
+		}
+	}
+}
+
+func (report *runReport) failedImages() int {
+	failed := 0
+	for _, emote := range report.Emotes {
+		if emote.Status == emoteStatusFailed && len(emote.Images) == 0 {
+			failed++
+		}
+		for _, image := range emote.Images {
+			if image.Status == imageStatusFailed {
+				failed++
+			}
+		}
+	}
+	return failed
+}
+
+func (report *runReport) addCollision(collision reportCollision) {
+	report.Collisions = append(report.Collisions, collision)
+}
+
+func (report *runReport) finish(err error) {
+	report.FinishedAt = time.Now().UTC()
+	if err != nil {
+		report.Error = err.Error()
+	}
+}
+
+func (report *runReport) text() string {
+	var builder strings.Builder
+	channelName := report.ChannelName
+	if channelName == "" {
+		channelName = report.ChannelID
+	}
+	fmt.Fprintf(&builder, "Channel:   %s (%s %s)\n", channelName, report.Provider, report.ChannelID)
+	fmt.Fprintf(&builder, "Started:   %s\n", report.StartedAt.Local().Format(time.DateTime))
+	fmt.Fprintf(&builder, "Finished:  %s (%s)\n", report.FinishedAt.Local().Format(time.DateTime), report.FinishedAt.Sub(report.StartedAt).Round(time.Millisecond))
+	fmt.Fprintf(&builder, "Arguments: %s\n", strings.Join(report.Arguments, " "))
+	if report.Error != "" {
+		fmt.Fprintf(&builder, "Error:     %s\n", report.Error)
+	}
+
+	counts := make([]string, 0, len(report.Counts))
+	for _, status := range []string{emoteStatusOK, emoteStatusUnchanged, emoteStatusPartial, emoteStatusFailed, emoteStatusSkipped} {
+		if report.Counts[status] > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", report.Counts[status], status))
+		}
+	}
+	fmt.Fprintf(&builder, "Emotes:    %d (%s)\n", len(report.Emotes), strings.Join(counts, ", "))
+	if report.Optimized > 0 {
+		fmt.Fprintf(&builder, "Optimized: %d PNG files, %s saved\n", report.Optimized, formatByteSize(report.SavedBytes))
+	}
+
+	if len(report.Failures) > 0 {
+		builder.WriteString("\nFailures:\n")
+		for _, failure := range report.Failures {
+			size := ""
+			if failure.Size != "" {
+				size = " " + failure.Size
+			}
+			fmt.Fprintf(&builder, "  %s (%s)%s: %s\n", failure.Emote, failure.ID, size, failure.Reason)
+		}
+	}
+
+	if len(report.Collisions) > 0 {
+		builder.WriteString("\nCollisions:\n")
+		for _, collision := range report.Collisions {
+			fmt.Fprintf(&builder, "  %s (%s): folder %s is used by %s, saved to %s\n", collision.Code, collision.ID, collision.Folder, collision.ConflictID, collision.SavedTo)
+		}
+	}
+
+	builder.WriteString("\nEmotes:\n")
+	for _, emote := range report.Emotes {
+		images := make([]string, 0, len(emote.Images))
+		for _, image := range emote.Images {
+			images = append(images, image.Size+" "+image.Status)
+		}
+		detail := strings.Join(images, ", ")
+		if emote.Error != "" {
+			detail = emote.Error
+		}
+		if emote.Duration > 0 {
+			detail += fmt.Sprintf(" (%s)", emote.Duration.Round(time.Millisecond))
+		}
+		fmt.Fprintf(&builder, "  [%s] %s (%s): %s\n", emote.Status, emote.Code, emote.ID, detail)
+	}
+	return builder.String()
+}
+
+func saveRunReport(store storage, outputRoot string, report *runReport) error {
+	reportBytes, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := store.WriteFile(filepath.Join(outputRoot, reportJSONFileName), append(reportBytes, '\n')); err != nil {
+		return err
+	}
+	return store.WriteFile(filepath.Join(outputRoot, reportTextFileName), []byte(report.text()))
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type resolvedChannel struct {
+	Input       string `json:"input"`
+	Provider    string `json:"provider"`
+	ChannelID   string `json:"channel_id,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+func newResolveFlagSet(options *downloadOptions, jsonOutput *bool) *flag.FlagSet {
+	flagSet := flag.NewFlagSet("resolve", flag.ContinueOnError)
+	flagSet.StringVar(&options.Provider, "provider", defaultProviderName, "emote provider to use ("+strings.Join(providerNames(), ", ")+")")
+	flagSet.StringVar(&options.YouTubeToken, "youtube-token", os.Getenv("YOUTUBE_OAUTH_TOKEN"), "OAuth token for the YouTube Data API, used to resolve handles")
+	flagSet.BoolVar(jsonOutput, "json", false, "print a JSON array with the provider and display name of each channel instead of just the IDs")
+	return flagSet
+}
+
+func runResolveCommand(arguments []string) int {
+	options := downloadOptions{CacheDir: defaultCacheDir(), CacheTTL: defaultCacheTTL, MaxConnsPerHost: defaultMaxConnsPerHost}
+	var jsonOutput bool
+
+	flagSet := newResolveFlagSet(&options, &jsonOutput)
+	positional, err := parseInterspersed(flagSet, arguments)
+	if err != nil {
+		return exitCodeForParseError(err)
+	}
+	if _, err := lookupProvider(options.Provider); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	configureProviders(options)
+	positional, err = expandStdinArguments(positional, os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if len(positional) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: twe-dlp resolve [--json] <channel>...")
+		return 2
+	}
+	httpClient := createHTTPClient(options)
+
+	exitCode := 0
+	results := make([]resolvedChannel, 0, len(positional))
+	for _, channelIdentifier := range positional {
+		channelIdentifier = strings.TrimSpace(channelIdentifier)
+		result := resolvedChannel{Input: channelIdentifier}
+
+		provider, providerIdentifier, err := selectProvider(channelIdentifier, options.Provider)
+		if err == nil {
+			result.Provider = provider.Name()
+			result.ChannelID, err = provider.ResolveChannelID(httpClient, providerIdentifier)
+		}
+		if err == nil && jsonOutput {
+			var channel *ChannelData
+			if channel, err = provider.FetchChannel(httpClient, result.ChannelID); err == nil {
+				result.DisplayName = channel.DisplayName
+			}
+		}
+		if err != nil {
+			result.Error = err.Error()
+			exitCode = 1
+		}
+		results = append(results, result)
+
+		if jsonOutput {
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", channelIdentifier, err)
+			continue
+		}
+		if len(positional) > 1 {
+			fmt.Printf("%s\t%s\n", channelIdentifier, result.ChannelID)
+		} else {
+			fmt.Println(result.ChannelID)
+		}
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(results)
+	}
+	return exitCode
+}
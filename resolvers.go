@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	twitchGQLURL      = "https://gql.twitch.tv/gql"
+	twitchGQLClientID = "kimne78kx3ncx6brgo4mv6wki5h1ko"
+	decapiBaseURL     = "https://decapi.me"
+	ivrBaseURL        = "https://api.ivr.fi"
+)
+
+var (
+	twitchLoginPattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,25}$`)
+	twitchIDPattern    = regexp.MustCompile(`^\d+$`)
+	errUserNotFound    = errors.New("user not found")
+)
+
+type channelResolver struct {
+	Name    string
+	Resolve func(httpClient *http.Client, login string) (string, error)
+}
+
+var channelResolvers = []channelResolver{
+	{"twitchemotes.com", resolveViaTwitchemotes},
+	{"gql.twitch.tv", resolveViaTwitchGQL},
+	{"decapi.me", resolveViaDecapi},
+	{"ivr.fi", resolveViaIVR},
+}
+
+func resolveViaTwitchemotes(httpClient *http.Client, login string) (string, error) {
+	formValues := url.Values{}
+	formValues.Set("query", login)
+	formValues.Set("source", "twe-dlp")
+
+	requestURL := twitchemotesBaseURL + "/search/channel"
+	request, err := http.NewRequest("POST", requestURL, strings.NewReader(formValues.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	finalURL := response.Request.URL.String()
+	match := channelURLPattern.FindStringSubmatch(finalURL)
+	if len(match) == 2 {
+		return match[1], nil
+	}
+
+	bodyBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+	match = channelURLPattern.FindStringSubmatch(string(bodyBytes))
+	if len(match) == 2 {
+		return match[1], nil
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("search failed with status %s", response.Status)
+	}
+	return "", errUserNotFound
+}
+
+func resolveViaTwitchGQL(httpClient *http.Client, login string) (string, error) {
+	if !twitchLoginPattern.MatchString(login) {
+		return "", fmt.Errorf("%q is not a valid Twitch login", login)
+	}
+
+	requestBody, err := json.Marshal(map[string]any{
+		"query":     "query($login: String!) { user(login: $login) { id } }",
+		"variables": map[string]string{"login": strings.ToLower(login)},
+	})
+	if err != nil {
+		return "", err
+	}
+	request, err := http.NewRequest("POST", twitchGQLURL, bytes.NewReader(requestBody))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Client-ID", twitchGQLClientID)
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("request failed with status %s", response.Status)
+	}
+
+	var result struct {
+		Data struct {
+			User *struct {
+				ID string `json:"id"`
+			} `json:"user"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if len(result.Errors) > 0 {
+		return "", errors.New(result.Errors[0].Message)
+	}
+	if result.Data.User == nil || !twitchIDPattern.MatchString(result.Data.User.ID) {
+		return "", errUserNotFound
+	}
+	return result.Data.User.ID, nil
+}
+
+func resolveViaDecapi(httpClient *http.Client, login string) (string, error) {
+	if !twitchLoginPattern.MatchString(login) {
+		return "", fmt.Errorf("%q is not a valid Twitch login", login)
+	}
+
+	response, err := httpClient.Get(decapiBaseURL + "/twitch/id/" + url.PathEscape(login))
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("request failed with status %s", response.Status)
+	}
+
+	bodyBytes, err := io.ReadAll(io.LimitReader(response.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+	channelID := strings.TrimSpace(string(bodyBytes))
+	if !twitchIDPattern.MatchString(channelID) {
+		return "", errUserNotFound
+	}
+	return channelID, nil
+}
+
+func resolveViaIVR(httpClient *http.Client, login string) (string, error) {
+	if !twitchLoginPattern.MatchString(login) {
+		return "", fmt.Errorf("%q is not a valid Twitch login", login)
+	}
+
+	var users []struct {
+		ID    string `json:"id"`
+		Login string `json:"login"`
+	}
+	if err := fetchJSON(httpClient, ivrBaseURL+"/v2/twitch/user?login="+url.QueryEscape(login), &users); err != nil {
+		return "", err
+	}
+	for _, user := range users {
+		if strings.EqualFold(user.Login, login) && twitchIDPattern.MatchString(user.ID) {
+			return user.ID, nil
+		}
+	}
+	return "", errUserNotFound
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+func loadRunReport(channelRoot string) (*runReport, error) {
+	reportBytes, err := os.ReadFile(filepath.Join(channelRoot, reportJSONFileName))
+	if err != nil {
+		return nil, err
+	}
+	var report runReport
+	if err := json.Unmarshal(reportBytes, &report); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", reportJSONFileName, err)
+	}
+	return &report, nil
+}
+
+func (report *runReport) retryEmoteIDs() []string {
+	var emoteIDs []string
+	for _, emote := range report.Emotes {
+		switch emote.Status {
+		case emoteStatusFailed, emoteStatusPartial, emoteStatusSkipped:
+			emoteIDs = append(emoteIDs, emote.ID)
+		}
+	}
+	return emoteIDs
+}
+
+func fetchReportChannel(httpClient *http.Client, provider emoteProvider, report *runReport) (*ChannelData, error) {
+	channel, err := provider.FetchChannel(httpClient, report.ChannelID)
+	if err == nil || report.ChannelName == "" {
+		return channel, err
+	}
+	channelID, resolveErr := provider.ResolveChannelID(httpClient, report.ChannelName)
+	if resolveErr != nil {
+		return nil, err
+	}
+	return provider.FetchChannel(httpClient, channelID)
+}
+
+func retryChannelFolder(httpClient *http.Client, channelRoot string, options downloadOptions, logFunc func(string)) error {
+	report, err := loadRunReport(channelRoot)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s has no %s; download the channel first", channelRoot, reportJSONFileName)
+	}
+	if err != nil {
+		return err
+	}
+	emoteIDs := report.retryEmoteIDs()
+	if len(emoteIDs) == 0 {
+		logFunc(fmt.Sprintf("Nothing to retry in %s: the last run had no failed or skipped emotes", channelRoot))
+		return nil
+	}
+
+	provider, err := lookupProvider(report.Provider)
+	if err != nil {
+		return err
+	}
+	channel, err := fetchReportChannel(httpClient, provider, report)
+	if err != nil {
+		return fmt.Errorf("cannot fetch channel %s: %w", report.ChannelID, err)
+	}
+
+	retryEmotes := make(map[string]EmoteData, len(emoteIDs))
+	var missing []string
+	for _, emoteID := range emoteIDs {
+		emoteData, exists := channel.Emotes[emoteID]
+		if !exists {
+			missing = append(missing, emoteID)
+			continue
+		}
+		retryEmotes[emoteID] = emoteData
+	}
+	sort.Strings(missing)
+	for _, emoteID := range missing {
+		logFunc(fmt.Sprintf("[skip] Emote %s is no longer listed for the channel", emoteID))
+	}
+	if len(retryEmotes) == 0 {
+		logFunc(fmt.Sprintf("Nothing to retry in %s: none of the failed emotes are listed anymore", channelRoot))
+		return nil
+	}
+	logFunc(fmt.Sprintf("Retrying %d of %d emotes from the last run", len(retryEmotes), len(report.Emotes)))
+	channel.Emotes = retryEmotes
+
+	options.ChannelRoot = channelRoot
+	return downloadChannelData(httpClient, provider, channel, options, logFunc)
+}
+
+func runRetryFailedCommand(arguments []string) int {
+	var options downloadOptions
+
+	flagSet := newCommandFlagSet("retry-failed", &options)
+	positional, err := parseCommandLine(flagSet, &options, arguments)
+	if err != nil {
+		return exitCodeForParseError(err)
+	}
+	if len(positional) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: twe-dlp retry-failed <channel folder>...")
+		return 2
+	}
+	options.Storage = localStorage{Durable: options.Durable}
+	httpClient := createHTTPClient(options)
+
+	logFunc := func(line string) {
+		fmt.Println(logTimestamp(options.Timestamps) + line)
+		options.Log.write(line)
+	}
+
+	restoreSignals := stopOnSignal(&options)
+	defer restoreSignals()
+
+	exitCode := 0
+	for _, channelRoot := range positional {
+		if len(positional) > 1 {
+			logFunc(fmt.Sprintf("Retrying %s", channelRoot))
+		}
+		if err := retryChannelFolder(httpClient, filepath.Clean(channelRoot), options, logFunc); err != nil {
+			if errors.Is(err, errDownloadInterrupted) {
+				printResumeHint(options)
+				return interruptedExitCode
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			options.Log.write(fmt.Sprintf("Error: %v", err))
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	emoteCodeSourceID = "emote ID"
+
+	twitchEmoteHostPath        = "static-cdn.jtvnw.net/emoticons/v2/"
+	twitchProfileImageHostPath = "static-cdn.jtvnw.net/jtv_user_pictures/"
+)
+
+var emoteUsesPattern = regexp.MustCompile(`(?i)(\d[\d,.]*)\s*([km])?\s*(?:uses|times|usages)\b`)
+
+var errMarkupNotRecognized = errors.New("twitchemotes.com page markup was not recognized (run \"twe-dlp doctor\" to see which selector broke)")
+
+type displayNameStrategy struct {
+	Name    string
+	Extract func(document *goquery.Document) string
+}
+
+type emoteImageStrategy struct {
+	Name      string
+	Selector  string
+	Attribute string
+}
+
+type emoteCodeStrategy struct {
+	Name    string
+	Extract func(selection *goquery.Selection) string
+}
+
+var displayNameStrategies = []displayNameStrategy{
+	{"div.card-header a", func(document *goquery.Document) string {
+		return strings.TrimSpace(document.Find("div.card-header").First().Find("a").First().Text())
+	}},
+	{"div.card-header h1-h3", func(document *goquery.Document) string {
+		return strings.TrimSpace(document.Find("div.card-header").First().Find("h1, h2, h3").First().Text())
+	}},
+	{"meta og:title", func(document *goquery.Document) string {
+		title, _ := document.Find("meta[property='og:title']").First().Attr("content")
+		return strings.TrimSpace(title)
+	}},
+}
+
+var emoteImageStrategies = []emoteImageStrategy{
+	{"img src", "img[src*='" + twitchEmoteHostPath + "']", "src"},
+	{"img data-src", "img[data-src*='" + twitchEmoteHostPath + "']", "data-src"},
+}
+
+var emoteCodeStrategies = []emoteCodeStrategy{
+	{"data-regex", func(selection *goquery.Selection) string {
+		code, _ := selection.Attr("data-regex")
+		return strings.TrimSpace(code)
+	}},
+	{"data-tooltip", func(selection *goquery.Selection) string {
+		tooltipHTML, _ := selection.Attr("data-tooltip")
+		return strings.TrimSpace(htmlTagPattern.ReplaceAllString(tooltipHTML, ""))
+	}},
+	{"alt", func(selection *goquery.Selection) string {
+		alt, _ := selection.Attr("alt")
+		return strings.TrimSpace(alt)
+	}},
+	{"title", func(selection *goquery.Selection) string {
+		return emoteOrParentAttr(selection, "title")
+	}},
+	{"aria-label", func(selection *goquery.Selection) string {
+		return emoteOrParentAttr(selection, "aria-label")
+	}},
+	{"table cell", func(selection *goquery.Selection) string {
+		code := ""
+		selection.Closest("td").Siblings().EachWithBreak(func(_ int, cell *goquery.Selection) bool {
+			if cell.Find("img").Length() > 0 {
+				return true
+			}
+			if fields := strings.Fields(cell.Text()); len(fields) > 0 {
+				code = fields[0]
+			}
+			return code == ""
+		})
+		return code
+	}},
+	{"parent text", func(selection *goquery.Selection) string {
+		return strings.TrimSpace(selection.Parent().Text())
+	}},
+}
+
+var emoteUsesStrategies = []emoteCodeStrategy{
+	{"data-uses", func(selection *goquery.Selection) string {
+		uses, _ := selection.Closest("[data-uses]").Attr("data-uses")
+		return uses
+	}},
+	{"data-count", func(selection *goquery.Selection) string {
+		count, _ := selection.Closest("[data-count]").Attr("data-count")
+		return count
+	}},
+	{"usage text", func(selection *goquery.Selection) string {
+		for container := selection.Parent(); container.Length() > 0; container = container.Parent() {
+			if container.Find("img").Length() > 1 {
+				return ""
+			}
+			if match := emoteUsesPattern.FindString(container.Text()); match != "" {
+				return match
+			}
+		}
+		return ""
+	}},
+}
+
+func getChannelDisplayName(document *goquery.Document) string {
+	for _, strategy := range displayNameStrategies {
+		if name := strategy.Extract(document); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+func getChannelProfileImage(document *goquery.Document) string {
+	imageSource, _ := document.Find("img[src*='" + twitchProfileImageHostPath + "']").First().Attr("src")
+	if imageSource == "" {
+		imageSource, _ = document.Find("meta[property='og:image']").First().Attr("content")
+		if !strings.Contains(imageSource, twitchProfileImageHostPath) {
+			return ""
+		}
+	}
+	if strings.HasPrefix(imageSource, "//") {
+		imageSource = "https:" + imageSource
+	}
+	return imageSource
+}
+
+func hasFollowerEmotes(document *goquery.Document) bool {
+	found := false
+	document.Find("h1, h2, h3, h4, h5, .card-header, .card-title, b, strong").EachWithBreak(func(_ int, selection *goquery.Selection) bool {
+		found = strings.Contains(strings.ToLower(selection.Text()), "follower")
+		return !found
+	})
+	return found
+}
+
+func parseEmoteImageURL(imageSource string) (string, EmoteData, bool) {
+	if !strings.Contains(imageSource, twitchEmoteHostPath) {
+		return "", EmoteData{}, false
+	}
+	if !strings.HasPrefix(imageSource, "http://") && !strings.HasPrefix(imageSource, "https://") {
+		resolved, err := resolveRelativeURL(twitchemotesBaseURL, imageSource)
+		if err != nil {
+			return "", EmoteData{}, false
+		}
+		imageSource = resolved
+	}
+
+	pathParts := strings.Split(imageSource, "/")
+	emoticonsIndex := -1
+	for index, part := range pathParts {
+		if part == "emoticons" {
+			emoticonsIndex = index
+			break
+		}
+	}
+	if emoticonsIndex == -1 || emoticonsIndex+3 >= len(pathParts) {
+		return "", EmoteData{}, false
+	}
+
+	emoteIdentifier := pathParts[emoticonsIndex+2]
+	return emoteIdentifier, EmoteData{
+		BaseURL:    strings.Join(pathParts[:emoticonsIndex+4], "/"),
+		FormatType: pathParts[emoticonsIndex+3],
+	}, true
+}
+
+func emoteOrParentAttr(selection *goquery.Selection, attribute string) string {
+	for _, candidate := range []*goquery.Selection{selection, selection.Parent()} {
+		if value, exists := candidate.Attr(attribute); exists && strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+func extractEmoteCode(selection *goquery.Selection) (string, string) {
+	for _, strategy := range emoteCodeStrategies {
+		if code := strategy.Extract(selection); code != "" {
+			return code, strategy.Name
+		}
+	}
+	return "", ""
+}
+
+func emoteCodeSources(emotes map[string]EmoteData) string {
+	counts := make(map[string]int)
+	for _, emoteData := range emotes {
+		if emoteData.CodeSource != "" {
+			counts[emoteData.CodeSource]++
+		}
+	}
+	sources := make([]string, 0, len(counts))
+	for source := range counts {
+		sources = append(sources, source)
+	}
+	sort.Slice(sources, func(left, right int) bool {
+		if counts[sources[left]] != counts[sources[right]] {
+			return counts[sources[left]] > counts[sources[right]]
+		}
+		return sources[left] < sources[right]
+	})
+	for index, source := range sources {
+		sources[index] = fmt.Sprintf("%s (%d)", source, counts[source])
+	}
+	return strings.Join(sources, ", ")
+}
+
+func parseUsageCount(text string) (int64, bool) {
+	text = strings.TrimSpace(text)
+	multiplier := 1.0
+	if match := emoteUsesPattern.FindStringSubmatch(text); match != nil {
+		text = match[1]
+		switch strings.ToLower(match[2]) {
+		case "k":
+			multiplier = 1e3
+		case "m":
+			multiplier = 1e6
+		}
+	}
+	if multiplier == 1 {
+		text = strings.ReplaceAll(text, ".", "")
+	}
+	value, err := strconv.ParseFloat(strings.ReplaceAll(text, ",", ""), 64)
+	if err != nil || value < 0 {
+		return 0, false
+	}
+	return int64(value * multiplier), true
+}
+
+func extractEmoteUses(selection *goquery.Selection) int64 {
+	for _, strategy := range emoteUsesStrategies {
+		if uses, valid := parseUsageCount(strategy.Extract(selection)); valid {
+			return uses
+		}
+	}
+	return 0
+}
+
+func collectEmoteMetadata(document *goquery.Document) map[string]EmoteData {
+	emoteMap := make(map[string]EmoteData)
+
+	for _, strategy := range emoteImageStrategies {
+		document.Find(strategy.Selector).Each(func(_ int, selection *goquery.Selection) {
+			imageSource, _ := selection.Attr(strategy.Attribute)
+			emoteIdentifier, emoteData, valid := parseEmoteImageURL(imageSource)
+			if !valid {
+				return
+			}
+			if _, exists := emoteMap[emoteIdentifier]; exists {
+				return
+			}
+
+			emoteData.EmoteCode, emoteData.CodeSource = extractEmoteCode(selection)
+			if emoteData.EmoteCode == "" {
+				emoteData.EmoteCode = emoteIdentifier
+				emoteData.CodeSource = emoteCodeSourceID
+			}
+			emoteData.Uses = extractEmoteUses(selection)
+			emoteMap[emoteIdentifier] = emoteData
+		})
+	}
+
+	return emoteMap
+}
+
+func parseChannelPage(channelID string, page io.Reader) (*ChannelData, error) {
+	document, err := goquery.NewDocumentFromReader(page)
+	if err != nil {
+		return nil, err
+	}
+	followerEmotes := hasFollowerEmotes(document)
+	channel := &ChannelData{
+		ID:              channelID,
+		DisplayName:     getChannelDisplayName(document),
+		ProfileImageURL: getChannelProfileImage(document),
+		FollowerEmotes:  &followerEmotes,
+		Emotes:          collectEmoteMetadata(document),
+	}
+	if channel.DisplayName == "" && len(channel.Emotes) == 0 {
+		return nil, errMarkupNotRecognized
+	}
+	return channel, nil
+}
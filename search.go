@@ -0,0 +1,610 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+const defaultSearchLimit = 20
+
+var (
+	sevenTVGQLURL        = "https://7tv.io/v3/gql"
+	bttvSearchURL        = "https://api.betterttv.net/3/emotes/shared/search"
+	ffzSearchURL         = "https://api.frankerfacez.com/v1/emotes"
+	twitchemotesEmoteURL = twitchemotesBaseURL + "/search/emote"
+)
+
+type emoteSearchSource struct {
+	Name   string
+	Sizes  []string
+	Search func(httpClient *http.Client, query string, limit int) ([]emoteSearchResult, error)
+}
+
+type emoteSearchResult struct {
+	Source   string    `json:"source"`
+	ID       string    `json:"id"`
+	Code     string    `json:"code"`
+	Owner    string    `json:"owner,omitempty"`
+	OwnerID  string    `json:"owner_id,omitempty"`
+	Animated bool      `json:"animated,omitempty"`
+	Emote    EmoteData `json:"-"`
+}
+
+type searchProvider struct {
+	name  string
+	sizes []string
+}
+
+var emoteSearchSources = []emoteSearchSource{
+	{"7tv", []string{"1x", "2x", "3x", "4x"}, searchSevenTV},
+	{"bttv", []string{"1x", "2x", "3x"}, searchBTTV},
+	{"ffz", []string{"1", "2", "4"}, searchFFZ},
+	{"twitch", emoteSizeList, searchTwitchemotes},
+}
+
+func emoteSearchSourceNames() []string {
+	names := make([]string, 0, len(emoteSearchSources))
+	for _, source := range emoteSearchSources {
+		names = append(names, source.Name)
+	}
+	return names
+}
+
+func (provider searchProvider) Name() string {
+	return provider.name
+}
+
+func (provider searchProvider) ResolveChannelID(httpClient *http.Client, channelIdentifier string) (string, error) {
+	return "", fmt.Errorf("%s channels can only be downloaded from search results", provider.name)
+}
+
+func (provider searchProvider) FetchChannel(httpClient *http.Client, channelID string) (*ChannelData, error) {
+	return nil, fmt.Errorf("%s channels can only be downloaded from search results", provider.name)
+}
+
+func (provider searchProvider) Sizes() []string {
+	return provider.sizes
+}
+
+func (provider searchProvider) ImageURL(emoteData EmoteData, sizeValue string) string {
+	return strings.ReplaceAll(emoteData.BaseURL, pluginSizePlaceholder, sizeValue)
+}
+
+func getSearchJSON(httpClient *http.Client, request *http.Request, target any) error {
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("request failed with status %s", response.Status)
+	}
+	return json.NewDecoder(response.Body).Decode(target)
+}
+
+func searchSevenTV(httpClient *http.Client, query string, limit int) ([]emoteSearchResult, error) {
+	requestBody, err := json.Marshal(map[string]any{
+		"query":     "query($query: String!, $limit: Int) { emotes(query: $query, limit: $limit) { items { id name animated owner { id username display_name } } } }",
+		"variables": map[string]any{"query": query, "limit": limit},
+	})
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest(http.MethodPost, sevenTVGQLURL, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	var result struct {
+		Data struct {
+			Emotes struct {
+				Items []struct {
+					ID       string `json:"id"`
+					Name     string `json:"name"`
+					Animated bool   `json:"animated"`
+					Owner    struct {
+						ID          string `json:"id"`
+						Username    string `json:"username"`
+						DisplayName string `json:"display_name"`
+					} `json:"owner"`
+				} `json:"items"`
+			} `json:"emotes"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := getSearchJSON(httpClient, request, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Errors) > 0 {
+		return nil, errors.New(result.Errors[0].Message)
+	}
+
+	results := make([]emoteSearchResult, 0, len(result.Data.Emotes.Items))
+	for _, item := range result.Data.Emotes.Items {
+		extension, formatType := "png", "static"
+		if item.Animated {
+			extension, formatType = "gif", "animated"
+		}
+		owner := item.Owner.DisplayName
+		if owner == "" {
+			owner = item.Owner.Username
+		}
+		results = append(results, emoteSearchResult{
+			Source: "7tv", ID: item.ID, Code: item.Name, Owner: owner, OwnerID: item.Owner.ID, Animated: item.Animated,
+			Emote: EmoteData{BaseURL: fmt.Sprintf("https://cdn.7tv.app/emote/%s/%s.%s", item.ID, pluginSizePlaceholder, extension), FormatType: formatType, EmoteCode: item.Name},
+		})
+	}
+	return results, nil
+}
+
+func searchBTTV(httpClient *http.Client, query string, limit int) ([]emoteSearchResult, error) {
+	requestURL := bttvSearchURL + "?" + url.Values{"query": {query}, "offset": {"0"}, "limit": {strconv.Itoa(limit)}}.Encode()
+	request, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []struct {
+		ID       string `json:"id"`
+		Code     string `json:"code"`
+		Animated bool   `json:"animated"`
+		User     struct {
+			ID          string `json:"id"`
+			Name        string `json:"name"`
+			DisplayName string `json:"displayName"`
+		} `json:"user"`
+	}
+	if err := getSearchJSON(httpClient, request, &items); err != nil {
+		return nil, err
+	}
+
+	results := make([]emoteSearchResult, 0, len(items))
+	for _, item := range items {
+		formatType := "static"
+		if item.Animated {
+			formatType = "animated"
+		}
+		owner := item.User.DisplayName
+		if owner == "" {
+			owner = item.User.Name
+		}
+		results = append(results, emoteSearchResult{
+			Source: "bttv", ID: item.ID, Code: item.Code, Owner: owner, OwnerID: item.User.ID, Animated: item.Animated,
+			Emote: EmoteData{BaseURL: fmt.Sprintf("https://cdn.betterttv.net/emote/%s/%s", item.ID, pluginSizePlaceholder), FormatType: formatType, EmoteCode: item.Code},
+		})
+	}
+	return results, nil
+}
+
+func searchFFZ(httpClient *http.Client, query string, limit int) ([]emoteSearchResult, error) {
+	requestURL := ffzSearchURL + "?" + url.Values{"q": {query}, "sort": {"count-desc"}, "per_page": {strconv.Itoa(limit)}}.Encode()
+	request, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Emoticons []struct {
+			ID    int    `json:"id"`
+			Name  string `json:"name"`
+			Owner struct {
+				ID          int    `json:"_id"`
+				Name        string `json:"name"`
+				DisplayName string `json:"display_name"`
+			} `json:"owner"`
+		} `json:"emoticons"`
+	}
+	if err := getSearchJSON(httpClient, request, &result); err != nil {
+		return nil, err
+	}
+
+	results := make([]emoteSearchResult, 0, len(result.Emoticons))
+	for _, item := range result.Emoticons {
+		emoteID := strconv.Itoa(item.ID)
+		owner := item.Owner.DisplayName
+		if owner == "" {
+			owner = item.Owner.Name
+		}
+		ownerID := ""
+		if item.Owner.ID != 0 {
+			ownerID = strconv.Itoa(item.Owner.ID)
+		}
+		results = append(results, emoteSearchResult{
+			Source: "ffz", ID: emoteID, Code: item.Name, Owner: owner, OwnerID: ownerID,
+			Emote: EmoteData{BaseURL: fmt.Sprintf("https://cdn.frankerfacez.com/emote/%s/%s", emoteID, pluginSizePlaceholder), FormatType: "static", EmoteCode: item.Name},
+		})
+	}
+	return results, nil
+}
+
+func searchTwitchemotes(httpClient *http.Client, query string, limit int) ([]emoteSearchResult, error) {
+	document, response, err := fetchDocument(httpClient, twitchemotesEmoteURL+"?"+url.Values{"query": {query}}.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request failed with status %s", response.Status)
+	}
+	return parseTwitchemotesSearch(document, query, limit), nil
+}
+
+func parseTwitchemotesSearch(document *goquery.Document, query string, limit int) []emoteSearchResult {
+	var results []emoteSearchResult
+	seen := make(map[string]bool)
+	lowerQuery := strings.ToLower(query)
+	for _, strategy := range emoteImageStrategies {
+		document.Find(strategy.Selector).EachWithBreak(func(_ int, selection *goquery.Selection) bool {
+			imageSource, _ := selection.Attr(strategy.Attribute)
+			emoteIdentifier, emoteData, valid := parseEmoteImageURL(imageSource)
+			if !valid || seen[emoteIdentifier] {
+				return true
+			}
+			emoteData.EmoteCode, emoteData.CodeSource = extractEmoteCode(selection)
+			if !strings.Contains(strings.ToLower(emoteData.EmoteCode), lowerQuery) {
+				return true
+			}
+			seen[emoteIdentifier] = true
+
+			result := emoteSearchResult{Source: "twitch", ID: emoteIdentifier, Code: emoteData.EmoteCode, Animated: emoteData.FormatType == "animated", Emote: emoteData}
+			ownerLink := selection.Parents().FilterFunction(func(_ int, parent *goquery.Selection) bool {
+				return parent.Find("a[href*='/channels/']").Length() > 0
+			}).First().Find("a[href*='/channels/']").First()
+			if href, exists := ownerLink.Attr("href"); exists {
+				if match := channelURLPattern.FindStringSubmatch(href); len(match) == 2 {
+					result.OwnerID = match[1]
+					result.Owner = strings.TrimSpace(ownerLink.Text())
+				}
+			}
+			results = append(results, result)
+			return len(results) < limit
+		})
+	}
+	return results
+}
+
+func searchEmotes(httpClient *http.Client, query string, sources []emoteSearchSource, limit int) ([]emoteSearchResult, []error) {
+	sourceResults := make([][]emoteSearchResult, len(sources))
+	sourceErrors := make([]error, len(sources))
+	var wait sync.WaitGroup
+	for index, source := range sources {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			results, err := source.Search(httpClient, query, limit)
+			if err != nil {
+				sourceErrors[index] = fmt.Errorf("%s: %w", source.Name, err)
+			}
+			if len(results) > limit {
+				results = results[:limit]
+			}
+			sourceResults[index] = results
+		}()
+	}
+	wait.Wait()
+
+	var results []emoteSearchResult
+	var errs []error
+	for index := range sources {
+		results = append(results, sourceResults[index]...)
+		if sourceErrors[index] != nil {
+			errs = append(errs, sourceErrors[index])
+		}
+	}
+	return results, errs
+}
+
+func parseResultSelection(selection string, count int) ([]int, error) {
+	if strings.EqualFold(strings.TrimSpace(selection), "all") {
+		indexes := make([]int, count)
+		for index := range indexes {
+			indexes[index] = index
+		}
+		return indexes, nil
+	}
+	var indexes []int
+	for _, part := range splitCommaList(selection) {
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(first))
+		end := start
+		if err == nil && isRange {
+			end, err = strconv.Atoi(strings.TrimSpace(last))
+		}
+		if err != nil || start < 1 || end < start || end > count {
+			return nil, fmt.Errorf("invalid result selection %q (results are numbered 1 to %d)", part, count)
+		}
+		for number := start; number <= end; number++ {
+			if !slices.Contains(indexes, number-1) {
+				indexes = append(indexes, number-1)
+			}
+		}
+	}
+	return indexes, nil
+}
+
+func downloadSearchResults(httpClient *http.Client, results []emoteSearchResult, options downloadOptions, logFunc func(string)) error {
+	type ownerGroup struct {
+		provider emoteProvider
+		channel  *ChannelData
+	}
+	var groups []*ownerGroup
+	groupIndex := make(map[string]*ownerGroup)
+	for _, result := range results {
+		owner, ownerID := result.Owner, result.OwnerID
+		if owner == "" && ownerID == "" {
+			owner, ownerID = "search", "search"
+		}
+		key := result.Source + "/" + ownerID + "/" + owner
+		group := groupIndex[key]
+		if group == nil {
+			var provider emoteProvider = twitchProvider{}
+			for _, source := range emoteSearchSources {
+				if source.Name == result.Source && source.Name != defaultProviderName {
+					provider = searchProvider{name: source.Name, sizes: source.Sizes}
+				}
+			}
+			group = &ownerGroup{provider: provider, channel: &ChannelData{ID: ownerID, DisplayName: owner, Emotes: make(map[string]EmoteData)}}
+			groupIndex[key] = group
+			groups = append(groups, group)
+		}
+		group.channel.Emotes[result.ID] = result.Emote
+	}
+
+	var errs []error
+	for _, group := range groups {
+		if err := downloadChannelData(httpClient, group.provider, group.channel, options, logFunc); err != nil {
+			errs = append(errs, fmt.Errorf("%s (%s): %w", group.channel.DisplayName, group.provider.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func formatSearchResult(result emoteSearchResult) string {
+	owner := result.Owner
+	if owner == "" {
+		owner = "-"
+	}
+	animated := ""
+	if result.Animated {
+		animated = " (animated)"
+	}
+	return fmt.Sprintf("%-24s %-6s %-20s %s%s", result.Code, result.Source, owner, result.ID, animated)
+}
+
+type searchPicker struct {
+	results   []emoteSearchResult
+	cursor    int
+	selected  map[int]bool
+	confirmed bool
+	accent    lipgloss.Style
+	muted     lipgloss.Style
+}
+
+func newSearchPicker(results []emoteSearchResult, palette themePalette) searchPicker {
+	return searchPicker{
+		results:  results,
+		selected: make(map[int]bool),
+		accent:   foregroundStyle(palette.Accent).Bold(true),
+		muted:    foregroundStyle(palette.Muted),
+	}
+}
+
+func (picker searchPicker) Init() tea.Cmd {
+	return nil
+}
+
+func (picker searchPicker) Update(message tea.Msg) (tea.Model, tea.Cmd) {
+	keyMessage, isKey := message.(tea.KeyMsg)
+	if !isKey {
+		return picker, nil
+	}
+	switch keyMessage.String() {
+	case "up", "k":
+		if picker.cursor > 0 {
+			picker.cursor--
+		}
+	case "down", "j":
+		if picker.cursor < len(picker.results)-1 {
+			picker.cursor++
+		}
+	case " ", "x":
+		picker.selected[picker.cursor] = !picker.selected[picker.cursor]
+	case "a":
+		selectAll := len(picker.chosen()) < len(picker.results)
+		for index := range picker.results {
+			picker.selected[index] = selectAll
+		}
+	case "enter":
+		if len(picker.chosen()) == 0 {
+			picker.selected[picker.cursor] = true
+		}
+		picker.confirmed = true
+		return picker, tea.Quit
+	case "q", "esc", "ctrl+c":
+		return picker, tea.Quit
+	}
+	return picker, nil
+}
+
+func (picker searchPicker) chosen() []int {
+	var indexes []int
+	for index := range picker.results {
+		if picker.selected[index] {
+			indexes = append(indexes, index)
+		}
+	}
+	return indexes
+}
+
+func (picker searchPicker) View() string {
+	if picker.confirmed {
+		return ""
+	}
+	var view strings.Builder
+	view.WriteString(picker.accent.Render(fmt.Sprintf("%d results", len(picker.results))) + "\n\n")
+	for index, result := range picker.results {
+		cursor := "  "
+		if index == picker.cursor {
+			cursor = "> "
+		}
+		check := "[ ]"
+		if picker.selected[index] {
+			check = "[x]"
+		}
+		line := fmt.Sprintf("%s%s %s", cursor, check, formatSearchResult(result))
+		if index == picker.cursor {
+			line = picker.accent.Render(line)
+		}
+		view.WriteString(line + "\n")
+	}
+	view.WriteString("\n" + picker.muted.Render("space select • a all • enter download • q cancel"))
+	return view.String()
+}
+
+func newSearchFlagSet(options *downloadOptions, sourceNames *[]string, limit *int, selection *string, pick *bool, jsonOutput *bool) *flag.FlagSet {
+	flagSet := newCommandFlagSet("search", options)
+	flagSet.Func("source", "only search these sources ("+strings.Join(emoteSearchSourceNames(), ", ")+"; comma separated, repeatable)", func(value string) error {
+		*sourceNames = append(*sourceNames, splitCommaList(value)...)
+		return nil
+	})
+	flagSet.IntVar(limit, "limit", defaultSearchLimit, "maximum number of results from each source")
+	flagSet.StringVar(selection, "download", "", "download these results by number, e.g. 1,3-5 or all")
+	flagSet.BoolVar(pick, "pick", false, "choose the results to download in an interactive picker")
+	flagSet.BoolVar(jsonOutput, "json", false, "print the results as JSON")
+	return flagSet
+}
+
+func runSearchCommand(arguments []string) int {
+	var options downloadOptions
+	var sourceNames []string
+	var limit int
+	var selection string
+	var pick, jsonOutput bool
+
+	flagSet := newSearchFlagSet(&options, &sourceNames, &limit, &selection, &pick, &jsonOutput)
+	positional, err := parseCommandLine(flagSet, &options, arguments)
+	if err != nil {
+		return exitCodeForParseError(err)
+	}
+	if len(positional) != 1 || strings.TrimSpace(positional[0]) == "" {
+		fmt.Fprintln(os.Stderr, "Usage: twe-dlp search [--source 7tv,bttv,ffz,twitch] [--download 1,3-5 | --pick] <code>")
+		return 2
+	}
+	if limit <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --limit must be positive")
+		return 2
+	}
+	if pick && (selection != "" || jsonOutput) {
+		fmt.Fprintln(os.Stderr, "Error: --pick cannot be combined with --download or --json")
+		return 2
+	}
+	if jsonOutput && selection != "" {
+		fmt.Fprintln(os.Stderr, "Error: --json cannot be combined with --download")
+		return 2
+	}
+	sources := emoteSearchSources
+	if len(sourceNames) > 0 {
+		sources = nil
+		for _, name := range sourceNames {
+			index := slices.IndexFunc(emoteSearchSources, func(source emoteSearchSource) bool { return strings.EqualFold(source.Name, name) })
+			if index < 0 {
+				fmt.Fprintf(os.Stderr, "Error: unknown search source %q (available: %s)\n", name, strings.Join(emoteSearchSourceNames(), ", "))
+				return 2
+			}
+			sources = append(sources, emoteSearchSources[index])
+		}
+	}
+	httpClient := createHTTPClient(options)
+	query := strings.TrimSpace(positional[0])
+
+	results, errs := searchEmotes(httpClient, query, sources, limit)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if results == nil {
+			results = []emoteSearchResult{}
+		}
+		if err := encoder.Encode(results); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if len(results) == 0 {
+		fmt.Fprintf(os.Stderr, "No emotes matching %q found.\n", query)
+		return 1
+	}
+
+	var chosen []int
+	switch {
+	case pick:
+		config := loadConfig()
+		if options.Theme == "" {
+			options.Theme = config.Theme
+		}
+		palette, err := resolveTheme(options.Theme, config.Palette)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		if options.NoColor {
+			lipgloss.SetColorProfile(termenv.Ascii)
+		}
+		finalModel, err := tea.NewProgram(newSearchPicker(results, palette)).Run()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error running picker: %v\n", err)
+			return 1
+		}
+		picker := finalModel.(searchPicker)
+		if !picker.confirmed {
+			return 0
+		}
+		chosen = picker.chosen()
+	case selection != "":
+		if chosen, err = parseResultSelection(selection, len(results)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+	default:
+		for index, result := range results {
+			fmt.Printf("%3d. %s\n", index+1, formatSearchResult(result))
+		}
+		return 0
+	}
+
+	selected := make([]emoteSearchResult, 0, len(chosen))
+	for _, index := range chosen {
+		selected = append(selected, results[index])
+	}
+	logFunc := func(line string) {
+		fmt.Println(logTimestamp(options.Timestamps) + line)
+		options.Log.write(line)
+	}
+	if err := downloadSearchResults(httpClient, selected, options, logFunc); err != nil {
+		fmt.Fprintf(os.Stderr, "Error downloading emotes: %v\n", err)
+		return 1
+	}
+	return 0
+}
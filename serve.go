@@ -0,0 +1,414 @@
+package main
+
+import (
+	"archive/zip"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	jobStatusQueued  = "queued"
+	jobStatusRunning = "running"
+	jobStatusDone    = "done"
+	jobStatusFailed  = "failed"
+
+	jobMaxLogLines = 1000
+
+	defaultServeListenAddress = "127.0.0.1:8080"
+)
+
+//go:embed web/index.html
+var webIndexHTML []byte
+
+type downloadJob struct {
+	ID         string     `json:"id"`
+	Channel    string     `json:"channel"`
+	Only       []string   `json:"only,omitempty"`
+	Exclude    []string   `json:"exclude,omitempty"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	OutputDir  string     `json:"output_dir,omitempty"`
+	Logs       []string   `json:"logs,omitempty"`
+
+	progress downloadProgress
+	logCount int
+}
+
+type downloadRequest struct {
+	Channel string   `json:"channel"`
+	Only    []string `json:"only"`
+	Exclude []string `json:"exclude"`
+}
+
+type serveOptions struct {
+	Listen     string
+	GRPCListen string
+	JobsPath   string
+}
+
+type downloadServer struct {
+	httpClient *http.Client
+	options    downloadOptions
+	slots      chan struct{}
+	store      *jobStore
+
+	lock   sync.Mutex
+	jobs   map[string]*downloadJob
+	order  []string
+	nextID int
+}
+
+func newDownloadServer(httpClient *http.Client, options downloadOptions) *downloadServer {
+	return &downloadServer{
+		httpClient: httpClient,
+		options:    options,
+		slots:      make(chan struct{}, options.ChannelConcurrency),
+		jobs:       make(map[string]*downloadJob),
+	}
+}
+
+func (server *downloadServer) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", server.handleIndex)
+	mux.HandleFunc("POST /download", server.handleDownload)
+	mux.HandleFunc("GET /jobs", server.handleListJobs)
+	mux.HandleFunc("GET /jobs/{id}", server.handleGetJob)
+	mux.HandleFunc("GET /jobs/{id}/zip", server.handleJobZip)
+	mux.HandleFunc("GET /metrics", handleMetrics)
+	listFeeds, serveFeed := feedHandlers(outputStorage(server.options), server.channelRoots)
+	mux.HandleFunc("GET /feeds", listFeeds)
+	mux.HandleFunc("GET /feeds/{name}", serveFeed)
+	return mux
+}
+
+func (server *downloadServer) channelRoots() []string {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	var channelRoots []string
+	for _, jobID := range server.order {
+		outputDir := server.jobs[jobID].OutputDir
+		if outputDir != "" && !slices.Contains(channelRoots, outputDir) {
+			channelRoots = append(channelRoots, outputDir)
+		}
+	}
+	return channelRoots
+}
+
+func (server *downloadServer) handleIndex(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	writer.Write(webIndexHTML)
+}
+
+func (server *downloadServer) handleJobZip(writer http.ResponseWriter, request *http.Request) {
+	job, exists := server.snapshot(request.PathValue("id"))
+	if !exists {
+		writeJSONError(writer, http.StatusNotFound, errors.New("job not found"))
+		return
+	}
+	if !readableOutput(server.options) {
+		writeJSONError(writer, http.StatusNotImplemented, errors.New("ZIP downloads need local output in the folders or flat layout"))
+		return
+	}
+	if job.Status != jobStatusDone || job.OutputDir == "" {
+		writeJSONError(writer, http.StatusConflict, fmt.Errorf("job is %s", job.Status))
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/zip")
+	writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(job.OutputDir)+".zip"))
+	if err := writeDirectoryZip(writer, job.OutputDir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing ZIP for job %s: %v\n", job.ID, err)
+	}
+}
+
+func readableOutput(options downloadOptions) bool {
+	return isLocalStorage(outputStorage(options)) && options.Layout != layoutCAS
+}
+
+func writeDirectoryZip(writer io.Writer, directory string) error {
+	archive := zip.NewWriter(writer)
+	rootName := filepath.Base(directory)
+	err := filepath.WalkDir(directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || strings.HasSuffix(path, ".part") {
+			return err
+		}
+		relativePath, err := filepath.Rel(directory, path)
+		if err != nil {
+			return err
+		}
+		entryWriter, err := archive.Create(filepath.ToSlash(filepath.Join(rootName, relativePath)))
+		if err != nil {
+			return err
+		}
+		sourceFile, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer sourceFile.Close()
+		_, err = io.Copy(entryWriter, sourceFile)
+		return err
+	})
+	if err != nil {
+		archive.Close()
+		return err
+	}
+	return archive.Close()
+}
+
+func (server *downloadServer) handleDownload(writer http.ResponseWriter, request *http.Request) {
+	var body downloadRequest
+	if err := json.NewDecoder(http.MaxBytesReader(writer, request.Body, 1<<20)).Decode(&body); err != nil {
+		writeJSONError(writer, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	body.Channel = strings.TrimSpace(body.Channel)
+	if body.Channel == "" {
+		writeJSONError(writer, http.StatusBadRequest, errors.New("channel is required"))
+		return
+	}
+	if _, _, err := selectProvider(body.Channel, server.options.Provider); err != nil {
+		writeJSONError(writer, http.StatusBadRequest, err)
+		return
+	}
+
+	job := server.enqueue(body)
+	writer.Header().Set("Location", "/jobs/"+job.ID)
+	writeJSON(writer, http.StatusAccepted, job)
+}
+
+func (server *downloadServer) handleListJobs(writer http.ResponseWriter, request *http.Request) {
+	writeJSON(writer, http.StatusOK, server.list())
+}
+
+func (server *downloadServer) list() []downloadJob {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	jobs := make([]downloadJob, 0, len(server.order))
+	for _, jobID := range server.order {
+		job := *server.jobs[jobID]
+		job.Logs = nil
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+func (server *downloadServer) handleGetJob(writer http.ResponseWriter, request *http.Request) {
+	job, exists := server.snapshot(request.PathValue("id"))
+	if !exists {
+		writeJSONError(writer, http.StatusNotFound, errors.New("job not found"))
+		return
+	}
+	writeJSON(writer, http.StatusOK, job)
+}
+
+func (server *downloadServer) enqueue(body downloadRequest) downloadJob {
+	server.lock.Lock()
+	server.nextID++
+	job := &downloadJob{
+		ID:        strconv.Itoa(server.nextID),
+		Channel:   body.Channel,
+		Only:      body.Only,
+		Exclude:   body.Exclude,
+		Status:    jobStatusQueued,
+		CreatedAt: time.Now().UTC(),
+		Logs:      []string{},
+	}
+	server.jobs[job.ID] = job
+	server.order = append(server.order, job.ID)
+	snapshot := *job
+	server.lock.Unlock()
+
+	server.persist(job.ID)
+	go server.run(job)
+	return snapshot
+}
+
+func (server *downloadServer) persist(jobID string) {
+	if server.store == nil {
+		return
+	}
+	job, exists := server.snapshot(jobID)
+	if !exists {
+		return
+	}
+	if err := server.store.save(job); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving job %s: %v\n", jobID, err)
+	}
+}
+
+func (server *downloadServer) restore() error {
+	jobs, err := server.store.load()
+	if err != nil {
+		return err
+	}
+	var pending []*downloadJob
+	server.lock.Lock()
+	for _, job := range jobs {
+		if jobNumber, err := strconv.Atoi(job.ID); err == nil && jobNumber > server.nextID {
+			server.nextID = jobNumber
+		}
+		if job.Logs == nil {
+			job.Logs = []string{}
+		}
+		if job.Status == jobStatusQueued || job.Status == jobStatusRunning {
+			job.Status = jobStatusQueued
+			job.StartedAt = nil
+			job.Logs = append(job.Logs, logTimestamp(server.options.Timestamps)+"Resuming after a server restart")
+			pending = append(pending, job)
+		}
+		job.logCount = len(job.Logs)
+		server.jobs[job.ID] = job
+		server.order = append(server.order, job.ID)
+	}
+	server.lock.Unlock()
+
+	for _, job := range pending {
+		server.persist(job.ID)
+		go server.run(job)
+	}
+	if len(pending) > 0 {
+		fmt.Printf("Resuming %d unfinished jobs\n", len(pending))
+	}
+	return nil
+}
+
+func (server *downloadServer) snapshot(jobID string) (downloadJob, bool) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	job, exists := server.jobs[jobID]
+	if !exists {
+		return downloadJob{}, false
+	}
+	snapshot := *job
+	snapshot.Logs = append([]string(nil), job.Logs...)
+	return snapshot, true
+}
+
+func (server *downloadServer) run(job *downloadJob) {
+	server.slots <- struct{}{}
+	defer func() { <-server.slots }()
+
+	server.lock.Lock()
+	startedAt := time.Now().UTC()
+	job.Status = jobStatusRunning
+	job.StartedAt = &startedAt
+	server.lock.Unlock()
+	server.persist(job.ID)
+
+	options := server.options
+	options.Only = append(append([]string(nil), options.Only...), job.Only...)
+	options.Exclude = append(append([]string(nil), options.Exclude...), job.Exclude...)
+	logFunc := func(line string) {
+		options.Log.write(fmt.Sprintf("[job %s %s] %s", job.ID, job.Channel, line))
+		server.lock.Lock()
+		job.Logs = append(job.Logs, logTimestamp(options.Timestamps)+line)
+		job.logCount++
+		if len(job.Logs) > jobMaxLogLines {
+			job.Logs = job.Logs[len(job.Logs)-jobMaxLogLines:]
+		}
+		server.lock.Unlock()
+	}
+	options.Progress = func(progress downloadProgress) {
+		server.lock.Lock()
+		job.progress = progress
+		server.lock.Unlock()
+	}
+
+	outputRoot, err := downloadChannelInput(server.httpClient, job.Channel, options, logFunc)
+
+	server.lock.Lock()
+	finishedAt := time.Now().UTC()
+	job.FinishedAt = &finishedAt
+	job.OutputDir = outputRoot
+	if err != nil {
+		job.Status = jobStatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = jobStatusDone
+	}
+	server.lock.Unlock()
+	server.persist(job.ID)
+}
+
+func writeJSON(writer http.ResponseWriter, status int, value any) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+func writeJSONError(writer http.ResponseWriter, status int, err error) {
+	writeJSON(writer, status, map[string]string{"error": err.Error()})
+}
+
+func newServeFlagSet(options *downloadOptions, serve *serveOptions) *flag.FlagSet {
+	flagSet := newCommandFlagSet("serve", options)
+	flagSet.StringVar(&serve.Listen, "listen", defaultServeListenAddress, "address to listen on (the API has no authentication, so only expose it to trusted networks)")
+	flagSet.StringVar(&serve.GRPCListen, "grpc-listen", "", "also serve the gRPC job API on this address")
+	flagSet.StringVar(&serve.JobsPath, "jobs-db", defaultJobsPath(), "file that keeps jobs across restarts (empty to keep them in memory only)")
+	return flagSet
+}
+
+func runServeCommand(arguments []string) int {
+	var options downloadOptions
+	var serve serveOptions
+
+	flagSet := newServeFlagSet(&options, &serve)
+	positional, err := parseCommandLine(flagSet, &options, arguments)
+	if err != nil {
+		return exitCodeForParseError(err)
+	}
+	if len(positional) != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: twe-dlp serve [--listen <address>] [--grpc-listen <address>] [--jobs-db <file>]")
+		return 2
+	}
+	options.RecordChanges = true
+	httpClient := createHTTPClient(options)
+
+	server := newDownloadServer(httpClient, options)
+	if serve.JobsPath != "" {
+		store, err := openJobStore(serve.JobsPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer store.Close()
+		server.store = store
+		if err := server.restore(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: loading jobs from %s: %v\n", serve.JobsPath, err)
+			return 1
+		}
+	}
+	if serve.GRPCListen != "" {
+		fmt.Printf("Serving gRPC on %s\n", serve.GRPCListen)
+		go func() {
+			if err := server.serveGRPC(serve.GRPCListen); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: gRPC: %v\n", err)
+				os.Exit(1)
+			}
+		}()
+	}
+	fmt.Printf("Listening on %s\n", serve.Listen)
+	if err := http.ListenAndServe(serve.Listen, server.routes()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+const interruptedExitCode = 130
+
+var errDownloadInterrupted = errors.New("download interrupted")
+
+func stopRequested(options downloadOptions) bool {
+	select {
+	case <-options.Stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func stopOnSignal(options *downloadOptions) func() {
+	stop := make(chan struct{})
+	options.Stop = stop
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		if _, received := <-signals; !received {
+			return
+		}
+		message := "Interrupted: finishing the current file and saving the manifest (press Ctrl+C again to quit now)"
+		fmt.Fprintln(os.Stderr, message)
+		options.Log.write(message)
+		close(stop)
+
+		if _, received := <-signals; received {
+			options.Log.write("Interrupted again, quitting immediately")
+			os.Exit(interruptedExitCode)
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(signals)
+	}
+}
+
+func printResumeHint(options downloadOptions) {
+	message := "Download interrupted. Completed files are recorded in manifest.json; run the same command again to resume."
+	fmt.Fprintln(os.Stderr, message)
+	options.Log.write(message)
+}
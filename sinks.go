@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	sinkRequestTimeout  = 30 * time.Second
+	ircDefaultNick      = "twe-dlp"
+	ircDefaultPort      = "6667"
+	ircDefaultTLSPort   = "6697"
+	ircMaxMessageLength = 350
+)
+
+type notificationSink interface {
+	Notify(result *syncResult) error
+	String() string
+}
+
+type webhookSink struct {
+	url    string
+	format string
+}
+
+type matrixSink struct {
+	homeserver string
+	room       string
+	token      string
+}
+
+type ircSink struct {
+	address  string
+	useTLS   bool
+	nick     string
+	password string
+	channel  string
+	key      string
+}
+
+func parseNotificationSink(value string) (notificationSink, error) {
+	sinkURL, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid sink %q: %w", value, err)
+	}
+	switch sinkURL.Scheme {
+	case "http", "https":
+		format := webhookFormatJSON
+		if sinkURL.Host == "discord.com" && strings.HasPrefix(sinkURL.Path, "/api/webhooks/") {
+			format = webhookFormatDiscord
+		}
+		return webhookSink{url: sinkURL.String(), format: format}, nil
+	case "matrix", "matrix+http":
+		return parseMatrixSink(sinkURL)
+	case "irc", "ircs":
+		return parseIRCSink(sinkURL)
+	}
+	return nil, fmt.Errorf("unknown sink %q (use an http(s) webhook URL, matrix://, irc:// or ircs://)", value)
+}
+
+func parseMatrixSink(sinkURL *url.URL) (notificationSink, error) {
+	sink := matrixSink{homeserver: "https://" + sinkURL.Host, room: strings.TrimPrefix(sinkURL.Path, "/")}
+	if sinkURL.Scheme == "matrix+http" {
+		sink.homeserver = "http://" + sinkURL.Host
+	}
+	if sinkURL.Fragment != "" {
+		sink.room = "#" + sinkURL.Fragment
+	}
+	if sinkURL.User != nil {
+		sink.token = sinkURL.User.Username()
+	}
+	if sink.token == "" {
+		sink.token = os.Getenv("MATRIX_ACCESS_TOKEN")
+	}
+	switch {
+	case sinkURL.Host == "":
+		return nil, errors.New("matrix sink needs a homeserver, e.g. matrix://matrix.org/!room:matrix.org")
+	case !strings.HasPrefix(sink.room, "!") && !strings.HasPrefix(sink.room, "#"):
+		return nil, fmt.Errorf("matrix sink needs a room ID (!room:server) or alias (#room:server), got %q", sink.room)
+	case sink.token == "":
+		return nil, errors.New("matrix sink needs an access token in the URL (matrix://TOKEN@host/...) or MATRIX_ACCESS_TOKEN")
+	}
+	return sink, nil
+}
+
+func parseIRCSink(sinkURL *url.URL) (notificationSink, error) {
+	sink := ircSink{useTLS: sinkURL.Scheme == "ircs", nick: ircDefaultNick, key: sinkURL.Query().Get("key")}
+	port := sinkURL.Port()
+	if port == "" {
+		port = ircDefaultPort
+		if sink.useTLS {
+			port = ircDefaultTLSPort
+		}
+	}
+	sink.address = net.JoinHostPort(sinkURL.Hostname(), port)
+	if sinkURL.User != nil {
+		if nick := sinkURL.User.Username(); nick != "" {
+			sink.nick = nick
+		}
+		sink.password, _ = sinkURL.User.Password()
+	}
+	channel := strings.TrimPrefix(sinkURL.Path, "/")
+	if sinkURL.Fragment != "" {
+		var query string
+		channel, query, _ = strings.Cut(sinkURL.Fragment, "?")
+		if values, err := url.ParseQuery(query); err == nil && values.Get("key") != "" {
+			sink.key = values.Get("key")
+		}
+	}
+	if channel != "" && !strings.ContainsAny(channel[:1], "#&") {
+		channel = "#" + channel
+	}
+	sink.channel = channel
+	if sinkURL.Hostname() == "" || sink.channel == "" {
+		return nil, errors.New("irc sink needs a server and a channel, e.g. ircs://irc.libera.chat/twe-dlp")
+	}
+	return sink, nil
+}
+
+func notificationSummary(payload webhookPayload) string {
+	channelName := payload.ChannelName
+	if channelName == "" {
+		channelName = payload.ChannelID
+	}
+	return fmt.Sprintf("%d new emotes in %s (%s)", len(payload.NewEmotes), channelName, payload.Provider)
+}
+
+func (sink webhookSink) Notify(result *syncResult) error {
+	return postWebhook(sink.url, sink.format, result)
+}
+
+func (sink webhookSink) String() string {
+	return "webhook"
+}
+
+func (sink matrixSink) request(method string, path string, body any, result any) error {
+	var requestBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&requestBody).Encode(body); err != nil {
+			return err
+		}
+	}
+	request, err := http.NewRequest(method, sink.homeserver+path, &requestBody)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+sink.token)
+	request.Header.Set("Content-Type", "application/json")
+	response, err := (&http.Client{Timeout: sinkRequestTimeout}).Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		var matrixErr struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(response.Body).Decode(&matrixErr)
+		return fmt.Errorf("homeserver returned status %s %s", response.Status, matrixErr.Error)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+func (sink matrixSink) Notify(result *syncResult) error {
+	roomID := sink.room
+	if strings.HasPrefix(roomID, "#") {
+		var alias struct {
+			RoomID string `json:"room_id"`
+		}
+		if err := sink.request(http.MethodGet, "/_matrix/client/v3/directory/room/"+url.PathEscape(roomID), nil, &alias); err != nil {
+			return fmt.Errorf("resolving %s: %w", roomID, err)
+		}
+		roomID = alias.RoomID
+	}
+
+	payload := buildWebhookPayload(result)
+	summary := notificationSummary(payload)
+	plain := []string{summary + ":"}
+	formatted := []string{html.EscapeString(summary) + ":<ul>"}
+	for _, emote := range payload.NewEmotes {
+		plain = append(plain, fmt.Sprintf("%s %s", emote.Code, emote.ImageURL))
+		formatted = append(formatted, fmt.Sprintf(`<li><a href="%s">%s</a></li>`, html.EscapeString(emote.ImageURL), html.EscapeString(emote.Code)))
+	}
+	message := map[string]string{
+		"msgtype":        "m.notice",
+		"body":           strings.Join(plain, "\n"),
+		"format":         "org.matrix.custom.html",
+		"formatted_body": strings.Join(formatted, "") + "</ul>",
+	}
+	transactionID := fmt.Sprintf("twe-dlp-%d", time.Now().UnixNano())
+	return sink.request(http.MethodPut, fmt.Sprintf("/_matrix/client/v3/rooms/%s/send/m.room.message/%s", url.PathEscape(roomID), transactionID), message, nil)
+}
+
+func (sink matrixSink) String() string {
+	return "matrix " + sink.room
+}
+
+func ircMessages(payload webhookPayload) []string {
+	messages := []string{notificationSummary(payload) + ":"}
+	line := ""
+	for _, emote := range payload.NewEmotes {
+		if line != "" && len(line)+len(emote.Code)+2 > ircMaxMessageLength {
+			messages = append(messages, line)
+			line = ""
+		}
+		if line != "" {
+			line += ", "
+		}
+		line += strings.NewReplacer("\r", "", "\n", "").Replace(emote.Code)
+	}
+	if line != "" {
+		messages = append(messages, line)
+	}
+	return messages
+}
+
+func (sink ircSink) Notify(result *syncResult) error {
+	dialer := &net.Dialer{Timeout: sinkRequestTimeout}
+	var connection net.Conn
+	var err error
+	if sink.useTLS {
+		host, _, _ := net.SplitHostPort(sink.address)
+		connection, err = tls.DialWithDialer(dialer, "tcp", sink.address, &tls.Config{ServerName: host})
+	} else {
+		connection, err = dialer.Dial("tcp", sink.address)
+	}
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+	connection.SetDeadline(time.Now().Add(sinkRequestTimeout))
+
+	send := func(format string, arguments ...any) error {
+		_, err := fmt.Fprintf(connection, format+"\r\n", arguments...)
+		return err
+	}
+	if sink.password != "" {
+		send("PASS %s", sink.password)
+	}
+	nick := sink.nick
+	send("NICK %s", nick)
+	send("USER %s 0 * :twe-dlp", sink.nick)
+
+	reader := bufio.NewReader(connection)
+	for registered := false; !registered; {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("registering with %s: %w", sink.address, err)
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case fields[0] == "PING":
+			send("PONG %s", strings.Join(fields[1:], " "))
+		case fields[0] == "ERROR":
+			return fmt.Errorf("%s: %s", sink.address, strings.TrimSpace(line))
+		case len(fields) > 1 && fields[1] == "001":
+			registered = true
+		case len(fields) > 1 && fields[1] == "433":
+			nick += "_"
+			send("NICK %s", nick)
+		}
+	}
+
+	if sink.key != "" {
+		send("JOIN %s %s", sink.channel, sink.key)
+	} else {
+		send("JOIN %s", sink.channel)
+	}
+	for _, message := range ircMessages(buildWebhookPayload(result)) {
+		if err := send("PRIVMSG %s :%s", sink.channel, message); err != nil {
+			return err
+		}
+	}
+	return send("QUIT :done")
+}
+
+func (sink ircSink) String() string {
+	return "irc " + sink.channel
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const statsFileName = "stats.json"
+
+var statsLock sync.Mutex
+
+type channelStats struct {
+	Provider      string        `json:"provider"`
+	ChannelID     string        `json:"channel_id"`
+	ChannelName   string        `json:"channel_name,omitempty"`
+	Folder        string        `json:"folder,omitempty"`
+	Runs          int           `json:"runs"`
+	Emotes        int           `json:"emotes"`
+	Files         int           `json:"files"`
+	Bytes         int64         `json:"bytes"`
+	Duration      time.Duration `json:"duration"`
+	LastRun       time.Time     `json:"last_run"`
+	LastBytes     int64         `json:"last_bytes"`
+	LastDuration  time.Duration `json:"last_duration"`
+	LastFiles     int           `json:"last_files"`
+	FailedImages  int           `json:"failed_images,omitempty"`
+	LastRunFailed bool          `json:"last_run_failed,omitempty"`
+}
+
+type downloadStats struct {
+	Channels map[string]*channelStats `json:"channels"`
+}
+
+type statsTotals struct {
+	Channels int
+	Runs     int
+	Emotes   int
+	Files    int
+	Bytes    int64
+	Duration time.Duration
+}
+
+func statsKey(providerName string, channelID string) string {
+	return providerName + "/" + channelID
+}
+
+func loadDownloadStats() *downloadStats {
+	stats := &downloadStats{Channels: make(map[string]*channelStats)}
+	directory, err := configDir()
+	if err != nil {
+		return stats
+	}
+	statsBytes, err := os.ReadFile(filepath.Join(directory, statsFileName))
+	if err != nil {
+		return stats
+	}
+	if err := json.Unmarshal(statsBytes, stats); err != nil || stats.Channels == nil {
+		stats.Channels = make(map[string]*channelStats)
+	}
+	return stats
+}
+
+func saveDownloadStats(stats *downloadStats) error {
+	directory, err := configDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return err
+	}
+	statsBytes, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(directory, statsFileName), append(statsBytes, '\n'), false)
+}
+
+func recordRunStats(report *runReport, outputRoot string) error {
+	statsLock.Lock()
+	defer statsLock.Unlock()
+
+	stats := loadDownloadStats()
+	key := statsKey(report.Provider, report.ChannelID)
+	entry := stats.Channels[key]
+	if entry == nil {
+		entry = &channelStats{Provider: report.Provider, ChannelID: report.ChannelID}
+		stats.Channels[key] = entry
+	}
+	if report.ChannelName != "" {
+		entry.ChannelName = report.ChannelName
+	}
+	entry.Folder = outputRoot
+
+	var runBytes int64
+	runFiles, failedImages := 0, 0
+	for _, emote := range report.Emotes {
+		for _, image := range emote.Images {
+			switch image.Status {
+			case imageStatusDownloaded:
+				runFiles++
+				runBytes += image.Bytes
+			case imageStatusFailed:
+				failedImages++
+			}
+		}
+	}
+	duration := report.FinishedAt.Sub(report.StartedAt)
+
+	entry.Runs++
+	entry.Emotes = len(report.Emotes)
+	entry.Files += runFiles
+	entry.Bytes += runBytes
+	entry.Duration += duration
+	entry.LastRun = report.FinishedAt
+	entry.LastFiles = runFiles
+	entry.LastBytes = runBytes
+	entry.LastDuration = duration
+	entry.FailedImages = failedImages
+	entry.LastRunFailed = report.Error != ""
+	return saveDownloadStats(stats)
+}
+
+func (stats *downloadStats) totals() statsTotals {
+	var totals statsTotals
+	for _, entry := range stats.Channels {
+		totals.Channels++
+		totals.Runs += entry.Runs
+		totals.Emotes += entry.Emotes
+		totals.Files += entry.Files
+		totals.Bytes += entry.Bytes
+		totals.Duration += entry.Duration
+	}
+	return totals
+}
+
+func formatStatsFooter(stats *downloadStats, providerName string, channelID string) string {
+	totals := stats.totals()
+	footer := fmt.Sprintf("All time: %d channels, %d emotes, %s", totals.Channels, totals.Emotes, formatByteSize(totals.Bytes))
+	if entry := stats.Channels[statsKey(providerName, channelID)]; entry != nil {
+		name := entry.ChannelName
+		if name == "" {
+			name = entry.ChannelID
+		}
+		footer = fmt.Sprintf("Last run: %s, %d emotes, %d new files, %s in %s • %s", name, entry.Emotes, entry.LastFiles, formatByteSize(entry.LastBytes), entry.LastDuration.Round(time.Second), footer)
+	}
+	return footer
+}
+
+func newStatsFlagSet(jsonOutput *bool) *flag.FlagSet {
+	flagSet := flag.NewFlagSet("stats", flag.ContinueOnError)
+	flagSet.BoolVar(jsonOutput, "json", false, "print the statistics as JSON")
+	return flagSet
+}
+
+func runStatsCommand(arguments []string) int {
+	var jsonOutput bool
+	flagSet := newStatsFlagSet(&jsonOutput)
+	if err := flagSet.Parse(arguments); err != nil {
+		return exitCodeForParseError(err)
+	}
+
+	stats := loadDownloadStats()
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(stats); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if len(stats.Channels) == 0 {
+		fmt.Println("No downloads recorded yet.")
+		return 0
+	}
+
+	entries := make([]*channelStats, 0, len(stats.Channels))
+	for _, entry := range stats.Channels {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(left, right int) bool {
+		if entries[left].Bytes != entries[right].Bytes {
+			return entries[left].Bytes > entries[right].Bytes
+		}
+		return statsKey(entries[left].Provider, entries[left].ChannelID) < statsKey(entries[right].Provider, entries[right].ChannelID)
+	})
+
+	fmt.Printf("%-24s %-8s %6s %7s %10s %10s  %s\n", "CHANNEL", "PROVIDER", "RUNS", "EMOTES", "BYTES", "TIME", "LAST RUN")
+	for _, entry := range entries {
+		name := entry.ChannelName
+		if name == "" {
+			name = entry.ChannelID
+		}
+		lastRun := entry.LastRun.Local().Format(time.DateTime)
+		if entry.LastRunFailed {
+			lastRun += " (failed)"
+		}
+		fmt.Printf("%-24s %-8s %6d %7d %10s %10s  %s\n", name, entry.Provider, entry.Runs, entry.Emotes, formatByteSize(entry.Bytes), entry.Duration.Round(time.Second), lastRun)
+	}
+	totals := stats.totals()
+	fmt.Printf("\n%d channels archived, %d emotes, %d files, %s downloaded in %d runs taking %s\n", totals.Channels, totals.Emotes, totals.Files, formatByteSize(totals.Bytes), totals.Runs, totals.Duration.Round(time.Second))
+	return 0
+}
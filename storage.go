@@ -0,0 +1,525 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type storage interface {
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte) error
+	Exists(name string) bool
+	String() string
+}
+
+type localStorage struct {
+	Durable bool
+}
+
+func (localStorage) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (store localStorage) WriteFile(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(name, data, store.Durable)
+}
+
+func (localStorage) Exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+func (localStorage) String() string {
+	return "local disk"
+}
+
+func outputStorage(options downloadOptions) storage {
+	if options.Storage != nil {
+		return options.Storage
+	}
+	return localStorage{Durable: options.Durable}
+}
+
+func isLocalStorage(store storage) bool {
+	_, local := store.(localStorage)
+	return local
+}
+
+func openStorage(destination string, durable bool) (storage, error) {
+	if destination == "" {
+		return localStorage{Durable: durable}, nil
+	}
+	destinationURL, err := url.Parse(destination)
+	if err != nil || destinationURL.Scheme == "" {
+		return nil, fmt.Errorf("invalid destination %q (use s3://bucket/prefix, webdav://host/path or sftp://user@host/path)", destination)
+	}
+
+	storageClient := &http.Client{Timeout: httpRequestTimeout}
+	switch destinationURL.Scheme {
+	case "s3":
+		return newS3Storage(storageClient, destinationURL)
+	case "webdav", "webdav+http":
+		return newWebDAVStorage(storageClient, destinationURL)
+	case "sftp":
+		return newSFTPStorage(destinationURL)
+	default:
+		return nil, fmt.Errorf("unknown destination scheme %q (available: s3, webdav, webdav+http, sftp)", destinationURL.Scheme)
+	}
+}
+
+func storageKey(prefix string, name string) string {
+	return strings.TrimPrefix(path.Join(prefix, filepath.ToSlash(name)), "/")
+}
+
+func storageResponseError(response *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+	message := strings.TrimSpace(string(body))
+	if message == "" {
+		return fmt.Errorf("status %s", response.Status)
+	}
+	return fmt.Errorf("status %s: %s", response.Status, message)
+}
+
+type webDAVStorage struct {
+	client      *http.Client
+	baseURL     *url.URL
+	username    string
+	password    string
+	lock        sync.Mutex
+	collections map[string]bool
+}
+
+func newWebDAVStorage(client *http.Client, destinationURL *url.URL) (*webDAVStorage, error) {
+	baseURL := *destinationURL
+	baseURL.Scheme = "https"
+	if destinationURL.Scheme == "webdav+http" {
+		baseURL.Scheme = "http"
+	}
+	baseURL.User = nil
+	baseURL.Path = strings.TrimSuffix(baseURL.Path, "/")
+
+	store := &webDAVStorage{
+		client:      client,
+		baseURL:     &baseURL,
+		password:    os.Getenv("TWE_DLP_WEBDAV_PASSWORD"),
+		collections: make(map[string]bool),
+	}
+	if destinationURL.User != nil {
+		store.username = destinationURL.User.Username()
+		if password, set := destinationURL.User.Password(); set {
+			store.password = password
+		}
+	}
+	return store, nil
+}
+
+func (store *webDAVStorage) String() string {
+	return store.baseURL.String()
+}
+
+func (store *webDAVStorage) request(method string, key string, body []byte) (*http.Response, error) {
+	requestURL := *store.baseURL
+	requestURL.Path = store.baseURL.Path + "/" + key
+	request, err := http.NewRequest(method, requestURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if store.username != "" {
+		request.SetBasicAuth(store.username, store.password)
+	}
+	return store.client.Do(request)
+}
+
+func (store *webDAVStorage) ReadFile(name string) ([]byte, error) {
+	response, err := store.request("GET", storageKey("", name), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, fs.ErrNotExist
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, storageResponseError(response)
+	}
+	return io.ReadAll(response.Body)
+}
+
+func (store *webDAVStorage) makeCollections(key string) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	collection := ""
+	for _, segment := range strings.Split(path.Dir(key), "/") {
+		if segment == "." || segment == "" {
+			continue
+		}
+		collection = path.Join(collection, segment)
+		if store.collections[collection] {
+			continue
+		}
+		response, err := store.request("MKCOL", collection, nil)
+		if err != nil {
+			return err
+		}
+		response.Body.Close()
+		switch response.StatusCode {
+		case http.StatusCreated, http.StatusMethodNotAllowed:
+		default:
+			return fmt.Errorf("cannot create collection %s: status %s", collection, response.Status)
+		}
+		store.collections[collection] = true
+	}
+	return nil
+}
+
+func (store *webDAVStorage) WriteFile(name string, data []byte) error {
+	key := storageKey("", name)
+	if err := store.makeCollections(key); err != nil {
+		return err
+	}
+	response, err := store.request("PUT", key, data)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusCreated && response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusOK {
+		return storageResponseError(response)
+	}
+	return nil
+}
+
+func (store *webDAVStorage) Exists(name string) bool {
+	response, err := store.request("HEAD", storageKey("", name), nil)
+	if err != nil {
+		return false
+	}
+	response.Body.Close()
+	return response.StatusCode == http.StatusOK
+}
+
+type s3Storage struct {
+	client       *http.Client
+	endpoint     *url.URL
+	bucket       string
+	prefix       string
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+func newS3Storage(client *http.Client, destinationURL *url.URL) (*s3Storage, error) {
+	if destinationURL.Host == "" {
+		return nil, errors.New("s3 destination needs a bucket (s3://bucket/prefix)")
+	}
+	store := &s3Storage{
+		client:       client,
+		bucket:       destinationURL.Host,
+		prefix:       strings.Trim(destinationURL.Path, "/"),
+		region:       os.Getenv("AWS_REGION"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if store.region == "" {
+		store.region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if store.region == "" {
+		store.region = "us-east-1"
+	}
+	if store.accessKey == "" || store.secretKey == "" {
+		return nil, errors.New("s3 destination needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_S3")
+	if endpoint == "" {
+		endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", store.region)
+	}
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+	}
+	store.endpoint = endpointURL
+	return store, nil
+}
+
+func (store *s3Storage) String() string {
+	return fmt.Sprintf("s3://%s/%s", store.bucket, store.prefix)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func s3EscapePath(key string) string {
+	var escaped strings.Builder
+	for _, character := range []byte(key) {
+		switch {
+		case 'A' <= character && character <= 'Z', 'a' <= character && character <= 'z', '0' <= character && character <= '9',
+			character == '-', character == '_', character == '.', character == '~', character == '/':
+			escaped.WriteByte(character)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", character)
+		}
+	}
+	return escaped.String()
+}
+
+func (store *s3Storage) request(method string, name string, body []byte) (*http.Response, error) {
+	objectPath := "/" + store.bucket + "/" + s3EscapePath(storageKey(store.prefix, name))
+	request, err := http.NewRequest(method, store.endpoint.Scheme+"://"+store.endpoint.Host+strings.TrimSuffix(store.endpoint.Path, "/")+objectPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	store.sign(request, body, time.Now().UTC())
+	return store.client.Do(request)
+}
+
+func (store *s3Storage) sign(request *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	shortDate := now.Format("20060102")
+	payloadHash := sha256.Sum256(body)
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if store.sessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", store.sessionToken)
+	}
+
+	headerNames := []string{"host"}
+	for name := range request.Header {
+		headerNames = append(headerNames, strings.ToLower(name))
+	}
+	sort.Strings(headerNames)
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		value := request.Header.Get(name)
+		if name == "host" {
+			value = request.URL.Host
+		}
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, strings.TrimSpace(value))
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := shortDate + "/" + store.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+store.secretKey), shortDate)
+	signingKey = hmacSHA256(signingKey, store.region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", store.accessKey, scope, signedHeaders, signature))
+}
+
+func (store *s3Storage) ReadFile(name string) ([]byte, error) {
+	response, err := store.request("GET", name, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, fs.ErrNotExist
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, storageResponseError(response)
+	}
+	return io.ReadAll(response.Body)
+}
+
+func (store *s3Storage) WriteFile(name string, data []byte) error {
+	response, err := store.request("PUT", name, data)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		return storageResponseError(response)
+	}
+	return nil
+}
+
+func (store *s3Storage) Exists(name string) bool {
+	response, err := store.request("HEAD", name, nil)
+	if err != nil {
+		return false
+	}
+	response.Body.Close()
+	return response.StatusCode == http.StatusOK
+}
+
+type sftpStorage struct {
+	target      string
+	root        string
+	options     []string
+	lock        sync.Mutex
+	directories map[string]bool
+	uploads     atomic.Int64
+}
+
+func newSFTPStorage(destinationURL *url.URL) (*sftpStorage, error) {
+	if destinationURL.Host == "" {
+		return nil, errors.New("sftp destination needs a host (sftp://user@host/path)")
+	}
+	if _, err := exec.LookPath("sftp"); err != nil {
+		return nil, errors.New("sftp destination needs the OpenSSH sftp client on PATH")
+	}
+
+	target := destinationURL.Hostname()
+	if destinationURL.User != nil {
+		target = destinationURL.User.Username() + "@" + target
+	}
+	root := strings.TrimSuffix(destinationURL.Path, "/")
+	if root == "/~" || strings.HasPrefix(root, "/~/") {
+		root = strings.TrimPrefix(strings.TrimPrefix(root, "/~"), "/")
+	}
+
+	options := []string{
+		"-q",
+		"-o", "BatchMode=yes",
+		"-o", "ControlMaster=auto",
+		"-o", "ControlPath=" + filepath.Join(os.TempDir(), "twe-dlp-%C"),
+		"-o", "ControlPersist=60",
+	}
+	if port := destinationURL.Port(); port != "" {
+		options = append(options, "-P", port)
+	}
+	return &sftpStorage{
+		target:      target,
+		root:        root,
+		options:     options,
+		directories: make(map[string]bool),
+	}, nil
+}
+
+func (store *sftpStorage) String() string {
+	return "sftp://" + store.target + "/" + strings.TrimPrefix(store.root, "/")
+}
+
+func (store *sftpStorage) remotePath(name string) string {
+	key := storageKey("", name)
+	if store.root == "" {
+		return key
+	}
+	return store.root + "/" + key
+}
+
+func sftpQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+func (store *sftpStorage) run(commands ...string) error {
+	arguments := append(append([]string{}, store.options...), "-b", "-", store.target)
+	command := exec.Command("sftp", arguments...)
+	command.Stdin = strings.NewReader(strings.Join(commands, "\n") + "\n")
+	if output, err := command.CombinedOutput(); err != nil {
+		message := strings.TrimSpace(string(output))
+		if strings.Contains(message, "not found") || strings.Contains(message, "No such file") {
+			return fs.ErrNotExist
+		}
+		return fmt.Errorf("sftp: %v: %s", err, message)
+	}
+	return nil
+}
+
+func (store *sftpStorage) ReadFile(name string) ([]byte, error) {
+	localFile, err := os.CreateTemp("", "twe-dlp-sftp-*")
+	if err != nil {
+		return nil, err
+	}
+	localFile.Close()
+	defer os.Remove(localFile.Name())
+
+	if err := store.run("get " + sftpQuote(store.remotePath(name)) + " " + sftpQuote(localFile.Name())); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(localFile.Name())
+}
+
+func (store *sftpStorage) makeDirectories(remotePath string) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	var commands []string
+	var created []string
+	directory := path.Dir(remotePath)
+	for directory != "." && directory != "/" && !store.directories[directory] {
+		commands = append([]string{"-mkdir " + sftpQuote(directory)}, commands...)
+		created = append(created, directory)
+		directory = path.Dir(directory)
+	}
+	if len(commands) == 0 {
+		return nil
+	}
+	if err := store.run(commands...); err != nil {
+		return err
+	}
+	for _, directory := range created {
+		store.directories[directory] = true
+	}
+	return nil
+}
+
+func (store *sftpStorage) WriteFile(name string, data []byte) error {
+	remotePath := store.remotePath(name)
+	if err := store.makeDirectories(remotePath); err != nil {
+		return err
+	}
+
+	localFile, err := os.CreateTemp("", "twe-dlp-sftp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(localFile.Name())
+	if _, err := localFile.Write(data); err != nil {
+		localFile.Close()
+		return err
+	}
+	if err := localFile.Close(); err != nil {
+		return err
+	}
+
+	temporaryPath := fmt.Sprintf("%s/.%s.%d-%d.part", path.Dir(remotePath), path.Base(remotePath), os.Getpid(), store.uploads.Add(1))
+	return store.run(
+		"put "+sftpQuote(localFile.Name())+" "+sftpQuote(temporaryPath),
+		"rename "+sftpQuote(temporaryPath)+" "+sftpQuote(remotePath),
+	)
+}
+
+func (store *sftpStorage) Exists(name string) bool {
+	return store.run("ls "+sftpQuote(store.remotePath(name))) == nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	configFileName   = "config.json"
+	defaultThemeName = "catppuccin"
+)
+
+type themePalette struct {
+	Accent string `json:"accent,omitempty"`
+	Base   string `json:"base,omitempty"`
+	Text   string `json:"text,omitempty"`
+	OK     string `json:"ok,omitempty"`
+	Warn   string `json:"warn,omitempty"`
+	Error  string `json:"error,omitempty"`
+	Muted  string `json:"muted,omitempty"`
+}
+
+type appConfig struct {
+	Theme   string        `json:"theme,omitempty"`
+	Palette themePalette  `json:"palette,omitempty"`
+	Watch   []watchTarget `json:"watch,omitempty"`
+}
+
+var themes = map[string]themePalette{
+	"catppuccin": {
+		Accent: "#f5c2e7", Base: "#11111b", Text: "#cdd6f4",
+		OK: "#a6e3a1", Warn: "#f9e2af", Error: "#f38ba8", Muted: "#585b70",
+	},
+	"dracula": {
+		Accent: "#bd93f9", Base: "#282a36", Text: "#f8f8f2",
+		OK: "#50fa7b", Warn: "#f1fa8c", Error: "#ff5555", Muted: "#6272a4",
+	},
+	"nord": {
+		Accent: "#88c0d0", Base: "#2e3440", Text: "#eceff4",
+		OK: "#a3be8c", Warn: "#ebcb8b", Error: "#bf616a", Muted: "#4c566a",
+	},
+	"mono": {},
+}
+
+func themeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func loadConfig() appConfig {
+	var config appConfig
+	directory, err := configDir()
+	if err != nil {
+		return config
+	}
+	configBytes, err := os.ReadFile(filepath.Join(directory, configFileName))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "Warning: cannot read config: %v\n", err)
+		}
+		return config
+	}
+	if err := json.Unmarshal(configBytes, &config); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring invalid config %s: %v\n", configFileName, err)
+		return appConfig{}
+	}
+	return config
+}
+
+func resolveTheme(name string, custom themePalette) (themePalette, error) {
+	if name == "" {
+		name = defaultThemeName
+	}
+	palette, exists := themes[strings.ToLower(name)]
+	if !exists {
+		return themePalette{}, fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(themeNames(), ", "))
+	}
+
+	for _, override := range []struct {
+		target *string
+		value  string
+	}{
+		{&palette.Accent, custom.Accent}, {&palette.Base, custom.Base}, {&palette.Text, custom.Text},
+		{&palette.OK, custom.OK}, {&palette.Warn, custom.Warn}, {&palette.Error, custom.Error},
+		{&palette.Muted, custom.Muted},
+	} {
+		if override.value != "" {
+			*override.target = override.value
+		}
+	}
+	return palette, nil
+}
+
+func foregroundStyle(color string) lipgloss.Style {
+	style := lipgloss.NewStyle()
+	if color != "" {
+		style = style.Foreground(lipgloss.Color(color))
+	}
+	return style
+}
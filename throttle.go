@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	throttleAttempts       = 5
+	throttleDefaultWait    = 5 * time.Second
+	throttleMaxWait        = 5 * time.Minute
+	throttleRetryAfterName = "Retry-After"
+)
+
+var hostPauses = struct {
+	lock  sync.Mutex
+	until map[string]time.Time
+}{until: make(map[string]time.Time)}
+
+type throttleTransport struct {
+	base    http.RoundTripper
+	logFunc func(string)
+	logLock sync.Mutex
+}
+
+func throttledClient(httpClient *http.Client, logFunc func(string)) *http.Client {
+	baseTransport := httpClient.Transport
+	if baseTransport == nil {
+		baseTransport = http.DefaultTransport
+	}
+	throttled := *httpClient
+	throttled.Transport = &throttleTransport{base: baseTransport, logFunc: logFunc}
+	return &throttled
+}
+
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(0, time.Duration(seconds)*time.Second), true
+	}
+	if retryAt, err := http.ParseTime(value); err == nil {
+		return max(0, retryAt.Sub(now)), true
+	}
+	return 0, false
+}
+
+func hostPause(host string) time.Duration {
+	hostPauses.lock.Lock()
+	defer hostPauses.lock.Unlock()
+	return time.Until(hostPauses.until[host])
+}
+
+func pauseHost(host string, wait time.Duration) {
+	hostPauses.lock.Lock()
+	defer hostPauses.lock.Unlock()
+	if until := time.Now().Add(wait); until.After(hostPauses.until[host]) {
+		hostPauses.until[host] = until
+	}
+}
+
+func throttleWait(response *http.Response, attempt int) (time.Duration, bool) {
+	switch response.StatusCode {
+	case http.StatusTooManyRequests:
+		if wait, parsed := parseRetryAfter(response.Header.Get(throttleRetryAfterName), time.Now()); parsed {
+			return wait, wait <= throttleMaxWait
+		}
+		return throttleDefaultWait << (attempt - 1), true
+	case http.StatusServiceUnavailable:
+		wait, parsed := parseRetryAfter(response.Header.Get(throttleRetryAfterName), time.Now())
+		return wait, parsed && wait <= throttleMaxWait
+	}
+	return 0, false
+}
+
+func (transport *throttleTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	host := request.URL.Host
+	for attempt := 1; ; attempt++ {
+		if wait := hostPause(host); wait > 0 {
+			if err := sleepContext(request.Context(), wait); err != nil {
+				return nil, err
+			}
+		}
+
+		attemptRequest := request
+		if attempt > 1 && request.Body != nil {
+			if request.GetBody == nil {
+				return nil, fmt.Errorf("%s is throttling requests and the request body cannot be replayed", host)
+			}
+			body, err := request.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptRequest = request.Clone(request.Context())
+			attemptRequest.Body = body
+		}
+
+		response, err := transport.base.RoundTrip(attemptRequest)
+		if err != nil || attempt == throttleAttempts {
+			return response, err
+		}
+		wait, retry := throttleWait(response, attempt)
+		if !retry {
+			return response, nil
+		}
+		response.Body.Close()
+		pauseHost(host, wait)
+		if transport.logFunc != nil {
+			transport.logLock.Lock()
+			transport.logFunc(fmt.Sprintf("[throttled] %s returned %s, waiting %s", host, response.Status, wait.Round(time.Second)))
+			transport.logLock.Unlock()
+		}
+	}
+}
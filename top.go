@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const defaultTopCount = 50
+
+type topOptions struct {
+	Count    int
+	Download bool
+	JSON     bool
+}
+
+type topChannel struct {
+	Rank int    `json:"rank"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func parseTopChannels(document *goquery.Document, count int) []topChannel {
+	var channels []topChannel
+	seen := make(map[string]bool)
+	document.Find("a[href*='/channels/']").EachWithBreak(func(_ int, selection *goquery.Selection) bool {
+		href, _ := selection.Attr("href")
+		_, channelPath, _ := strings.Cut(href, "/channels/")
+		channelID := strings.Trim(strings.SplitN(channelPath, "?", 2)[0], "/")
+		if channelID == "" || seen[channelID] || strings.Trim(channelID, "0123456789") != "" {
+			return true
+		}
+		name := strings.TrimSpace(selection.Text())
+		if name == "" {
+			name, _ = selection.Find("img").First().Attr("alt")
+			name = strings.TrimSpace(name)
+		}
+		if name == "" {
+			name = channelID
+		}
+		seen[channelID] = true
+		channels = append(channels, topChannel{Rank: len(channels) + 1, ID: channelID, Name: name})
+		return len(channels) < count
+	})
+	return channels
+}
+
+func fetchTopChannels(httpClient *http.Client, count int) ([]topChannel, error) {
+	document, response, err := fetchDocument(httpClient, twitchemotesBaseURL+"/")
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request failed with status %s", response.Status)
+	}
+	channels := parseTopChannels(document, count)
+	if len(channels) == 0 {
+		return nil, errors.New("no channels found on the twitchemotes.com front page (run \"twe-dlp doctor\" to check the scraper)")
+	}
+	return channels, nil
+}
+
+func newTopFlagSet(options *downloadOptions, top *topOptions) *flag.FlagSet {
+	flagSet := newCommandFlagSet("top", options)
+	flagSet.IntVar(&top.Count, "count", defaultTopCount, "number of channels to list")
+	flagSet.BoolVar(&top.Download, "download", false, "download the emotes of every listed channel instead of printing them")
+	flagSet.BoolVar(&top.JSON, "json", false, "print the rank, ID and name of each channel as JSON")
+	return flagSet
+}
+
+func runTopCommand(arguments []string) int {
+	var options downloadOptions
+	var top topOptions
+
+	flagSet := newTopFlagSet(&options, &top)
+	positional, err := parseCommandLine(flagSet, &options, arguments)
+	if err != nil {
+		return exitCodeForParseError(err)
+	}
+	if len(positional) > 0 {
+		fmt.Fprintln(os.Stderr, "Usage: twe-dlp top [--count N] [--json | --download]")
+		return 2
+	}
+	if top.Count <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --count must be positive")
+		return 2
+	}
+	if top.Download && top.JSON {
+		fmt.Fprintln(os.Stderr, "Error: --json cannot be combined with --download")
+		return 2
+	}
+	httpClient := createHTTPClient(options)
+
+	channels, err := fetchTopChannels(httpClient, top.Count)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	switch {
+	case top.Download:
+		channelIdentifiers := make([]string, 0, len(channels))
+		for _, channel := range channels {
+			channelIdentifiers = append(channelIdentifiers, "twitch:"+channel.ID)
+		}
+		return runBatchMode(httpClient, channelIdentifiers, options)
+	case top.JSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(channels); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	default:
+		for _, channel := range channels {
+			fmt.Println(channel.Name)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var transliterations = map[rune]string{
+	'ß': "ss", 'ẞ': "SS",
+	'æ': "ae", 'Æ': "AE",
+	'œ': "oe", 'Œ': "OE",
+	'ø': "o", 'Ø': "O",
+	'ł': "l", 'Ł': "L",
+	'đ': "d", 'Đ': "D",
+	'ð': "d", 'Ð': "D",
+	'þ': "th", 'Þ': "TH",
+	'ı': "i",
+}
+
+func transliterate(name string) string {
+	var builder strings.Builder
+	for _, character := range norm.NFKD.String(name) {
+		switch {
+		case character <= unicode.MaxASCII:
+			builder.WriteRune(character)
+		case unicode.Is(unicode.Mn, character):
+		case transliterations[character] != "":
+			builder.WriteString(transliterations[character])
+		case unicode.IsLetter(character) || unicode.IsNumber(character):
+			builder.WriteString(fmt.Sprintf("u%04X", character))
+		default:
+			builder.WriteRune('_')
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxConnsPerHost = 16
+	transportIdleConns     = 100
+	transportIdleTimeout   = 90 * time.Second
+	transportKeepAlive     = 30 * time.Second
+	transportDialTimeout   = 30 * time.Second
+	transportTLSTimeout    = 10 * time.Second
+)
+
+type deadlineTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+var sharedTransports = struct {
+	lock       sync.Mutex
+	transports map[int]*http.Transport
+}{transports: make(map[int]*http.Transport)}
+
+func sharedTransport(maxConnsPerHost int) *http.Transport {
+	sharedTransports.lock.Lock()
+	defer sharedTransports.lock.Unlock()
+	if transport, exists := sharedTransports.transports[maxConnsPerHost]; exists {
+		return transport
+	}
+
+	idleConnsPerHost := maxConnsPerHost
+	if idleConnsPerHost <= 0 {
+		idleConnsPerHost = defaultMaxConnsPerHost
+	}
+	dialer := &net.Dialer{Timeout: transportDialTimeout, KeepAlive: transportKeepAlive}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          max(transportIdleConns, idleConnsPerHost),
+		MaxIdleConnsPerHost:   idleConnsPerHost,
+		MaxConnsPerHost:       max(maxConnsPerHost, 0),
+		IdleConnTimeout:       transportIdleTimeout,
+		TLSHandshakeTimeout:   transportTLSTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+	sharedTransports.transports[maxConnsPerHost] = transport
+	return transport
+}
+
+func (body cancelOnCloseBody) Close() error {
+	err := body.ReadCloser.Close()
+	body.cancel()
+	return err
+}
+
+func (transport deadlineTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(request.Context(), transport.timeout)
+	response, err := transport.base.RoundTrip(request.WithContext(ctx))
+	if err != nil {
+		cancel()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && request.Context().Err() == nil {
+			return nil, fmt.Errorf("no response from %s within %s: %w", request.URL.Host, transport.timeout, err)
+		}
+		return nil, err
+	}
+	response.Body = cancelOnCloseBody{ReadCloser: response.Body, cancel: cancel}
+	return response, nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	tuiStateFileName = "tui-state.json"
+	tuiStateLogLines = 50
+)
+
+type tuiStateOptions struct {
+	Provider  string   `json:"provider,omitempty"`
+	Sizes     []string `json:"sizes,omitempty"`
+	Theme     string   `json:"theme,omitempty"`
+	OutputDir string   `json:"output_dir,omitempty"`
+}
+
+type tuiStateItem struct {
+	Input  string `json:"input"`
+	Status string `json:"status"`
+}
+
+type tuiState struct {
+	SavedAt time.Time       `json:"saved_at"`
+	Options tuiStateOptions `json:"options"`
+	Queue   []tuiStateItem  `json:"queue"`
+	Log     []string        `json:"log,omitempty"`
+}
+
+func tuiStatePath() (string, error) {
+	directory, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(directory, tuiStateFileName), nil
+}
+
+func (state *tuiState) unfinished() []string {
+	inputs := make([]string, 0, len(state.Queue))
+	for _, item := range state.Queue {
+		switch item.Status {
+		case queueStatusDone, queueStatusFailed, queueStatusSkipped:
+		default:
+			inputs = append(inputs, item.Input)
+		}
+	}
+	return inputs
+}
+
+func loadTUIState() *tuiState {
+	statePath, err := tuiStatePath()
+	if err != nil {
+		return nil
+	}
+	stateBytes, err := os.ReadFile(statePath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "Warning: cannot read %s: %v\n", tuiStateFileName, err)
+		}
+		return nil
+	}
+	state := &tuiState{}
+	if err := json.Unmarshal(stateBytes, state); err != nil || len(state.unfinished()) == 0 {
+		return nil
+	}
+	return state
+}
+
+func removeTUIState() {
+	if statePath, err := tuiStatePath(); err == nil {
+		os.Remove(statePath)
+	}
+}
+
+func (m model) saveState() error {
+	if m.restoreState != nil {
+		return nil
+	}
+	state := tuiState{
+		SavedAt: time.Now().UTC(),
+		Options: tuiStateOptions{
+			Provider:  m.options.Provider,
+			Sizes:     m.options.Sizes,
+			Theme:     m.options.Theme,
+			OutputDir: m.options.OutputDir,
+		},
+		Queue: make([]tuiStateItem, 0, len(m.queue)),
+		Log:   m.logLines[max(0, len(m.logLines)-tuiStateLogLines):],
+	}
+	for _, item := range m.queue {
+		state.Queue = append(state.Queue, tuiStateItem{Input: item.Input, Status: item.Status})
+	}
+	if len(state.unfinished()) == 0 {
+		removeTUIState()
+		return nil
+	}
+
+	directory, err := configDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return err
+	}
+	stateBytes, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(directory, tuiStateFileName), append(stateBytes, '\n'), false)
+}
+
+func (m *model) restoreSession() tea.Cmd {
+	state := m.restoreState
+	m.restoreState = nil
+	removeTUIState()
+
+	if state.Options.Provider != "" {
+		if _, err := lookupProvider(state.Options.Provider); err == nil {
+			m.options.Provider = state.Options.Provider
+		}
+	}
+	m.options.Sizes = state.Options.Sizes
+	if state.Options.OutputDir != "" {
+		m.options.OutputDir = state.Options.OutputDir
+	}
+	if state.Options.Theme != "" {
+		if palette, err := resolveTheme(state.Options.Theme, loadConfig().Palette); err == nil {
+			m.options.Theme = state.Options.Theme
+			m.applyPalette(palette)
+		}
+	}
+
+	m.logLines = append(append([]string{}, state.Log...), m.logLines...)
+	channels := state.unfinished()
+	for _, channelIdentifier := range channels {
+		m.queue = append(m.queue, queueItem{
+			Input:  channelIdentifier,
+			Status: queueStatusQueued,
+		})
+	}
+	m.appendLogLine(fmt.Sprintf("Restored %d channels from the session saved %s", len(channels), state.SavedAt.Local().Format(time.DateTime)))
+	return m.startQueuedDownloads()
+}
+
+func (m model) updateRestorePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y", "enter":
+		return m, m.restoreSession()
+	case "n", "N", "esc":
+		m.restoreState = nil
+		removeTUIState()
+		m.appendLogLine("Discarded the last session")
+	}
+	return m, nil
+}
+
+func (m model) renderRestorePrompt() string {
+	state := m.restoreState
+	prompt := fmt.Sprintf("Restore the session from %s with %d unfinished channels? (y/n)", state.SavedAt.Local().Format(time.DateTime), len(state.unfinished()))
+	return m.styleHelpBoxTitle.Render(prompt)
+}
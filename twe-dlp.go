@@ -3,27 +3,52 @@ package main
 import (
 	"bufio"
 	"errors"
+	"flag"
 	"fmt"
+	"image"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+	"github.com/muesli/termenv"
+	"golang.org/x/text/unicode/norm"
 )
 
 const (
 	twitchemotesBaseURL  = "https://twitchemotes.com"
 	defaultUserAgent     = "Mozilla/5.0 (X11; Linux x86_64) twe-dlp/1.0"
 	httpRequestTimeout   = 30 * time.Second
+	defaultScrapeDelay   = time.Second
 	logBufferMaxMessages = 200
+	pipelineBufferSize   = 64
+
+	queueStatusQueued    = "queued"
+	queueStatusFetching  = "fetching"
+	queueStatusReview    = "review"
+	queueStatusConfirmed = "confirmed"
+	queueStatusRunning   = "running"
+	queueStatusDone      = "done"
+	queueStatusFailed    = "failed"
+	queueStatusSkipped   = "skipped"
+
+	reviewVisibleRows  = 12
+	compactLayoutWidth = 60
+
+	downloadResumeAttempts = 3
 )
 
 var (
@@ -31,26 +56,186 @@ var (
 	channelURLPattern = regexp.MustCompile(`/channels/(\d+)`)
 	htmlTagPattern    = regexp.MustCompile(`<.*?>`)
 	safeNamePattern   = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+	unsafePathPattern = regexp.MustCompile(`[<>:"/\\|?*\x00-\x1f]+`)
+
+	windowsReservedNames = map[string]bool{
+		"CON": true, "PRN": true, "AUX": true, "NUL": true,
+		"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+		"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+		"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+		"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+	}
 )
 
 type EmoteData struct {
 	BaseURL    string
 	FormatType string
 	EmoteCode  string
+	CodeSource string
+	Uses       int64
+	CreatedAt  time.Time
+}
+
+type downloadOptions struct {
+	KeepUnicode  bool
+	Provider     string
+	YouTubeToken string
+	OutputDir    string
+	ChannelRoot  string
+	Preview      string
+	Durable      bool
+
+	Layout          string
+	ManifestFormat  string
+	ConvertAnimated string
+	Largest         string
+	ZipPerEmote     bool
+	ExtractFrames   bool
+	Trim            bool
+	Pad             image.Point
+	Gallery         bool
+	Markdown        bool
+	Checksums       string
+	ChannelImages   bool
+
+	Optimize bool
+
+	ExecAfterEmote   string
+	ExecAfterChannel string
+
+	ChannelConcurrency int
+	ChannelRateLimit   float64
+	Pipeline           bool
+
+	Check        bool
+	DryRun       bool
+	CheckSpace   bool
+	Strict       bool
+	MaxTotalSize int64
+
+	Sizes     []string
+	Only      []string
+	Exclude   []string
+	Filters   []*regexp.Regexp
+	Top       int
+	NewerThan time.Time
+
+	UserAgent       string
+	Headers         http.Header
+	ScrapeDelay     time.Duration
+	MaxConnsPerHost int
+
+	NoCache  bool
+	CacheDir string
+	CacheTTL time.Duration
+
+	RecordFixtures string
+	Offline        string
+
+	Theme   string
+	NoColor bool
+	Palette themePalette
+
+	Notify bool
+
+	LogFile    string
+	Timestamps string
+	Log        *logFile
+
+	WARCFile string
+	WARC     *warcWriter
+
+	Dest    string
+	Storage storage
+
+	ExistingMode    string
+	ConfirmExisting func(outputRoot string, summary existingSummary) (string, error)
+	RecordChanges   bool
+
+	Progress     func(downloadProgress)
+	ProgressMode string
+	ChannelDone  func(channelIdentifier string, err error)
+	Stop         <-chan struct{}
+}
+
+type stringListFlag []string
+
+type headerTransport struct {
+	base      http.RoundTripper
+	userAgent string
+	headers   http.Header
+}
+
+type nameAllocator struct {
+	owners map[string]string
 }
 
 type downloadResultMessage struct {
-	Error    error
-	LogLines []string
+	QueueIndex int
+	OutputRoot string
+	Error      error
+	LogLines   []string
+}
+
+type channelFetchedMessage struct {
+	QueueIndex int
+	Provider   emoteProvider
+	Channel    *ChannelData
+	Previews   map[string]string
+	EmoteSize  int64
+	Error      error
+	LogLines   []string
+}
+
+type progressMessage struct {
+	QueueIndex int
+	Progress   downloadProgress
+}
+
+type queueItem struct {
+	Input      string
+	Status     string
+	Error      error
+	Provider   emoteProvider
+	Channel    *ChannelData
+	Previews   map[string]string
+	EmoteOrder []string
+	Selected   map[string]bool
+	Cursor     int
+	EmoteSize  int64
+	Confirming bool
+	StartedAt  time.Time
 }
 
 type model struct {
 	textInput         textinput.Model
+	spinner           spinner.Model
+	spinning          bool
+	progressUpdates   chan progressMessage
+	progress          map[int]downloadProgress
+	history           []string
+	historyIndex      int
+	historyDraft      string
+	favorites         []string
+	favoritesCursor   int
+	favoritesFocused  bool
+	doneFocused       bool
+	lastOutputRoot    string
+	statsFooter       string
+	notifiedFinished  int
+	notifiedFailed    int
 	logLines          []string
-	downloading       bool
+	queue             []queueItem
 	downloadError     error
 	httpClient        *http.Client
+	options           downloadOptions
+	previewMode       string
+	reviewFocused     bool
+	optionsForm       *optionsForm
+	restoreState      *tuiState
 	showHelp          bool
+	width             int
+	height            int
 	styleTitle        lipgloss.Style
 	styleLogPlain     lipgloss.Style
 	styleLogOK        lipgloss.Style
@@ -61,24 +246,127 @@ type model struct {
 	styleFooter       lipgloss.Style
 }
 
-func createHTTPClient() *http.Client {
+func createHTTPClient(options downloadOptions) *http.Client {
+	userAgent := options.UserAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+	var networkTransport http.RoundTripper = sharedTransport(options.MaxConnsPerHost)
+	if options.Offline != "" {
+		networkTransport = &fixtureReplayer{directory: options.Offline}
+	}
+	networkTransport = deadlineTransport{base: networkTransport, timeout: httpRequestTimeout}
+	if options.WARC != nil {
+		networkTransport = &warcTransport{base: networkTransport, writer: options.WARC}
+	}
+	var baseTransport http.RoundTripper = metricsTransport{base: networkTransport}
+	if options.ScrapeDelay > 0 {
+		baseTransport = newScrapeDelayTransport(baseTransport, options.ScrapeDelay)
+	}
+	if !options.NoCache && options.CacheDir != "" {
+		baseTransport = &cacheTransport{
+			base:       baseTransport,
+			directory:  options.CacheDir,
+			defaultTTL: options.CacheTTL,
+		}
+	}
+	if options.RecordFixtures != "" {
+		baseTransport = &fixtureRecorder{base: baseTransport, directory: options.RecordFixtures}
+	}
+	baseTransport = &credentialTransport{base: baseTransport}
 	return &http.Client{
-		Timeout: httpRequestTimeout,
+		Transport: &headerTransport{
+			base:      baseTransport,
+			userAgent: userAgent,
+			headers:   options.Headers,
+		},
 	}
 }
 
-func makeSafeName(name string) string {
+func (transport *headerTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	request = request.Clone(request.Context())
+	request.Header.Set("User-Agent", transport.userAgent)
+	for name, values := range transport.headers {
+		request.Header[name] = values
+	}
+	return transport.base.RoundTrip(request)
+}
+
+func parseHeaderFlag(value string) (string, string, error) {
+	name, headerValue, hasSeparator := strings.Cut(value, ":")
+	name = strings.TrimSpace(name)
+	if !hasSeparator || name == "" {
+		return "", "", fmt.Errorf("invalid header %q, expected 'Name: value'", value)
+	}
+	return http.CanonicalHeaderKey(name), strings.TrimSpace(headerValue), nil
+}
+
+func (list *stringListFlag) String() string {
+	return strings.Join(*list, ",")
+}
+
+func (list *stringListFlag) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
+func makeSafeName(name string, keepUnicode bool) string {
 	name = strings.TrimSpace(name)
 	if name == "" {
 		return "unknown"
 	}
-	safe := safeNamePattern.ReplaceAllString(name, "_")
+
+	var safe string
+	if keepUnicode {
+		safe = unsafePathPattern.ReplaceAllString(norm.NFC.String(name), "_")
+	} else {
+		safe = safeNamePattern.ReplaceAllString(transliterate(name), "_")
+	}
+	safe = strings.TrimRight(safe, ". ")
 	if safe == "" {
 		return "unknown"
 	}
+
+	baseName := safe
+	if dotIndex := strings.IndexByte(baseName, '.'); dotIndex >= 0 {
+		baseName = baseName[:dotIndex]
+	}
+	if windowsReservedNames[strings.ToUpper(strings.TrimSpace(baseName))] {
+		safe = "_" + safe
+	}
 	return safe
 }
 
+func newNameAllocator() *nameAllocator {
+	return &nameAllocator{
+		owners: make(map[string]string),
+	}
+}
+
+func (allocator *nameAllocator) reserve(name string, owner string) {
+	if name != "" {
+		allocator.owners[strings.ToLower(name)] = owner
+	}
+}
+
+func (allocator *nameAllocator) used(name string) bool {
+	_, exists := allocator.owners[strings.ToLower(name)]
+	return exists
+}
+
+func (allocator *nameAllocator) allocate(name string, owner string) (string, string) {
+	conflict, collided := allocator.owners[strings.ToLower(name)]
+	candidate := name
+	if collided {
+		candidate = name + "_" + makeSafeName(owner, false)
+		for suffix := 2; allocator.used(candidate); suffix++ {
+			candidate = fmt.Sprintf("%s_%s_%d", name, makeSafeName(owner, false), suffix)
+		}
+	}
+	allocator.owners[strings.ToLower(candidate)] = owner
+	return candidate, conflict
+}
+
 func readStdinLine(prompt string) (string, error) {
 	fmt.Print(prompt)
 	reader := bufio.NewReader(os.Stdin)
@@ -105,41 +393,15 @@ func resolveChannelIdentifierToID(httpClient *http.Client, channelIdentifier str
 		return channelIdentifier, nil
 	}
 
-	formValues := url.Values{}
-	formValues.Set("query", channelIdentifier)
-	formValues.Set("source", "twe-dlp")
-
-	requestURL := twitchemotesBaseURL + "/search/channel"
-	request, err := http.NewRequest("POST", requestURL, strings.NewReader(formValues.Encode()))
-	if err != nil {
-		return "", err
-	}
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	request.Header.Set("User-Agent", defaultUserAgent)
-
-	response, err := httpClient.Do(request)
-	if err != nil {
-		return "", err
-	}
-	defer response.Body.Close()
-
-	finalURL := response.Request.URL.String()
-	match := channelURLPattern.FindStringSubmatch(finalURL)
-	if len(match) == 2 {
-		return match[1], nil
-	}
-
-	bodyBytes, err := io.ReadAll(response.Body)
-	if err != nil {
-		return "", err
-	}
-	bodyText := string(bodyBytes)
-	match = channelURLPattern.FindStringSubmatch(bodyText)
-	if len(match) == 2 {
-		return match[1], nil
+	failures := make([]string, 0, len(channelResolvers))
+	for _, resolver := range channelResolvers {
+		channelID, err := resolver.Resolve(httpClient, channelIdentifier)
+		if err == nil {
+			return channelID, nil
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", resolver.Name, err))
 	}
-
-	return "", fmt.Errorf("could not resolve channel name %q to an ID", channelIdentifier)
+	return "", fmt.Errorf("could not resolve channel name %q to an ID (%s)", channelIdentifier, strings.Join(failures, "; "))
 }
 
 func fetchDocument(httpClient *http.Client, pageURL string) (*goquery.Document, *http.Response, error) {
@@ -147,7 +409,6 @@ func fetchDocument(httpClient *http.Client, pageURL string) (*goquery.Document,
 	if err != nil {
 		return nil, nil, err
 	}
-	request.Header.Set("User-Agent", defaultUserAgent)
 
 	response, err := httpClient.Do(request)
 	if err != nil {
@@ -163,31 +424,6 @@ func fetchDocument(httpClient *http.Client, pageURL string) (*goquery.Document,
 	return document, response, nil
 }
 
-func getChannelDisplayName(document *goquery.Document) string {
-	headerSelection := document.Find("div.card-header").First()
-	if headerSelection.Length() == 0 {
-		return ""
-	}
-
-	anchorSelection := headerSelection.Find("a").First()
-	if anchorSelection.Length() > 0 {
-		text := strings.TrimSpace(anchorSelection.Text())
-		if text != "" {
-			return text
-		}
-	}
-
-	headerTagSelection := headerSelection.Find("h1, h2, h3").First()
-	if headerTagSelection.Length() > 0 {
-		text := strings.TrimSpace(headerTagSelection.Text())
-		if text != "" {
-			return text
-		}
-	}
-
-	return ""
-}
-
 func resolveRelativeURL(base string, relative string) (string, error) {
 	baseURL, err := url.Parse(base)
 	if err != nil {
@@ -201,78 +437,6 @@ func resolveRelativeURL(base string, relative string) (string, error) {
 	return resolved.String(), nil
 }
 
-func collectEmoteMetadata(document *goquery.Document) map[string]EmoteData {
-	emoteMap := make(map[string]EmoteData)
-
-	document.Find("img").Each(func(_ int, selection *goquery.Selection) {
-		imageSource, hasSrc := selection.Attr("src")
-		if !hasSrc || imageSource == "" {
-			return
-		}
-
-		if !strings.Contains(imageSource, "static-cdn.jtvnw.net/emoticons/v2/") {
-			return
-		}
-
-		fullImageSource := imageSource
-		if !strings.HasPrefix(fullImageSource, "http://") && !strings.HasPrefix(fullImageSource, "https://") {
-			resolved, err := resolveRelativeURL(twitchemotesBaseURL, fullImageSource)
-			if err != nil {
-				return
-			}
-			fullImageSource = resolved
-		}
-
-		pathParts := strings.Split(fullImageSource, "/")
-		emoticonsIndex := -1
-		for index, part := range pathParts {
-			if part == "emoticons" {
-				emoticonsIndex = index
-				break
-			}
-		}
-		if emoticonsIndex == -1 {
-			return
-		}
-		if emoticonsIndex+3 >= len(pathParts) {
-			return
-		}
-
-		emoteIdentifier := pathParts[emoticonsIndex+2]
-		formatType := pathParts[emoticonsIndex+3]
-		baseURL := strings.Join(pathParts[:emoticonsIndex+4], "/")
-
-		emoteCode, hasRegex := selection.Attr("data-regex")
-		if !hasRegex || strings.TrimSpace(emoteCode) == "" {
-			tooltipHTML, hasTooltip := selection.Attr("data-tooltip")
-			if hasTooltip && strings.TrimSpace(tooltipHTML) != "" {
-				emoteCode = htmlTagPattern.ReplaceAllString(tooltipHTML, "")
-				emoteCode = strings.TrimSpace(emoteCode)
-			}
-		}
-		if emoteCode == "" {
-			parentText := strings.TrimSpace(selection.Parent().Text())
-			if parentText != "" {
-				emoteCode = parentText
-			} else {
-				emoteCode = emoteIdentifier
-			}
-		}
-
-		if _, exists := emoteMap[emoteIdentifier]; exists {
-			return
-		}
-
-		emoteMap[emoteIdentifier] = EmoteData{
-			BaseURL:    baseURL,
-			FormatType: formatType,
-			EmoteCode:  emoteCode,
-		}
-	})
-
-	return emoteMap
-}
-
 func determineFileExtension(contentType string) string {
 	contentType = strings.ToLower(contentType)
 	if strings.Contains(contentType, "gif") {
@@ -284,40 +448,77 @@ func determineFileExtension(contentType string) string {
 	if strings.Contains(contentType, "png") {
 		return "png"
 	}
+	if strings.Contains(contentType, "webp") {
+		return "webp"
+	}
 	return "img"
 }
 
-func downloadEmoteImages(httpClient *http.Client, emoteIdentifier string, emoteData EmoteData, outputRoot string, logFunc func(string)) {
-	emoteCode := emoteData.EmoteCode
-	emoteBaseURL := emoteData.BaseURL
+func downloadEmoteImages(httpClient *http.Client, provider emoteProvider, emoteIdentifier string, emoteData EmoteData, safeEmoteCode string, outputRoot string, previousFiles map[string]manifestFile, options downloadOptions, logFunc func(string)) (manifestEmote, reportEmote) {
+	emoteRecord := manifestEmote{
+		ID:     emoteIdentifier,
+		Code:   emoteData.EmoteCode,
+		Folder: safeEmoteCode,
+		Uses:   emoteData.Uses,
+		Files:  []manifestFile{},
+	}
+	emoteReport := reportEmote{ID: emoteIdentifier, Code: emoteData.EmoteCode}
 
-	safeEmoteCode := makeSafeName(emoteCode)
+	store := outputStorage(options)
+	local := isLocalStorage(store)
+	contentAddressed := options.Layout == layoutCAS
 	emoteFolder := filepath.Join(outputRoot, safeEmoteCode)
-	err := os.MkdirAll(emoteFolder, 0o755)
-	if err != nil {
-		logFunc(fmt.Sprintf("[error] cannot create folder %s: %v", emoteFolder, err))
-		return
+	if options.Layout == layoutFlat {
+		emoteFolder = outputRoot
+	}
+	partFolder := emoteFolder
+	if contentAddressed {
+		partFolder = outputRoot
+	}
+	if local {
+		if err := os.MkdirAll(partFolder, 0o755); err != nil {
+			logFunc(fmt.Sprintf("[error] cannot create folder %s: %v", emoteFolder, err))
+			emoteReport.Error = fmt.Sprintf("cannot create folder %s: %v", emoteFolder, err)
+			return emoteRecord, emoteReport
+		}
 	}
 
-	for _, sizeValue := range emoteSizeList {
-		imageURL := fmt.Sprintf("%s/light/%s", emoteBaseURL, sizeValue)
+	for _, sizeValue := range downloadSizes(provider, options) {
+		if stopRequested(options) {
+			break
+		}
+		variants := imageVariants(provider, emoteData, sizeValue)
+		imageURL := variants[0].URL
 
-		request, err := http.NewRequest("GET", imageURL, nil)
-		if err != nil {
-			logFunc(fmt.Sprintf("[skip] %s (%v)", imageURL, err))
-			continue
+		previousFile, hasPrevious := previousFiles[imageURL]
+		if hasPrevious && !store.Exists(filepath.Join(outputRoot, previousFile.Path)) {
+			hasPrevious = false
 		}
-		request.Header.Set("User-Agent", defaultUserAgent)
 
-		response, err := httpClient.Do(request)
+		partPath := filepath.Join(partFolder, fmt.Sprintf("%s_%s.part", safeEmoteCode, sizeValue))
+		var resumeOffset int64
+		var resumeValidator string
+		if partInfo, statError := os.Stat(partPath); local && statError == nil {
+			if resumeValidator = readPartValidator(partPath, imageURL); resumeValidator != "" {
+				resumeOffset = partInfo.Size()
+			} else {
+				removePart(partPath)
+			}
+		}
+
+		response, variant, err := requestImage(httpClient, variants, previousFile, hasPrevious, resumeOffset, resumeValidator)
 		if err != nil {
-			logFunc(fmt.Sprintf("[skip] %s (%v)", imageURL, err))
+			logFunc(fmt.Sprintf("%s %s (%v)", skipTag(options), imageURL, err))
+			emoteReport.Images = append(emoteReport.Images, reportImage{Size: sizeValue, Status: imageStatusFailed, Error: err.Error()})
 			continue
 		}
 
-		if response.StatusCode != http.StatusOK {
-			logFunc(fmt.Sprintf("[skip] %s (status %s)", imageURL, response.Status))
+		if response.StatusCode == http.StatusNotModified {
 			response.Body.Close()
+			postProcessImageFile(outputRoot, &previousFile, options, logFunc)
+			emoteRecord.Files = append(emoteRecord.Files, previousFile)
+			logFunc(fmt.Sprintf("[unchanged] %s", filepath.Base(previousFile.Path)))
+			emoteReport.Images = append(emoteReport.Images, reportImage{Size: sizeValue, Status: imageStatusUnchanged, File: previousFile.Path, Bytes: previousFile.Bytes})
 			continue
 		}
 
@@ -326,147 +527,735 @@ func downloadEmoteImages(httpClient *http.Client, emoteIdentifier string, emoteD
 		outputFilename := fmt.Sprintf("%s_%s.%s", safeEmoteCode, sizeValue, fileExtension)
 		outputPath := filepath.Join(emoteFolder, outputFilename)
 
-		outputFile, err := os.Create(outputPath)
+		var onProgress func(int64, int64, float64)
+		if options.Progress != nil {
+			onProgress = func(bytesDone int64, bytesTotal int64, bytesPerSecond float64) {
+				options.Progress(downloadProgress{
+					EmoteCode:      emoteData.EmoteCode,
+					Size:           sizeValue,
+					BytesDone:      bytesDone,
+					BytesTotal:     bytesTotal,
+					BytesPerSecond: bytesPerSecond,
+				})
+			}
+		}
+
+		var bytesWritten, bytesSaved int64
+		var imageBytes []byte
+		var digest string
+		if local {
+			bytesWritten, err = writeImagePart(httpClient, response, variant, partPath, variant.URL == imageURL, onProgress)
+			if err == nil && options.Optimize && fileExtension == "png" {
+				if bytesSaved, err = optimizePNGFile(partPath); err == nil {
+					bytesWritten -= bytesSaved
+				}
+			}
+			if err == nil {
+				digest, err = hashFile(partPath)
+			}
+		} else {
+			imageBytes, err = readImage(response, onProgress)
+			if err == nil && options.Optimize && fileExtension == "png" {
+				if optimized, smaller := optimizePNG(imageBytes); smaller {
+					bytesSaved = int64(len(imageBytes) - len(optimized))
+					imageBytes = optimized
+				}
+			}
+			bytesWritten = int64(len(imageBytes))
+			digest = sha256Hex(imageBytes)
+		}
 		if err != nil {
-			logFunc(fmt.Sprintf("[skip] %s (cannot create file: %v)", outputPath, err))
-			response.Body.Close()
+			logFunc(fmt.Sprintf("%s %s (%v)", skipTag(options), outputPath, err))
+			emoteReport.Images = append(emoteReport.Images, reportImage{Size: sizeValue, Status: imageStatusFailed, Error: err.Error()})
 			continue
 		}
 
-		_, copyError := io.Copy(outputFile, response.Body)
-		outputFile.Close()
-		response.Body.Close()
+		filePath := filepath.ToSlash(filepath.Join(safeEmoteCode, outputFilename))
+		if options.Layout == layoutFlat {
+			filePath = outputFilename
+		}
+		if contentAddressed {
+			outputPath = filepath.Join(objectsRoot(options), objectFileName(digest, fileExtension))
+			relativePath, err := filepath.Rel(outputRoot, outputPath)
+			if err != nil {
+				relativePath = outputPath
+			}
+			filePath = filepath.ToSlash(relativePath)
+		}
 
-		if copyError != nil {
-			logFunc(fmt.Sprintf("[skip] %s (copy error: %v)", outputPath, copyError))
-			continue
+		switch {
+		case contentAddressed && store.Exists(outputPath):
+			if local {
+				removePart(partPath)
+			}
+		case local:
+			if contentAddressed {
+				err = os.MkdirAll(filepath.Dir(outputPath), 0o755)
+			}
+			if err == nil {
+				err = commitFile(partPath, outputPath, options.Durable)
+			}
+			if err == nil {
+				os.Remove(partValidatorPath(partPath))
+			}
+			if err != nil {
+				logFunc(fmt.Sprintf("%s %s (cannot move partial file into place: %v)", skipTag(options), outputPath, err))
+				emoteReport.Images = append(emoteReport.Images, reportImage{Size: sizeValue, Status: imageStatusFailed, Error: fmt.Sprintf("cannot move partial file into place: %v", err)})
+				continue
+			}
+		default:
+			if err := store.WriteFile(outputPath, imageBytes); err != nil {
+				logFunc(fmt.Sprintf("%s %s (cannot upload to %s: %v)", skipTag(options), outputPath, store, err))
+				emoteReport.Images = append(emoteReport.Images, reportImage{Size: sizeValue, Status: imageStatusFailed, Error: fmt.Sprintf("cannot upload to %s: %v", store, err)})
+				continue
+			}
 		}
 
-		logFunc(fmt.Sprintf("[ok] %s", outputFilename))
+		appMetrics.recordFile(provider.Name(), bytesWritten)
+		fileRecord := manifestFile{
+			Size:         sizeValue,
+			URL:          imageURL,
+			Variant:      variant.Name,
+			Path:         filePath,
+			ContentType:  contentType,
+			Bytes:        bytesWritten,
+			SHA256:       digest,
+			ETag:         response.Header.Get("ETag"),
+			LastModified: response.Header.Get("Last-Modified"),
+		}
+		if variant.URL != imageURL {
+			fileRecord.SourceURL = variant.URL
+			logFunc(fmt.Sprintf("[ok] %s (fallback %s)", outputFilename, variant.Name))
+		} else {
+			logFunc(fmt.Sprintf("[ok] %s", outputFilename))
+		}
+		postProcessImageFile(outputRoot, &fileRecord, options, logFunc)
+		emoteRecord.Files = append(emoteRecord.Files, fileRecord)
+		emoteReport.Images = append(emoteReport.Images, reportImage{Size: sizeValue, Status: imageStatusDownloaded, File: fileRecord.Path, Variant: variant.Name, Bytes: bytesWritten, Saved: bytesSaved})
 	}
-}
 
-func downloadChannelEmotes(httpClient *http.Client, channelID string, logFunc func(string)) error {
-	channelURL := fmt.Sprintf("%s/channels/%s", twitchemotesBaseURL, channelID)
+	return emoteRecord, emoteReport
+}
 
-	document, response, err := fetchDocument(httpClient, channelURL)
-	if err != nil {
-		return err
+func skipTag(options downloadOptions) string {
+	if options.Strict {
+		return "[error]"
 	}
-	defer response.Body.Close()
+	return "[skip]"
+}
 
-	if response.StatusCode != http.StatusOK {
-		return fmt.Errorf("request failed with status %s", response.Status)
+func postProcessImageFile(outputRoot string, file *manifestFile, options downloadOptions, logFunc func(string)) {
+	store := outputStorage(options)
+	convertAnimatedFile(store, outputRoot, file, options.ConvertAnimated, logFunc)
+	if options.ExtractFrames {
+		extractAnimationFrames(store, outputRoot, *file, logFunc)
 	}
+	normalizeImageFile(store, outputRoot, file, options.Trim, options.Pad, logFunc)
+}
 
-	channelDisplayName := getChannelDisplayName(document)
-	safeChannelName := makeSafeName(channelDisplayName)
-	if safeChannelName == "unknown" {
-		safeChannelName = makeSafeName(channelID)
+func requestImage(httpClient *http.Client, variants []imageVariant, previousFile manifestFile, hasPrevious bool, resumeOffset int64, resumeValidator string) (*http.Response, imageVariant, error) {
+	previousSourceURL := previousFile.SourceURL
+	if previousSourceURL == "" {
+		previousSourceURL = previousFile.URL
 	}
-	outputRoot := safeChannelName
 
-	err = os.MkdirAll(outputRoot, 0o755)
-	if err != nil {
-		return fmt.Errorf("cannot create output directory %s: %w", outputRoot, err)
+	lastStatus := ""
+	for index := 0; index < len(variants); index++ {
+		variant := variants[index]
+		request, err := http.NewRequest("GET", variant.URL, nil)
+		if err != nil {
+			return nil, variant, err
+		}
+		conditional := hasPrevious && variant.URL == previousSourceURL
+		if conditional {
+			if previousFile.ETag != "" {
+				request.Header.Set("If-None-Match", previousFile.ETag)
+			}
+			if previousFile.LastModified != "" {
+				request.Header.Set("If-Modified-Since", previousFile.LastModified)
+			}
+		}
+		ranged := index == 0 && resumeOffset > 0 && resumeValidator != "" && !conditional
+		if ranged {
+			request.Header.Set("Range", fmt.Sprintf("bytes=%d-", resumeOffset))
+			request.Header.Set("If-Range", resumeValidator)
+		}
+
+		response, err := httpClient.Do(request)
+		if err != nil {
+			return nil, variant, err
+		}
+		if response.StatusCode == http.StatusPartialContent && ranged {
+			if validator := responseValidator(response); validator != "" && validator != resumeValidator {
+				response.Body.Close()
+				resumeOffset = 0
+				index--
+				continue
+			}
+		}
+		switch {
+		case response.StatusCode == http.StatusOK,
+			response.StatusCode == http.StatusPartialContent && ranged,
+			response.StatusCode == http.StatusNotModified && conditional:
+			return response, variant, nil
+		case response.StatusCode == http.StatusRequestedRangeNotSatisfiable && ranged:
+			response.Body.Close()
+			resumeOffset = 0
+			index--
+			continue
+		}
+		lastStatus = response.Status
+		response.Body.Close()
 	}
 
-	logFunc(fmt.Sprintf("Channel ID: %s", channelID))
-	if channelDisplayName != "" {
-		logFunc(fmt.Sprintf("Channel Name: %s", channelDisplayName))
+	if len(variants) > 1 {
+		return nil, imageVariant{}, fmt.Errorf("status %s, tried %d variants", lastStatus, len(variants))
 	}
-	logFunc(fmt.Sprintf("Output Folder: %s", outputRoot))
-	logFunc("Collecting emote metadata...")
+	return nil, imageVariant{}, fmt.Errorf("status %s", lastStatus)
+}
 
-	emoteMap := collectEmoteMetadata(document)
-	logFunc(fmt.Sprintf("Found %d emotes", len(emoteMap)))
+func readImage(response *http.Response, onProgress func(int64, int64, float64)) ([]byte, error) {
+	defer response.Body.Close()
+	return io.ReadAll(newProgressReader(response.Body, 0, response.ContentLength, onProgress))
+}
 
-	if len(emoteMap) == 0 {
-		return nil
+func writeImagePart(httpClient *http.Client, response *http.Response, variant imageVariant, partPath string, keepPartial bool, onProgress func(int64, int64, float64)) (int64, error) {
+	validator := ""
+	if keepPartial {
+		validator = responseValidator(response)
+		writePartValidator(partPath, variant.URL, validator)
 	}
+	for attempt := 1; ; attempt++ {
+		totalBytes, err := appendResponseToPart(response, partPath, onProgress)
+		response.Body.Close()
+		if err == nil {
+			return totalBytes, nil
+		}
+		if !keepPartial || validator == "" {
+			removePart(partPath)
+			return 0, err
+		}
 
-	for emoteIdentifier, emoteData := range emoteMap {
-		logFunc(fmt.Sprintf("Downloading sizes for emote: %s (%s)", emoteData.EmoteCode, emoteIdentifier))
-		downloadEmoteImages(httpClient, emoteIdentifier, emoteData, outputRoot, logFunc)
+		partInfo, statError := os.Stat(partPath)
+		if statError != nil || attempt >= downloadResumeAttempts {
+			return 0, fmt.Errorf("%w, partial file kept for resume", err)
+		}
+		response, _, err = requestImage(httpClient, []imageVariant{variant}, manifestFile{}, false, partInfo.Size(), validator)
+		if err != nil {
+			return 0, fmt.Errorf("%w, partial file kept for resume", err)
+		}
 	}
+}
 
-	return nil
+func responseValidator(response *http.Response) string {
+	if etag := response.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return response.Header.Get("Last-Modified")
 }
 
-func newModel(httpClient *http.Client) model {
-	input := textinput.New()
-	input.Placeholder = ""
-	input.Focus()
-	input.Prompt = "> "
-	input.CharLimit = 128
+func partValidatorPath(partPath string) string {
+	return strings.TrimSuffix(partPath, ".part") + ".validator.part"
+}
 
-	title := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#11111b")).
-		Background(lipgloss.Color("#f5c2e7")).
-		Bold(true).
-		Padding(0, 1)
+func readPartValidator(partPath string, imageURL string) string {
+	contents, err := os.ReadFile(partValidatorPath(partPath))
+	if err != nil {
+		return ""
+	}
+	sourceURL, validator, _ := strings.Cut(strings.TrimSpace(string(contents)), "\n")
+	if sourceURL != imageURL {
+		return ""
+	}
+	return validator
+}
 
-	logPlain := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#cdd6f4"))
+func writePartValidator(partPath string, imageURL string, validator string) {
+	if validator == "" {
+		os.Remove(partValidatorPath(partPath))
+		return
+	}
+	os.WriteFile(partValidatorPath(partPath), []byte(imageURL+"\n"+validator+"\n"), 0o644)
+}
 
-	logOK := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#a6e3a1"))
+func removePart(partPath string) {
+	os.Remove(partPath)
+	os.Remove(partValidatorPath(partPath))
+}
 
-	logSkip := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#f9e2af"))
+func appendResponseToPart(response *http.Response, partPath string, onProgress func(int64, int64, float64)) (int64, error) {
+	var startOffset int64
+	openFlags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if response.StatusCode == http.StatusPartialContent {
+		if _, err := fmt.Sscanf(response.Header.Get("Content-Range"), "bytes %d-", &startOffset); err != nil {
+			return 0, fmt.Errorf("invalid Content-Range %q", response.Header.Get("Content-Range"))
+		}
+		if err := os.Truncate(partPath, startOffset); err != nil {
+			return 0, err
+		}
+		openFlags = os.O_WRONLY | os.O_APPEND
+	}
 
-	logError := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#f38ba8"))
+	partFile, err := os.OpenFile(partPath, openFlags, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	bytesWritten, copyError := io.Copy(partFile, newProgressReader(response.Body, startOffset, response.ContentLength, onProgress))
+	closeError := partFile.Close()
+	if copyError != nil {
+		return 0, copyError
+	}
+	if closeError != nil {
+		return 0, closeError
+	}
+	return startOffset + bytesWritten, nil
+}
 
-	helpTitle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#f5c2e7")).
-		Bold(true)
+type discoveredEmote struct {
+	ID   string
+	Data EmoteData
+}
 
-	helpBody := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#cdd6f4"))
+type emoteStream struct {
+	Emotes <-chan discoveredEmote
+	Done   <-chan error
+}
 
-	footer := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#585b70")).
-		PaddingTop(1)
+func downloadChannelEmotes(httpClient *http.Client, provider emoteProvider, channelID string, options downloadOptions, logFunc func(string)) (string, error) {
+	if streamer, streams := provider.(emoteStreamer); streams && options.Pipeline && !options.Check && !options.DryRun {
+		return downloadChannelPipelined(httpClient, provider, streamer, channelID, options, logFunc)
+	}
 
-	return model{
-		textInput:         input,
-		logLines:          []string{},
-		httpClient:        httpClient,
-		showHelp:          false,
-		styleTitle:        title,
-		styleLogPlain:     logPlain,
-		styleLogOK:        logOK,
-		styleLogSkip:      logSkip,
-		styleLogError:     logError,
-		styleHelpBoxTitle: helpTitle,
-		styleHelpBoxBody:  helpBody,
-		styleFooter:       footer,
+	channel, err := provider.FetchChannel(httpClient, channelID)
+	if err != nil {
+		return "", err
+	}
+	if options.Check {
+		return "", checkChannelImages(httpClient, provider, channel, options, logFunc)
+	}
+	if options.DryRun {
+		return "", planChannelDownload(provider, channel, options, logFunc)
 	}
+	outputRoot := channelOutputRoot(provider, channel, options)
+	return outputRoot, downloadChannelData(httpClient, provider, channel, options, logFunc)
 }
 
-func (m model) Init() tea.Cmd {
-	return textinput.Blink
+func downloadChannelPipelined(httpClient *http.Client, provider emoteProvider, streamer emoteStreamer, channelID string, options downloadOptions, logFunc func(string)) (string, error) {
+	channelFound := make(chan *ChannelData, 1)
+	emotes := make(chan discoveredEmote, pipelineBufferSize)
+	done := make(chan error, 1)
+	go func() {
+		err := streamer.StreamChannel(httpClient, channelID, func(channel *ChannelData) {
+			channelFound <- channel
+		}, func(emoteIdentifier string, emoteData EmoteData) {
+			emotes <- discoveredEmote{ID: emoteIdentifier, Data: emoteData}
+		})
+		close(emotes)
+		done <- err
+	}()
+	defer func() {
+		for range emotes {
+		}
+	}()
+
+	var channel *ChannelData
+	select {
+	case channel = <-channelFound:
+	case err := <-done:
+		if err == nil {
+			err = errors.New("provider finished without channel details")
+		}
+		return "", err
+	}
+
+	outputRoot := channelOutputRoot(provider, channel, options)
+	return outputRoot, downloadChannelSource(httpClient, provider, channel, &emoteStream{Emotes: emotes, Done: done}, options, logFunc)
 }
 
-func (m model) hasExactLogLine(line string) bool {
-	for _, existing := range m.logLines {
-		if existing == line {
-			return true
-		}
+func channelOutputRoot(provider emoteProvider, channel *ChannelData, options downloadOptions) string {
+	if options.ChannelRoot != "" {
+		return options.ChannelRoot
 	}
-	return false
+	safeChannelName := makeSafeName(channel.DisplayName, options.KeepUnicode)
+	if safeChannelName == "unknown" {
+		safeChannelName = makeSafeName(channel.ID, options.KeepUnicode)
+	}
+	if provider.Name() != defaultProviderName {
+		safeChannelName = fmt.Sprintf("%s_%s", safeChannelName, provider.Name())
+	}
+	return filepath.Join(options.OutputDir, safeChannelName)
 }
 
-func (m model) Update(message tea.Msg) (tea.Model, tea.Cmd) {
-	switch msg := message.(type) {
-	case tea.KeyMsg:
-		switch msg.Type {
-		case tea.KeyCtrlC, tea.KeyEsc:
-			return m, tea.Quit
+func downloadChannelData(httpClient *http.Client, provider emoteProvider, channel *ChannelData, options downloadOptions, logFunc func(string)) error {
+	return downloadChannelSource(httpClient, provider, channel, nil, options, logFunc)
+}
+
+func downloadChannelSource(httpClient *http.Client, provider emoteProvider, channel *ChannelData, stream *emoteStream, options downloadOptions, logFunc func(string)) (err error) {
+	channelID := channel.ID
+	channelDisplayName := channel.DisplayName
+	outputRoot := channelOutputRoot(provider, channel, options)
+	store := outputStorage(options)
+
+	overwrite, err := prepareExistingArchive(store, provider, channel, outputRoot, stream == nil, options, logFunc)
+	if err != nil {
+		return err
+	}
+
+	if isLocalStorage(store) {
+		if err := os.MkdirAll(outputRoot, 0o755); err != nil {
+			return fmt.Errorf("cannot create output directory %s: %w", outputRoot, err)
+		}
+	}
+
+	report := newRunReport(provider, channel, options)
+	defer func() {
+		report.finish(err)
+		if reportErr := saveRunReport(store, outputRoot, report); reportErr != nil {
+			logFunc(fmt.Sprintf("[error] cannot write run report: %v", reportErr))
+		}
+		if statsErr := recordRunStats(report, outputRoot); statsErr != nil {
+			logFunc(fmt.Sprintf("[error] cannot update %s: %v", statsFileName, statsErr))
+		}
+	}()
+
+	previousManifest, err := loadManifest(store, outputRoot)
+	if err != nil {
+		logFunc(fmt.Sprintf("[error] ignoring previous manifest: %v", err))
+		previousManifest = &channelManifest{}
+	}
+	if overwrite {
+		previousManifest = &channelManifest{}
+	}
+	previousFiles := previousManifest.filesByURL()
+
+	logFunc(fmt.Sprintf("Channel ID: %s", channelID))
+	if channelDisplayName != "" {
+		logFunc(fmt.Sprintf("Channel Name: %s", channelDisplayName))
+	}
+	if isLocalStorage(store) {
+		logFunc(fmt.Sprintf("Output Folder: %s", outputRoot))
+	} else {
+		logFunc(fmt.Sprintf("Output Folder: %s in %s", filepath.ToSlash(outputRoot), store))
+	}
+	manifest := &channelManifest{
+		Provider:    provider.Name(),
+		ChannelID:   channelID,
+		ChannelName: channelDisplayName,
+		Metadata:    newChannelMetadata(channel),
+		Emotes:      []manifestEmote{},
+	}
+	downloadChannelImages(httpClient, store, outputRoot, manifest.Metadata, previousManifest.Metadata, options.ChannelImages, logFunc)
+
+	previousEmotes := previousManifest.emotesByID()
+	folderNames := newNameAllocator()
+	for _, previousEmote := range previousManifest.Emotes {
+		folderNames.reserve(previousEmote.Folder, previousEmote.ID)
+	}
+
+	tracker := newChannelProgress(options.Progress)
+	if options.Progress != nil {
+		options.Progress = tracker.update
+	}
+
+	var downloadedBytes int64
+	downloaded := make(map[string]bool)
+	interrupted := 0
+	sizeLimitReached := false
+	downloadEmote := func(emoteIdentifier string, emoteData EmoteData) {
+		if stopRequested(options) {
+			interrupted++
+			report.addEmote(reportEmote{ID: emoteIdentifier, Code: emoteData.EmoteCode, Status: emoteStatusSkipped, Error: "interrupted"})
+			return
+		}
+		if options.MaxTotalSize > 0 && downloadedBytes >= options.MaxTotalSize {
+			if !sizeLimitReached {
+				sizeLimitReached = true
+				logFunc(fmt.Sprintf("[stop] --max-total-size of %s reached after %s", formatByteSize(options.MaxTotalSize), formatByteSize(downloadedBytes)))
+			}
+			report.addEmote(reportEmote{ID: emoteIdentifier, Code: emoteData.EmoteCode, Status: emoteStatusSkipped, Error: "--max-total-size reached"})
+			return
+		}
+		safeEmoteCode := previousEmotes[emoteIdentifier].Folder
+		if safeEmoteCode == "" {
+			baseName := makeSafeName(emoteData.EmoteCode, options.KeepUnicode)
+			var conflict string
+			safeEmoteCode, conflict = folderNames.allocate(baseName, emoteIdentifier)
+			if safeEmoteCode != baseName {
+				logFunc(fmt.Sprintf("[warn] emote %s (%s) maps to folder %s already used by emote %s, saving to %s", emoteData.EmoteCode, emoteIdentifier, baseName, conflict, safeEmoteCode))
+				report.addCollision(reportCollision{Folder: baseName, ID: emoteIdentifier, Code: emoteData.EmoteCode, ConflictID: conflict, SavedTo: safeEmoteCode})
+			}
+		}
+		logFunc(fmt.Sprintf("Downloading sizes for emote: %s (%s)", emoteData.EmoteCode, emoteIdentifier))
+		emoteStartedAt := time.Now()
+		emoteRecord, emoteReport := downloadEmoteImages(httpClient, provider, emoteIdentifier, emoteData, safeEmoteCode, outputRoot, previousFiles, options, logFunc)
+		emoteReport.Duration = time.Since(emoteStartedAt)
+		if options.Timestamps != "" {
+			logFunc(fmt.Sprintf("[time] %s (%s) took %s", emoteData.EmoteCode, emoteIdentifier, emoteReport.Duration.Round(time.Millisecond)))
+		}
+		emoteRecord.AddedAt = emoteAddedAt(emoteIdentifier, emoteData, previousEmotes, time.Now().UTC())
+		writeLargestImage(store, outputRoot, &emoteRecord, options.Largest, logFunc)
+		if options.ZipPerEmote {
+			writeEmoteZip(store, outputRoot, &emoteRecord, logFunc)
+		}
+		runEmoteHook(outputRoot, channel, emoteRecord, emoteReport, options, logFunc)
+		report.addEmote(emoteReport)
+		manifest.Emotes = append(manifest.Emotes, emoteRecord)
+		downloaded[emoteIdentifier] = true
+		for _, file := range emoteRecord.Files {
+			if previousFiles[file.URL] != file {
+				downloadedBytes += file.Bytes
+			}
+		}
+		if progressLine, due := tracker.finishEmote(downloadedBytes); due && options.ProgressMode == progressModePlain {
+			logFunc(progressLine)
+		}
+	}
+
+	var streamErr error
+	if stream != nil {
+		logFunc("Downloading emotes as they are discovered...")
+		found, selected := 0, 0
+		for emote := range stream.Emotes {
+			found++
+			if _, seen := channel.Emotes[emote.ID]; seen {
+				continue
+			}
+			channel.Emotes[emote.ID] = emote.Data
+			if !emoteSelected(emote.ID, emote.Data, options) || !emoteNewerThan(emote.ID, emote.Data, previousEmotes, options.NewerThan) {
+				continue
+			}
+			selected++
+			downloadEmote(emote.ID, emote.Data)
+		}
+		streamErr = <-stream.Done
+		logFunc(fmt.Sprintf("Found %d emotes", found))
+		if selected != found {
+			logFunc(fmt.Sprintf("Selected %d of %d emotes", selected, found))
+		}
+	} else {
+		logFunc("Collecting emote metadata...")
+
+		emoteMap := filterNewerThan(filterEmotes(channel.Emotes, options), previousEmotes, options.NewerThan)
+		logFunc(fmt.Sprintf("Found %d emotes", len(channel.Emotes)))
+		if sources := emoteCodeSources(channel.Emotes); sources != "" {
+			logFunc("Emote codes from: " + sources)
+		}
+		if options.Top > 0 && !hasUsageCounts(channel.Emotes) {
+			logFunc("[warn] no usage counts found for this channel; --top kept the first emotes by ID")
+		}
+		if len(emoteMap) != len(channel.Emotes) {
+			logFunc(fmt.Sprintf("Selected %d of %d emotes", len(emoteMap), len(channel.Emotes)))
+		}
+
+		if len(emoteMap) == 0 {
+			return nil
+		}
+
+		emoteIdentifiers := make([]string, 0, len(emoteMap))
+		for emoteIdentifier := range emoteMap {
+			emoteIdentifiers = append(emoteIdentifiers, emoteIdentifier)
+		}
+		sort.Strings(emoteIdentifiers)
+
+		tracker.emotesTotal = len(emoteIdentifiers)
+
+		if options.CheckSpace || options.MaxTotalSize > 0 {
+			if err := checkDiskSpace(httpClient, provider, emoteMap, previousFiles, outputRoot, options, logFunc); err != nil {
+				return err
+			}
+		}
+
+		for _, emoteIdentifier := range emoteIdentifiers {
+			downloadEmote(emoteIdentifier, emoteMap[emoteIdentifier])
+		}
+	}
+
+	for _, previousEmote := range previousManifest.Emotes {
+		if !downloaded[previousEmote.ID] {
+			manifest.Emotes = append(manifest.Emotes, previousEmote)
+		}
+	}
+	sort.Slice(manifest.Emotes, func(left, right int) bool {
+		return manifest.Emotes[left].ID < manifest.Emotes[right].ID
+	})
+
+	manifest.UpdatedAt = time.Now().UTC()
+	if err := saveManifest(store, outputRoot, manifest); err != nil {
+		return fmt.Errorf("cannot write manifest: %w", err)
+	}
+	if options.ManifestFormat == manifestFormatCSV {
+		if err := saveManifestCSV(store, outputRoot, manifest); err != nil {
+			return fmt.Errorf("cannot write CSV manifest: %w", err)
+		}
+	}
+	if options.Gallery {
+		if err := writeGallery(store, outputRoot, manifest); err != nil {
+			return fmt.Errorf("cannot write gallery: %w", err)
+		}
+	}
+	if options.Markdown {
+		if err := writeMarkdownTable(store, outputRoot, manifest); err != nil {
+			return fmt.Errorf("cannot write markdown table: %w", err)
+		}
+	}
+	if options.Checksums == checksumSHA256 {
+		if err := writeChecksums(store, outputRoot, manifest); err != nil {
+			return fmt.Errorf("cannot write checksums: %w", err)
+		}
+	}
+
+	if options.RecordChanges && streamErr == nil && !stopRequested(options) {
+		entry, err := recordChannelChanges(store, outputRoot, previousManifest, manifest, channel.Emotes)
+		if err != nil {
+			logFunc(fmt.Sprintf("[error] cannot update %s: %v", changesFileName, err))
+		} else if entry != nil {
+			logFunc(fmt.Sprintf("[feed] %s", entry.title(channelDisplayName)))
+		}
+	}
+
+	if options.Optimize && report.Optimized > 0 {
+		logFunc(fmt.Sprintf("Optimized %d PNG files, saved %s", report.Optimized, formatByteSize(report.SavedBytes)))
+	}
+	if streamErr != nil {
+		return fmt.Errorf("emote listing failed: %w", streamErr)
+	}
+	if stopRequested(options) {
+		logFunc(fmt.Sprintf("[stop] interrupted with %d emotes left; manifest saved with %d emotes", interrupted, len(downloaded)))
+		return errDownloadInterrupted
+	}
+	if failed := report.failedImages(); options.Strict && failed > 0 {
+		return fmt.Errorf("%d emote images could not be downloaded (--strict)", failed)
+	}
+	runChannelHook(outputRoot, provider, channel, options, logFunc)
+	return nil
+}
+
+func newModel(httpClient *http.Client, options downloadOptions, previewMode string) model {
+	input := textinput.New()
+	input.Placeholder = ""
+	input.Focus()
+	input.Prompt = "> "
+	input.CharLimit = 128
+	input.ShowSuggestions = true
+	input.KeyMap.NextSuggestion = key.NewBinding(key.WithKeys("ctrl+n"))
+	input.KeyMap.PrevSuggestion = key.NewBinding(key.WithKeys("ctrl+p"))
+
+	history := loadLines(historyFileName)
+	input.SetSuggestions(historySuggestions(history))
+
+	activity := spinner.New()
+	activity.Spinner = spinner.MiniDot
+
+	m := model{
+		textInput:       input,
+		spinner:         activity,
+		progressUpdates: make(chan progressMessage, 64),
+		progress:        make(map[int]downloadProgress),
+		history:         history,
+		historyIndex:    len(history),
+		favorites:       loadLines(favoritesFileName),
+		logLines:        []string{},
+		queue:           []queueItem{},
+		httpClient:      httpClient,
+		options:         options,
+		previewMode:     previewMode,
+		restoreState:    loadTUIState(),
+		showHelp:        false,
+	}
+	m.applyPalette(options.Palette)
+	return m
+}
+
+func (m *model) applyPalette(palette themePalette) {
+	title := foregroundStyle(palette.Base).
+		Bold(true).
+		Padding(0, 1)
+	if palette.Accent != "" {
+		title = title.Background(lipgloss.Color(palette.Accent))
+	} else {
+		title = title.Reverse(true)
+	}
+
+	logPlain := foregroundStyle(palette.Text)
+
+	logOK := foregroundStyle(palette.OK)
+
+	logSkip := foregroundStyle(palette.Warn)
+
+	logError := foregroundStyle(palette.Error)
+
+	helpTitle := foregroundStyle(palette.Accent).
+		Bold(true)
+
+	helpBody := foregroundStyle(palette.Text)
+
+	footer := foregroundStyle(palette.Muted).
+		Faint(palette.Muted == "").
+		PaddingTop(1)
+
+	m.spinner.Style = foregroundStyle(palette.Accent)
+	m.options.Palette = palette
+	m.styleTitle = title
+	m.styleLogPlain = logPlain
+	m.styleLogOK = logOK
+	m.styleLogSkip = logSkip
+	m.styleLogError = logError
+	m.styleHelpBoxTitle = helpTitle
+	m.styleHelpBoxBody = helpBody
+	m.styleFooter = footer
+}
+
+func (m model) Init() tea.Cmd {
+	return tea.Batch(textinput.Blink, waitForProgress(m.progressUpdates))
+}
+
+func waitForProgress(progressUpdates chan progressMessage) tea.Cmd {
+	return func() tea.Msg {
+		return <-progressUpdates
+	}
+}
+
+func (m model) hasExactLogLine(line string) bool {
+	for _, existing := range m.logLines {
+		if stripLogTimestamp(existing) == line {
+			return true
+		}
+	}
+	return false
+}
+
+func (m model) Update(message tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := message.(type) {
+	case tea.KeyMsg:
+		if m.restoreState != nil && msg.Type != tea.KeyCtrlC {
+			return m.updateRestorePrompt(msg)
+		}
+		if m.optionsForm != nil && msg.Type != tea.KeyCtrlC {
+			return m.updateOptionsForm(msg)
+		}
+
+		switch msg.Type {
+		case tea.KeyCtrlC, tea.KeyEsc:
+			return m, tea.Quit
 		default:
 		}
 
+		if msg.Paste {
+			channels, _ := readChannelList(strings.NewReader(string(msg.Runes)))
+			if len(channels) > 1 {
+				return m, m.enqueuePastedChannels(channels)
+			}
+			if len(channels) == 0 {
+				return m, nil
+			}
+			m.setFavoritesFocus(false)
+			m.setReviewFocus(false)
+			msg.Runes = []rune(channels[0])
+			var cmd tea.Cmd
+			m.textInput, cmd = m.textInput.Update(msg)
+			return m, cmd
+		}
+
 		if msg.String() == "q" {
 			return m, tea.Quit
 		}
@@ -476,85 +1265,573 @@ func (m model) Update(message tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
-		if msg.Type == tea.KeyEnter {
-			if m.downloading {
+		if m.lastOutputRoot != "" {
+			switch {
+			case msg.Type == tea.KeyCtrlO, m.doneFocused && msg.String() == "o":
+				m.doneFocused = false
+				m.openOutputFolder()
 				return m, nil
+			case msg.Type == tea.KeyCtrlY, m.doneFocused && msg.String() == "y":
+				m.doneFocused = false
+				m.copyOutputPath()
+				return m, nil
+			}
+		}
+		m.doneFocused = false
+
+		if msg.Type == tea.KeyCtrlS {
+			m.openOptionsForm()
+			return m, nil
+		}
+
+		if msg.Type == tea.KeyCtrlB && len(m.favorites) > 0 {
+			m.setFavoritesFocus(!m.favoritesFocused)
+			return m, nil
+		}
+
+		if m.favoritesFocused {
+			return m.updateFavorites(msg)
+		}
+
+		if msg.Type == tea.KeyCtrlF {
+			channelIdentifier := strings.TrimSpace(m.textInput.Value())
+			if channelIdentifier == "" && m.reviewIndex() >= 0 {
+				channelIdentifier = m.queue[m.reviewIndex()].Input
+			}
+			if channelIdentifier != "" {
+				m.toggleFavorite(channelIdentifier)
 			}
+			return m, nil
+		}
+
+		if msg.Type == tea.KeyTab && m.reviewIndex() >= 0 && !m.canAcceptSuggestion() {
+			m.setReviewFocus(!m.reviewFocused)
+			return m, nil
+		}
+
+		if m.reviewFocused {
+			return m.updateReview(msg)
+		}
 
+		if msg.Type == tea.KeyEnter {
 			channelIdentifier := strings.TrimSpace(m.textInput.Value())
 			if channelIdentifier == "" {
-				warningText := "Please enter a Twitch channel name or ID."
+				warningText := "Please enter a channel name or ID."
 				if !m.hasExactLogLine(warningText) {
 					m.appendLogLine(warningText)
 				}
 				return m, nil
 			}
 
-			m.downloading = true
-			m.downloadError = nil
-			m.appendLogLine(fmt.Sprintf("Resolving channel %q...", channelIdentifier))
+			m.textInput.SetValue("")
+			return m, m.enqueueChannel(channelIdentifier)
+		}
+
+		if msg.Type == tea.KeyUp || msg.Type == tea.KeyDown {
+			m.browseHistory(msg.Type == tea.KeyUp)
+			return m, nil
+		}
+
+		var cmd tea.Cmd
+		m.textInput, cmd = m.textInput.Update(msg)
+		return m, cmd
+
+	case progressMessage:
+		if m.queue[msg.QueueIndex].Status == queueStatusRunning {
+			m.progress[msg.QueueIndex] = msg.Progress
+		}
+		return m, waitForProgress(m.progressUpdates)
+
+	case spinner.TickMsg:
+		if m.runningDownloads() == 0 {
+			m.spinning = false
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.textInput.Width = max(1, msg.Width-lipgloss.Width(m.textInput.Prompt)-1)
+		return m, nil
+
+	case channelFetchedMessage:
+		item := &m.queue[msg.QueueIndex]
+		for _, line := range msg.LogLines {
+			m.appendCollectedLogLine(line)
+		}
+		if msg.Error != nil {
+			m.appendLogLine(fmt.Sprintf("Error: %v", msg.Error))
+			m.downloadError = msg.Error
+			item.Status = queueStatusFailed
+			item.Error = msg.Error
+			return m, m.startQueuedDownloads()
+		}
+
+		item.Status = queueStatusReview
+		item.Provider = msg.Provider
+		item.Channel = msg.Channel
+		item.Previews = msg.Previews
+		item.EmoteSize = msg.EmoteSize
+		item.EmoteOrder = sortedEmoteIdentifiersByCode(msg.Channel.Emotes)
+		item.Selected = make(map[string]bool, len(msg.Channel.Emotes))
+		for emoteIdentifier := range msg.Channel.Emotes {
+			item.Selected[emoteIdentifier] = true
+		}
+		m.appendLogLine(fmt.Sprintf("Found %d emotes for %s, waiting for confirmation", len(msg.Channel.Emotes), item.Input))
+		if strings.TrimSpace(m.textInput.Value()) == "" {
+			m.setReviewFocus(true)
+		}
+		return m, m.startQueuedDownloads()
+
+	case downloadResultMessage:
+		item := &m.queue[msg.QueueIndex]
+		for _, line := range msg.LogLines {
+			m.appendCollectedLogLine(line)
+		}
+		if msg.Error != nil {
+			m.appendLogLine(fmt.Sprintf("Error: %v", msg.Error))
+			m.downloadError = msg.Error
+			item.Status = queueStatusFailed
+			item.Error = msg.Error
+		} else {
+			m.appendLogLine(fmt.Sprintf("Download completed: %s", item.Input))
+			item.Status = queueStatusDone
+			m.lastOutputRoot = msg.OutputRoot
+			m.doneFocused = !m.reviewFocused && !m.favoritesFocused && m.textInput.Value() == ""
+		}
+		if item.Provider != nil && item.Channel != nil {
+			m.statsFooter = formatStatsFooter(loadDownloadStats(), item.Provider.Name(), item.Channel.ID)
+		}
+		item.Channel = nil
+		item.Previews = nil
+		delete(m.progress, msg.QueueIndex)
+		command := m.startQueuedDownloads()
+		m.notifyWhenIdle()
+		return m, command
+
+	default:
+		var cmd tea.Cmd
+		m.textInput, cmd = m.textInput.Update(message)
+		return m, cmd
+	}
+}
+
+func (m *model) notifyWhenIdle() {
+	if !m.options.Notify {
+		return
+	}
+	finished := 0
+	failed := make([]string, 0)
+	for _, item := range m.queue {
+		switch item.Status {
+		case queueStatusQueued, queueStatusFetching, queueStatusConfirmed, queueStatusRunning:
+			return
+		case queueStatusDone:
+			finished++
+		case queueStatusFailed:
+			failed = append(failed, item.Input)
+		}
+	}
+	if finished == m.notifiedFinished && len(failed) == m.notifiedFailed {
+		return
+	}
+	m.notifiedFinished = finished
+	m.notifiedFailed = len(failed)
+
+	title := "twe-dlp: download finished"
+	body := fmt.Sprintf("%d channels downloaded", finished)
+	if len(failed) > 0 {
+		title = "twe-dlp: download failed"
+		body = fmt.Sprintf("%s, %d failed: %s", body, len(failed), strings.Join(failed, ", "))
+	}
+	if err := sendNotification(title, body); err != nil {
+		m.appendLogLine(fmt.Sprintf("[error] cannot send notification: %v", err))
+	}
+}
+
+func (m *model) openOutputFolder() {
+	outputPath, err := filepath.Abs(m.lastOutputRoot)
+	if err == nil {
+		err = openInFileManager(outputPath)
+	}
+	if err != nil {
+		m.appendLogLine(fmt.Sprintf("[error] cannot open %s: %v", m.lastOutputRoot, err))
+		return
+	}
+	m.appendLogLine(fmt.Sprintf("Opened %s", outputPath))
+}
+
+func (m *model) copyOutputPath() {
+	outputPath, err := filepath.Abs(m.lastOutputRoot)
+	if err == nil {
+		err = copyToClipboard(outputPath)
+	}
+	if err != nil {
+		m.appendLogLine(fmt.Sprintf("[error] cannot copy path to the clipboard: %v", err))
+		return
+	}
+	m.appendLogLine(fmt.Sprintf("Copied %s to the clipboard", outputPath))
+}
+
+func (m *model) enqueueChannel(channelIdentifier string) tea.Cmd {
+	m.queue = append(m.queue, queueItem{
+		Input:  channelIdentifier,
+		Status: queueStatusQueued,
+	})
+	m.rememberInput(channelIdentifier)
+	return m.startQueuedDownloads()
+}
+
+func (m *model) enqueuePastedChannels(channels []string) tea.Cmd {
+	for _, channelIdentifier := range channels {
+		m.queue = append(m.queue, queueItem{
+			Input:  channelIdentifier,
+			Status: queueStatusQueued,
+		})
+		m.rememberInput(channelIdentifier)
+	}
+	m.appendLogLine(fmt.Sprintf("Queued %d pasted channels", len(channels)))
+	return m.startQueuedDownloads()
+}
+
+func (m *model) toggleFavorite(channelIdentifier string) {
+	var removed bool
+	m.favorites, removed = removeFavorite(m.favorites, channelIdentifier)
+	if removed {
+		m.appendLogLine(fmt.Sprintf("Removed %s from favorites", channelIdentifier))
+	} else {
+		m.favorites, _ = addFavorite(m.favorites, channelIdentifier)
+		m.appendLogLine(fmt.Sprintf("Added %s to favorites", channelIdentifier))
+	}
+	if m.favoritesCursor >= len(m.favorites) {
+		m.favoritesCursor = max(0, len(m.favorites)-1)
+	}
+	if len(m.favorites) == 0 {
+		m.setFavoritesFocus(false)
+	}
+	if err := saveLines(favoritesFileName, m.favorites); err != nil {
+		m.appendLogLine(fmt.Sprintf("[error] cannot save favorites: %v", err))
+	}
+}
+
+func (m *model) setFavoritesFocus(focused bool) {
+	m.favoritesFocused = focused && len(m.favorites) > 0
+	if m.favoritesFocused {
+		m.reviewFocused = false
+		m.doneFocused = false
+		m.textInput.Blur()
+	} else {
+		m.textInput.Focus()
+	}
+}
+
+func (m model) updateFavorites(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if m.favoritesCursor > 0 {
+			m.favoritesCursor--
+		}
+	case "down", "j":
+		if m.favoritesCursor < len(m.favorites)-1 {
+			m.favoritesCursor++
+		}
+	case "f", "delete", "backspace":
+		m.toggleFavorite(m.favorites[m.favoritesCursor])
+	case "enter":
+		channelIdentifier := m.favorites[m.favoritesCursor]
+		m.setFavoritesFocus(false)
+		return m, m.enqueueChannel(channelIdentifier)
+	case "tab":
+		m.setFavoritesFocus(false)
+	}
+	return m, nil
+}
+
+func (m model) canAcceptSuggestion() bool {
+	return !m.reviewFocused && m.textInput.Value() != "" && m.textInput.CurrentSuggestion() != "" && m.textInput.CurrentSuggestion() != m.textInput.Value()
+}
+
+func (m *model) rememberInput(channelIdentifier string) {
+	m.history = addHistoryEntry(m.history, channelIdentifier)
+	m.historyIndex = len(m.history)
+	m.historyDraft = ""
+	m.textInput.SetSuggestions(historySuggestions(m.history))
+	if err := saveLines(historyFileName, m.history); err != nil {
+		m.appendLogLine(fmt.Sprintf("[error] cannot save history: %v", err))
+	}
+}
+
+func (m *model) browseHistory(older bool) {
+	if len(m.history) == 0 {
+		return
+	}
+	if m.historyIndex == len(m.history) {
+		m.historyDraft = m.textInput.Value()
+	}
+
+	if older {
+		if m.historyIndex == 0 {
+			return
+		}
+		m.historyIndex--
+	} else {
+		if m.historyIndex == len(m.history) {
+			return
+		}
+		m.historyIndex++
+	}
+
+	if m.historyIndex == len(m.history) {
+		m.textInput.SetValue(m.historyDraft)
+	} else {
+		m.textInput.SetValue(m.history[m.historyIndex])
+	}
+	m.textInput.CursorEnd()
+}
+
+func (item queueItem) selectedCount() int {
+	count := 0
+	for _, selected := range item.Selected {
+		if selected {
+			count++
+		}
+	}
+	return count
+}
+
+func (item queueItem) estimatedSize() int64 {
+	return item.EmoteSize * int64(item.selectedCount())
+}
+
+func sortedEmoteIdentifiersByCode(emotes map[string]EmoteData) []string {
+	emoteIdentifiers := make([]string, 0, len(emotes))
+	for emoteIdentifier := range emotes {
+		emoteIdentifiers = append(emoteIdentifiers, emoteIdentifier)
+	}
+	sort.Slice(emoteIdentifiers, func(left, right int) bool {
+		leftCode := strings.ToLower(emotes[emoteIdentifiers[left]].EmoteCode)
+		rightCode := strings.ToLower(emotes[emoteIdentifiers[right]].EmoteCode)
+		if leftCode != rightCode {
+			return leftCode < rightCode
+		}
+		return emoteIdentifiers[left] < emoteIdentifiers[right]
+	})
+	return emoteIdentifiers
+}
+
+func (m model) runningDownloads() int {
+	running := 0
+	for _, item := range m.queue {
+		if item.Status == queueStatusFetching || item.Status == queueStatusRunning {
+			running++
+		}
+	}
+	return running
+}
+
+func (m model) reviewIndex() int {
+	for index, item := range m.queue {
+		if item.Status == queueStatusReview {
+			return index
+		}
+	}
+	return -1
+}
+
+func (m *model) setReviewFocus(focused bool) {
+	m.reviewFocused = focused && m.reviewIndex() >= 0
+	if m.reviewFocused {
+		m.favoritesFocused = false
+		m.doneFocused = false
+		m.textInput.Blur()
+	} else {
+		m.textInput.Focus()
+	}
+}
+
+func (m model) updateReview(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	index := m.reviewIndex()
+	if index < 0 {
+		m.setReviewFocus(false)
+		return m, nil
+	}
+	item := &m.queue[index]
+
+	if msg.String() != "enter" {
+		item.Confirming = false
+	}
+	switch msg.String() {
+	case "up", "k":
+		if item.Cursor > 0 {
+			item.Cursor--
+		}
+		return m, nil
+	case "down", "j":
+		if item.Cursor < len(item.EmoteOrder)-1 {
+			item.Cursor++
+		}
+		return m, nil
+	case " ":
+		if item.Cursor < len(item.EmoteOrder) {
+			emoteIdentifier := item.EmoteOrder[item.Cursor]
+			item.Selected[emoteIdentifier] = !item.Selected[emoteIdentifier]
+		}
+		return m, nil
+	case "f":
+		m.toggleFavorite(item.Input)
+		return m, nil
+	case "a":
+		selectAll := item.selectedCount() < len(item.EmoteOrder)
+		for _, emoteIdentifier := range item.EmoteOrder {
+			item.Selected[emoteIdentifier] = selectAll
+		}
+		return m, nil
+	case "enter":
+		if item.selectedCount() == 0 {
+			m.appendLogLine(fmt.Sprintf("No emotes selected for %s", item.Input))
+			return m, nil
+		}
+		if item.selectedCount() >= largeDownloadEmotes && item.EmoteSize > 0 && !item.Confirming {
+			item.Confirming = true
+			return m, nil
+		}
+		selectedEmotes := make(map[string]EmoteData, item.selectedCount())
+		for emoteIdentifier, emoteData := range item.Channel.Emotes {
+			if item.Selected[emoteIdentifier] {
+				selectedEmotes[emoteIdentifier] = emoteData
+			}
+		}
+		item.Channel.Emotes = selectedEmotes
+		item.Status = queueStatusConfirmed
+	case "x", "n", "delete", "backspace":
+		item.Status = queueStatusSkipped
+		item.Channel = nil
+		item.Previews = nil
+		m.appendLogLine(fmt.Sprintf("Skipped %s", item.Input))
+	default:
+		return m, nil
+	}
 
-			return m, func() tea.Msg {
-				collectedLogs := make([]string, 0, 64)
-				logFunc := func(line string) {
-					collectedLogs = append(collectedLogs, line)
-				}
+	m.setReviewFocus(m.reviewIndex() >= 0)
+	return m, m.startQueuedDownloads()
+}
 
-				channelID, err := resolveChannelIdentifierToID(m.httpClient, channelIdentifier)
-				if err != nil {
-					logFunc(fmt.Sprintf("Error resolving channel: %v", err))
-					return downloadResultMessage{
-						Error:    err,
-						LogLines: collectedLogs,
-					}
-				}
+func (m *model) startQueuedDownloads() tea.Cmd {
+	var commands []tea.Cmd
+	running := m.runningDownloads()
+	for index := range m.queue {
+		if running >= m.options.ChannelConcurrency {
+			break
+		}
+		item := &m.queue[index]
+		switch item.Status {
+		case queueStatusQueued:
+			item.Status = queueStatusFetching
+			item.StartedAt = time.Now()
+			m.appendLogLine(fmt.Sprintf("Resolving channel %q...", item.Input))
+			commands = append(commands, fetchQueueItem(rateLimitedClient(m.httpClient, m.options.ChannelRateLimit), m.options, m.previewMode, index, item.Input))
+		case queueStatusConfirmed:
+			item.Status = queueStatusRunning
+			item.StartedAt = time.Now()
+			commands = append(commands, downloadQueueItem(rateLimitedClient(m.httpClient, m.options.ChannelRateLimit), m.options, index, item.Provider, item.Channel, m.progressUpdates))
+		default:
+			continue
+		}
+		running++
+	}
+	if running > 0 && !m.spinning {
+		m.spinning = true
+		commands = append(commands, m.spinner.Tick)
+	}
+	return tea.Batch(commands...)
+}
 
-				err = downloadChannelEmotes(m.httpClient, channelID, logFunc)
+func fetchQueueItem(httpClient *http.Client, options downloadOptions, previewMode string, queueIndex int, channelIdentifier string) tea.Cmd {
+	return func() tea.Msg {
+		collectedLogs := make([]string, 0, 4)
+		httpClient := throttledClient(httpClient, func(line string) {
+			collectedLogs = append(collectedLogs, logTimestamp(options.Timestamps)+line)
+		})
 
-				return downloadResultMessage{
-					Error:    err,
-					LogLines: collectedLogs,
-				}
+		provider, providerIdentifier, err := selectProvider(channelIdentifier, options.Provider)
+		if err != nil {
+			return channelFetchedMessage{
+				QueueIndex: queueIndex,
+				Error:      err,
+				LogLines:   collectedLogs,
 			}
 		}
 
-		if !m.downloading {
-			var cmd tea.Cmd
-			m.textInput, cmd = m.textInput.Update(msg)
-			return m, cmd
+		channelID, err := provider.ResolveChannelID(httpClient, providerIdentifier)
+		if err != nil {
+			collectedLogs = append(collectedLogs, fmt.Sprintf("Error resolving channel: %v", err))
+			return channelFetchedMessage{
+				QueueIndex: queueIndex,
+				Error:      err,
+				LogLines:   collectedLogs,
+			}
 		}
-		return m, nil
 
-	case downloadResultMessage:
-		for _, line := range msg.LogLines {
-			m.appendLogLine(line)
+		channel, err := provider.FetchChannel(httpClient, channelID)
+		if err != nil {
+			return channelFetchedMessage{
+				QueueIndex: queueIndex,
+				Error:      err,
+				LogLines:   collectedLogs,
+			}
 		}
-		if msg.Error != nil {
-			m.appendLogLine(fmt.Sprintf("Error: %v", msg.Error))
-			m.downloadError = msg.Error
-		} else {
-			m.appendLogLine("Download completed.")
+		channel.Emotes = filterEmotes(channel.Emotes, options)
+
+		return channelFetchedMessage{
+			QueueIndex: queueIndex,
+			Provider:   provider,
+			Channel:    channel,
+			Previews:   fetchEmotePreviews(httpClient, provider, channel, previewMode),
+			EmoteSize:  sampleEmoteSize(httpClient, provider, downloadSizes(provider, options), channel.Emotes),
+			LogLines:   collectedLogs,
 		}
-		m.downloading = false
-		m.textInput.SetValue("")
-		m.textInput.Focus()
-		return m, nil
+	}
+}
 
-	default:
-		if !m.downloading {
-			var cmd tea.Cmd
-			m.textInput, cmd = m.textInput.Update(message)
-			return m, cmd
+func downloadQueueItem(httpClient *http.Client, options downloadOptions, queueIndex int, provider emoteProvider, channel *ChannelData, progressUpdates chan progressMessage) tea.Cmd {
+	options.Progress = func(progress downloadProgress) {
+		select {
+		case progressUpdates <- progressMessage{QueueIndex: queueIndex, Progress: progress}:
+		default:
 		}
 	}
+	return func() tea.Msg {
+		collectedLogs := make([]string, 0, 64)
+		logFunc := func(line string) {
+			collectedLogs = append(collectedLogs, logTimestamp(options.Timestamps)+line)
+		}
 
-	return m, nil
+		err := downloadChannelData(throttledClient(httpClient, logFunc), provider, channel, options, logFunc)
+
+		return downloadResultMessage{
+			QueueIndex: queueIndex,
+			OutputRoot: channelOutputRoot(provider, channel, options),
+			Error:      err,
+			LogLines:   collectedLogs,
+		}
+	}
 }
 
 func (m *model) appendLogLine(line string) {
 	if line == "" {
 		return
 	}
+	m.appendCollectedLogLine(logTimestamp(m.options.Timestamps) + line)
+}
+
+func (m *model) appendCollectedLogLine(line string) {
+	if line == "" {
+		return
+	}
+	m.options.Log.write(line)
 	m.logLines = append(m.logLines, line)
 	if len(m.logLines) > logBufferMaxMessages {
 		m.logLines = m.logLines[len(m.logLines)-logBufferMaxMessages:]
@@ -567,6 +1844,159 @@ func (m model) renderHelpBox() string {
 	builder.WriteString(m.styleHelpBoxTitle.Render("Usage"))
 	builder.WriteString("\n")
 	builder.WriteString(m.styleHelpBoxBody.Render("  tw-dlp <channel|id>"))
+	builder.WriteString("\n")
+	builder.WriteString(m.styleHelpBoxBody.Render("  kick:<channel>, youtube:<@handle>  use another provider"))
+	builder.WriteString("\n")
+	builder.WriteString(m.styleHelpBoxBody.Render("  Enter adds the channel to the download queue"))
+	builder.WriteString("\n")
+	builder.WriteString(m.styleHelpBoxBody.Render("  Pasting several lines queues every channel at once"))
+	builder.WriteString("\n")
+	builder.WriteString(m.styleHelpBoxBody.Render("  ↑/↓ browse history, Tab completes a previous channel"))
+	builder.WriteString("\n")
+	builder.WriteString(m.styleHelpBoxBody.Render("  Ctrl+F favorites the typed channel, Ctrl+B picks a favorite"))
+	builder.WriteString("\n")
+	builder.WriteString(m.styleHelpBoxBody.Render("  Ctrl+S sets provider, sizes, theme and output folder"))
+
+	return builder.String()
+}
+
+func (m model) renderFavorites() string {
+	var builder strings.Builder
+
+	if m.compact() && !m.favoritesFocused {
+		builder.WriteString(m.styleHelpBoxTitle.Render(fmt.Sprintf("Favorites: %d", len(m.favorites))))
+		builder.WriteString(m.styleFooter.UnsetPaddingTop().Render(" (Ctrl+B)"))
+		return builder.String()
+	}
+
+	builder.WriteString(m.styleHelpBoxTitle.Render("Favorites"))
+	for index, favorite := range m.favorites {
+		cursorText := "  "
+		if m.favoritesFocused && index == m.favoritesCursor {
+			cursorText = "> "
+		}
+		builder.WriteString("\n")
+		builder.WriteString(m.styleLogPlain.Render(cursorText + favorite))
+	}
+
+	hintText := "Ctrl+B: pick a favorite • Ctrl+F: favorite the typed channel"
+	if m.favoritesFocused {
+		hintText = "↑/↓: move • Enter: queue • f: remove • Ctrl+B/Tab: back to input"
+	}
+	builder.WriteString(m.renderHint(hintText))
+
+	return builder.String()
+}
+
+func (m model) renderQueue() string {
+	var builder strings.Builder
+
+	builder.WriteString(m.styleHelpBoxTitle.Render("Queue"))
+	for _, item := range m.queue {
+		statusText := fmt.Sprintf("[%s] %s", item.Status, item.Input)
+		if item.Error != nil && !m.compact() {
+			statusText = fmt.Sprintf("%s (%v)", statusText, item.Error)
+		}
+		activityText := "  "
+		if item.Status == queueStatusFetching || item.Status == queueStatusRunning {
+			activityText = m.spinner.View() + " "
+			statusText = fmt.Sprintf("%s %s", statusText, time.Since(item.StartedAt).Truncate(time.Second))
+		}
+
+		var styledLine string
+		switch item.Status {
+		case queueStatusDone:
+			styledLine = m.styleLogOK.Render(statusText)
+		case queueStatusRunning:
+			styledLine = m.styleLogSkip.Render(statusText)
+		case queueStatusFailed:
+			styledLine = m.styleLogError.Render(statusText)
+		default:
+			styledLine = m.styleLogPlain.Render(statusText)
+		}
+		builder.WriteString("\n")
+		builder.WriteString(activityText)
+		builder.WriteString(styledLine)
+	}
+
+	return builder.String()
+}
+
+func (m model) renderProgress() string {
+	var builder strings.Builder
+
+	builder.WriteString(m.styleHelpBoxTitle.Render("Downloading"))
+	for index, item := range m.queue {
+		progress, exists := m.progress[index]
+		if !exists {
+			continue
+		}
+		progressText := formatProgressStatus(progress)
+		if !m.compact() {
+			progressText = fmt.Sprintf("%s: %s", item.Input, progressText)
+		}
+		builder.WriteString("\n  ")
+		builder.WriteString(m.styleLogPlain.Render(progressText))
+	}
+
+	return builder.String()
+}
+
+func (m model) renderReview(item queueItem) string {
+	var builder strings.Builder
+
+	channelName := item.Channel.DisplayName
+	if channelName == "" {
+		channelName = item.Input
+	}
+	titleText := fmt.Sprintf("Review: %s (%d of %d emotes selected)", channelName, item.selectedCount(), len(item.EmoteOrder))
+	if item.EmoteSize > 0 {
+		titleText = fmt.Sprintf("Review: %s (%d of %d emotes selected, ~%s)", channelName, item.selectedCount(), len(item.EmoteOrder), formatByteSize(item.estimatedSize()))
+	}
+	builder.WriteString(m.styleHelpBoxTitle.Render(titleText))
+
+	visibleRows := m.reviewRows()
+	firstRow := 0
+	if item.Cursor >= visibleRows {
+		firstRow = item.Cursor - visibleRows + 1
+	}
+	lastRow := min(firstRow+visibleRows, len(item.EmoteOrder))
+
+	for row := firstRow; row < lastRow; row++ {
+		emoteIdentifier := item.EmoteOrder[row]
+
+		cursorText := "  "
+		if row == item.Cursor && m.reviewFocused {
+			cursorText = "> "
+		}
+		checkboxText := "[ ]"
+		if item.Selected[emoteIdentifier] {
+			checkboxText = "[x]"
+		}
+		preview := item.Previews[emoteIdentifier]
+		if preview == "" {
+			preview = previewPlaceholder + " "
+		}
+
+		builder.WriteString("\n  ")
+		builder.WriteString(m.styleLogPlain.Render(cursorText + checkboxText + " "))
+		builder.WriteString(preview)
+		builder.WriteString(" ")
+		builder.WriteString(m.styleLogPlain.Render(fmt.Sprintf("%s (%s)", item.Channel.Emotes[emoteIdentifier].EmoteCode, emoteIdentifier)))
+	}
+	if len(item.EmoteOrder) > visibleRows {
+		builder.WriteString("\n  ")
+		builder.WriteString(m.styleFooter.UnsetPaddingTop().Render(fmt.Sprintf("%d-%d of %d", firstRow+1, lastRow, len(item.EmoteOrder))))
+	}
+
+	hintText := "↑/↓: move • Space: toggle • a: toggle all • Enter: download • x: skip • f: favorite • Tab: back to input"
+	if item.Confirming {
+		hintText = fmt.Sprintf("Download %d emotes, about %s? Enter: start download • any other key: back to the list", item.selectedCount(), formatByteSize(item.estimatedSize()))
+	}
+	if !m.reviewFocused {
+		hintText = "Tab: review pending channel"
+	}
+	builder.WriteString(m.renderHint(hintText))
 
 	return builder.String()
 }
@@ -582,71 +2012,498 @@ func (m model) View() string {
 		builder.WriteString("\n\n")
 	}
 
-	if len(m.logLines) > 0 {
-		for _, line := range m.logLines {
-			var styledLine string
-			switch {
-			case strings.HasPrefix(line, "[ok]"):
-				styledLine = m.styleLogOK.Render(line)
-			case strings.HasPrefix(line, "[skip]"):
-				styledLine = m.styleLogSkip.Render(line)
-			case strings.HasPrefix(line, "[error]"), strings.HasPrefix(line, "Error:"):
-				styledLine = m.styleLogError.Render(line)
-			default:
-				styledLine = m.styleLogPlain.Render(line)
-			}
-			builder.WriteString("  ")
-			builder.WriteString(styledLine)
-			builder.WriteString("\n")
-		}
-		builder.WriteString("\n")
+	if len(m.favorites) > 0 {
+		builder.WriteString(m.renderFavorites())
+		builder.WriteString("\n\n")
 	}
 
-	builder.WriteString(m.textInput.View())
-	builder.WriteString("\n")
+	if len(m.queue) > 0 {
+		builder.WriteString(m.renderQueue())
+		builder.WriteString("\n\n")
+	}
+
+	if len(m.progress) > 0 {
+		builder.WriteString(m.renderProgress())
+		builder.WriteString("\n\n")
+	}
+
+	if reviewIndex := m.reviewIndex(); reviewIndex >= 0 {
+		builder.WriteString(m.renderReview(m.queue[reviewIndex]))
+		builder.WriteString("\n\n")
+	}
+
+	if m.optionsForm != nil {
+		builder.WriteString(m.renderOptionsForm())
+		builder.WriteString("\n\n")
+	}
 
+	var bottom strings.Builder
+	if m.restoreState != nil {
+		bottom.WriteString(m.renderRestorePrompt())
+		bottom.WriteString("\n")
+	}
+	bottom.WriteString(m.textInput.View())
+	bottom.WriteString("\n")
 	footerText := "Esc/q: quit • ? more"
-	builder.WriteString(m.styleFooter.Render(footerText))
-	builder.WriteString("\n")
+	switch {
+	case m.doneFocused:
+		footerText = "o: open folder • y: copy path • " + footerText
+	case m.lastOutputRoot != "" && !m.compact():
+		footerText = "Ctrl+O: open folder • Ctrl+Y: copy path • " + footerText
+	}
+	bottom.WriteString(m.styleFooter.Render(footerText))
+	bottom.WriteString("\n")
+	if m.statsFooter != "" && !m.compact() {
+		bottom.WriteString(m.styleFooter.Render(m.statsFooter))
+		bottom.WriteString("\n")
+	}
+
+	logRows := m.renderLogLines()
+	if m.height > 0 {
+		availableRows := m.height - strings.Count(builder.String(), "\n") - strings.Count(bottom.String(), "\n") - 2
+		logRows = logRows[max(0, len(logRows)-max(0, availableRows)):]
+	}
+	if len(logRows) > 0 {
+		builder.WriteString(strings.Join(logRows, "\n"))
+		builder.WriteString("\n\n")
+	}
+
+	builder.WriteString(bottom.String())
 
 	return builder.String()
 }
 
-func runTextMode(httpClient *http.Client, channelIdentifier string) int {
-	channelID, err := resolveChannelIdentifierToID(httpClient, channelIdentifier)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error resolving channel: %v\n", err)
-		return 1
+func (m model) renderLogLines() []string {
+	rows := make([]string, 0, len(m.logLines))
+	for _, line := range m.logLines {
+		var lineStyle lipgloss.Style
+		message := stripLogTimestamp(line)
+		switch {
+		case strings.HasPrefix(message, "[ok]"):
+			lineStyle = m.styleLogOK
+		case strings.HasPrefix(message, "[skip]"):
+			lineStyle = m.styleLogSkip
+		case strings.HasPrefix(message, "[error]"), strings.HasPrefix(message, "Error:"):
+			lineStyle = m.styleLogError
+		default:
+			lineStyle = m.styleLogPlain
+		}
+		if m.width > 2 {
+			lineStyle = lineStyle.Width(m.width - 2)
+		}
+		for _, row := range strings.Split(lineStyle.Render(line), "\n") {
+			rows = append(rows, "  "+row)
+		}
+	}
+	return rows
+}
+
+func (m model) compact() bool {
+	return m.width > 0 && m.width < compactLayoutWidth
+}
+
+func (m model) reviewRows() int {
+	if m.height == 0 {
+		return reviewVisibleRows
+	}
+	return min(reviewVisibleRows, max(3, m.height/3))
+}
+
+func (m model) renderHint(hintText string) string {
+	if m.compact() {
+		return ""
+	}
+	hintStyle := m.styleFooter
+	if m.width > 0 {
+		hintStyle = hintStyle.Width(m.width)
+	}
+	return "\n" + hintStyle.Render(hintText)
+}
+
+func runTextMode(httpClient *http.Client, channelIdentifier string, options downloadOptions) int {
+	var status *statusLine
+	if options.ProgressMode == progressModeAuto && term.IsTerminal(os.Stderr.Fd()) {
+		status = &statusLine{writer: os.Stderr}
+		if width, _, err := term.GetSize(os.Stderr.Fd()); err == nil {
+			status.width = width
+		}
+		options.Progress = func(progress downloadProgress) {
+			status.show("[download] " + formatProgressStatus(progress))
+		}
 	}
+	defer status.clear()
 
 	logFunc := func(line string) {
-		fmt.Println(line)
+		status.clear()
+		fmt.Println(logTimestamp(options.Timestamps) + line)
+		options.Log.write(line)
 	}
 
-	err = downloadChannelEmotes(httpClient, channelID, logFunc)
-	if err != nil {
+	restoreSignals := stopOnSignal(&options)
+	defer restoreSignals()
+
+	if _, err := downloadChannelInput(httpClient, channelIdentifier, options, logFunc); err != nil {
+		if errors.Is(err, errDownloadInterrupted) {
+			printResumeHint(options)
+			return interruptedExitCode
+		}
 		fmt.Fprintf(os.Stderr, "Error downloading emotes: %v\n", err)
+		options.Log.write(fmt.Sprintf("Error downloading emotes: %v", err))
+		notifyIfRequested(options, true, fmt.Sprintf("%s: %v", channelIdentifier, err))
 		return 1
 	}
+	notifyIfRequested(options, false, fmt.Sprintf("Downloaded emotes for %s", channelIdentifier))
 	return 0
 }
 
-func main() {
-	httpClient := createHTTPClient()
+func newCommandFlagSet(name string, options *downloadOptions) *flag.FlagSet {
+	flagSet := flag.NewFlagSet(name, flag.ContinueOnError)
+	flagSet.BoolVar(&options.KeepUnicode, "keep-unicode", false, "keep unicode characters in emote folder and file names")
+	flagSet.StringVar(&options.Provider, "provider", defaultProviderName, "emote provider to use ("+strings.Join(providerNames(), ", ")+")")
+	flagSet.StringVar(&options.YouTubeToken, "youtube-token", os.Getenv("YOUTUBE_OAUTH_TOKEN"), "OAuth token for the YouTube Data API, used to resolve handles and read the channel name, avatar and banner (emojis always come from the membership page)")
+	flagSet.IntVar(&options.ChannelConcurrency, "channel-concurrency", 1, "number of channels to download at the same time")
+	flagSet.BoolVar(&options.Pipeline, "pipeline", false, "start downloading emotes while the provider's emote listing is still being read (no size estimate)")
+	flagSet.Float64Var(&options.ChannelRateLimit, "channel-rate-limit", 10, "maximum requests per second for each channel (0 for no limit)")
+	flagSet.Func("sizes", "only download these image sizes, e.g. 2.0,3.0 for Twitch or 48,96 for YouTube (comma separated, repeatable)", func(value string) error {
+		options.Sizes = append(options.Sizes, splitCommaList(value)...)
+		return nil
+	})
+	flagSet.Func("only", "only download emotes with these codes or IDs (comma separated, repeatable)", func(value string) error {
+		options.Only = append(options.Only, splitCommaList(value)...)
+		return nil
+	})
+	flagSet.Func("exclude", "skip emotes with these codes or IDs (comma separated, repeatable)", func(value string) error {
+		options.Exclude = append(options.Exclude, splitCommaList(value)...)
+		return nil
+	})
+	flagSet.Func("newer-than", "only download emotes added after this date (2024-01-01) or within this age (30d), using the provider's creation dates or when the emote first appeared in the manifest", func(value string) error {
+		since, err := parseNewerThan(value, time.Now())
+		if err != nil {
+			return err
+		}
+		options.NewerThan = since
+		return nil
+	})
+	flagSet.IntVar(&options.Top, "top", 0, "only download the channel's N most-used emotes, ranked by the usage counts twitchemotes.com shows")
+	flagSet.Func("filter", "only download emotes whose code matches this regex or glob, case-insensitively (repeatable)", func(value string) error {
+		filter, err := compileEmoteFilter(value)
+		if err != nil {
+			return err
+		}
+		options.Filters = append(options.Filters, filter)
+		return nil
+	})
+	flagSet.StringVar(&options.UserAgent, "user-agent", defaultUserAgent, "User-Agent header sent with every request")
+	flagSet.StringVar(&options.RecordFixtures, "record-fixtures", "", "save every fetched page, API response and image with its URL and status to this directory")
+	flagSet.StringVar(&options.Offline, "offline", "", "replay the responses saved by --record-fixtures in this directory instead of using the network")
+	flagSet.IntVar(&options.MaxConnsPerHost, "max-conns-per-host", defaultMaxConnsPerHost, "maximum open connections to each host, shared by all concurrent downloads (0 for no limit)")
+	flagSet.DurationVar(&options.ScrapeDelay, "scrape-delay", defaultScrapeDelay, "minimum time between twitchemotes.com page requests, shared by all channels (0 for none); CDN downloads are not delayed")
+	flagSet.Func("header", "extra request header as 'Name: value' (repeatable)", func(value string) error {
+		name, headerValue, err := parseHeaderFlag(value)
+		if err != nil {
+			return err
+		}
+		if options.Headers == nil {
+			options.Headers = make(http.Header)
+		}
+		options.Headers.Add(name, headerValue)
+		return nil
+	})
+	flagSet.BoolVar(&options.Check, "check", false, "send HEAD requests for every emote and size and report which URLs are live, their types and sizes, without downloading")
+	flagSet.BoolVar(&options.DryRun, "dry-run", false, "resolve channels and print the URLs that would be fetched and the files that would be written, without writing anything")
+	flagSet.BoolVar(&options.Strict, "strict", false, "treat every emote size that cannot be downloaded as an error and exit non-zero, for complete archives")
+	flagSet.BoolVar(&options.CheckSpace, "check-space", false, "estimate the download size and refuse to start when the disk is too full")
+	flagSet.Func("max-total-size", "stop a channel after downloading this much, e.g. 500M or 2G (also refuses to start when the estimate is larger)", func(value string) error {
+		maxTotalSize, err := parseByteSize(value)
+		if err != nil {
+			return err
+		}
+		options.MaxTotalSize = maxTotalSize
+		return nil
+	})
+	flagSet.StringVar(&options.Dest, "dest", "", "write channel folders to s3://bucket/prefix, webdav://[user@]host/path or sftp://user@host/path instead of the local disk")
+	flagSet.BoolVar(&options.Durable, "durable", false, "fsync downloaded files and their directories before moving on")
+	flagSet.BoolVar(&options.Gallery, "gallery", false, "write an index.html gallery of the downloaded emotes into each channel folder")
+	flagSet.BoolVar(&options.Markdown, "markdown", false, "write a README.md table of the downloaded emotes into each channel folder")
+	flagSet.StringVar(&options.Checksums, "checksums", "", "write a checksum file for the downloaded images into each channel folder ("+strings.Join(checksumAlgorithms, ", ")+" writes SHA256SUMS for sha256sum -c)")
+	flagSet.BoolVar(&options.Trim, "trim", false, "also write a copy of each emote with transparent margins cropped (<emote>_<size>_normalized.png)")
+	flagSet.Func("pad", "also write a copy of each emote centered on a transparent WIDTHxHEIGHT canvas, shrinking larger emotes to fit", func(value string) error {
+		pad, err := parsePadSize(value)
+		if err != nil {
+			return err
+		}
+		options.Pad = pad
+		return nil
+	})
+	flagSet.BoolVar(&options.Optimize, "optimize", false, "losslessly recompress downloaded PNG emotes and strip ancillary chunks, keeping the result only when smaller")
+	flagSet.StringVar(&options.ExecAfterEmote, "exec-after-emote", "", "run this shell command after each emote with new files, {} replaced by their paths (appended when missing)")
+	flagSet.StringVar(&options.ExecAfterChannel, "exec-after-channel", "", "run this shell command after each channel finishes, {} replaced by the channel folder (appended when missing)")
+	flagSet.BoolVar(&options.ChannelImages, "channel-images", false, "also download the channel avatar and banner next to the manifest")
+	flagSet.BoolVar(&options.ExtractFrames, "extract-frames", false, "split animated GIF emotes into numbered PNG frames")
+	flagSet.BoolVar(&options.ZipPerEmote, "zip-per-emote", false, "also write <code>.zip with all downloaded sizes of each emote into the channel folder, for sticker and emoji upload tools")
+	flagSet.StringVar(&options.Largest, "largest", "", "also write <code>.<ext> for each emote from its largest downloaded size, as a "+strings.Join(largestModes, " or "))
+	flagSet.StringVar(&options.ConvertAnimated, "convert-animated", "", "also write animated GIF emotes as "+strings.Join(animationFormats, " or ")+" (webm needs ffmpeg)")
+	flagSet.StringVar(&options.Layout, "layout", layoutFolders, "output layout ("+strings.Join(layouts, ", ")+"); flat writes <code>_<size>.<ext> straight into the channel folder, cas stores images once under objects/<sha256> shared by all channels")
+	flagSet.BoolFunc("merge", "update an existing channel folder in place without asking", existingModeFlag(options, existingMerge))
+	flagSet.BoolFunc("overwrite", "replace an existing channel folder without asking", existingModeFlag(options, existingOverwrite))
+	flagSet.BoolFunc("flat", "shorthand for --layout flat", func(string) error {
+		options.Layout = layoutFlat
+		return nil
+	})
+	flagSet.StringVar(&options.ManifestFormat, "manifest-format", manifestFormatJSON, "manifest format ("+strings.Join(manifestFormats, ", ")+"); csv is written next to manifest.json")
+	flagSet.BoolVar(&options.NoCache, "no-cache", false, "do not cache channel pages and API responses")
+	flagSet.StringVar(&options.CacheDir, "cache-dir", defaultCacheDir(), "directory for cached channel pages and API responses")
+	flagSet.DurationVar(&options.CacheTTL, "cache-ttl", defaultCacheTTL, "how long responses without caching headers stay fresh")
+	flagSet.StringVar(&options.WARCFile, "warc", "", "append every HTTP request and response of the run to this WARC file (.warc.gz to compress)")
+	flagSet.StringVar(&options.LogFile, "log-file", "", "append the full log with timestamps to this file")
+	flagSet.StringVar(&options.ProgressMode, "progress", progressModeAuto, "progress output in text and batch mode ("+strings.Join(progressModes, ", ")+"); auto shows a status line when stderr is a terminal, plain logs [progress] percent=... lines for CI logs")
+	flagSet.Var(timestampsFlag{&options.Timestamps}, "timestamps", "prefix every log line with the local time, or with the time since start for --timestamps=relative, and log how long each emote took")
+	flagSet.BoolVar(&options.Notify, "notify", false, "show a desktop notification when downloads finish or fail")
+	flagSet.StringVar(&options.Theme, "theme", "", "TUI color theme ("+strings.Join(themeNames(), ", ")+"), defaults to the config file or "+defaultThemeName)
+	flagSet.BoolVar(&options.NoColor, "no-color", os.Getenv("NO_COLOR") != "", "disable colors in the TUI")
+	flagSet.StringVar(&options.Preview, "preview", previewModeAuto, "emote previews in the TUI ("+strings.Join(previewModes, ", ")+")")
+	return flagSet
+}
+
+func parseInterspersed(flagSet *flag.FlagSet, arguments []string) ([]string, error) {
+	positional := make([]string, 0, len(arguments))
+	for {
+		if err := flagSet.Parse(arguments); err != nil {
+			return nil, err
+		}
+		arguments = flagSet.Args()
+		if len(arguments) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, arguments[0])
+		arguments = arguments[1:]
+	}
+}
+
+func parseCommandLine(flagSet *flag.FlagSet, options *downloadOptions, arguments []string) ([]string, error) {
+	positional, err := parseInterspersed(flagSet, arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := lookupProvider(options.Provider); err != nil {
+		fmt.Fprintf(flagSet.Output(), "Error: %v\n", err)
+		return nil, err
+	}
+	if !slices.Contains(layouts, options.Layout) {
+		err := fmt.Errorf("unknown layout %q (available: %s)", options.Layout, strings.Join(layouts, ", "))
+		fmt.Fprintf(flagSet.Output(), "Error: %v\n", err)
+		return nil, err
+	}
+	if !slices.Contains(manifestFormats, options.ManifestFormat) {
+		err := fmt.Errorf("unknown manifest format %q (available: %s)", options.ManifestFormat, strings.Join(manifestFormats, ", "))
+		fmt.Fprintf(flagSet.Output(), "Error: %v\n", err)
+		return nil, err
+	}
+	if !slices.Contains(progressModes, options.ProgressMode) {
+		err := fmt.Errorf("unknown progress mode %q (available: %s)", options.ProgressMode, strings.Join(progressModes, ", "))
+		fmt.Fprintf(flagSet.Output(), "Error: %v\n", err)
+		return nil, err
+	}
+	if options.Checksums != "" && !slices.Contains(checksumAlgorithms, options.Checksums) {
+		err := fmt.Errorf("unknown checksum algorithm %q (available: %s)", options.Checksums, strings.Join(checksumAlgorithms, ", "))
+		fmt.Fprintf(flagSet.Output(), "Error: %v\n", err)
+		return nil, err
+	}
+	if options.Largest != "" && !slices.Contains(largestModes, options.Largest) {
+		err := fmt.Errorf("unknown largest mode %q (available: %s)", options.Largest, strings.Join(largestModes, ", "))
+		fmt.Fprintf(flagSet.Output(), "Error: %v\n", err)
+		return nil, err
+	}
+	if options.ConvertAnimated != "" && !slices.Contains(animationFormats, options.ConvertAnimated) {
+		err := fmt.Errorf("unknown animation format %q (available: %s)", options.ConvertAnimated, strings.Join(animationFormats, ", "))
+		fmt.Fprintf(flagSet.Output(), "Error: %v\n", err)
+		return nil, err
+	}
+	if options.Dest != "" && options.ConvertAnimated == animationFormatWebM {
+		err := errors.New("--convert-animated webm needs a local output folder and cannot be combined with --dest")
+		fmt.Fprintf(flagSet.Output(), "Error: %v\n", err)
+		return nil, err
+	}
+	store, err := openStorage(options.Dest, options.Durable)
+	if err != nil {
+		fmt.Fprintf(flagSet.Output(), "Error: %v\n", err)
+		return nil, err
+	}
+	options.Storage = store
+	if options.Dest != "" && (options.ExecAfterEmote != "" || options.ExecAfterChannel != "") {
+		err := errors.New("--exec-after-emote and --exec-after-channel need a local output folder and cannot be combined with --dest")
+		fmt.Fprintf(flagSet.Output(), "Error: %v\n", err)
+		return nil, err
+	}
+	if options.Check && options.DryRun {
+		err := errors.New("--check cannot be combined with --dry-run")
+		fmt.Fprintf(flagSet.Output(), "Error: %v\n", err)
+		return nil, err
+	}
+	if options.Pipeline && options.CheckSpace {
+		err := errors.New("--pipeline cannot be combined with --check-space")
+		fmt.Fprintf(flagSet.Output(), "Error: %v\n", err)
+		return nil, err
+	}
+	if options.Pipeline && options.Top > 0 {
+		err := errors.New("--pipeline cannot be combined with --top")
+		fmt.Fprintf(flagSet.Output(), "Error: %v\n", err)
+		return nil, err
+	}
+	if options.Offline != "" {
+		if options.RecordFixtures != "" {
+			err := errors.New("--offline cannot be combined with --record-fixtures")
+			fmt.Fprintf(flagSet.Output(), "Error: %v\n", err)
+			return nil, err
+		}
+		if info, err := os.Stat(options.Offline); err != nil || !info.IsDir() {
+			err := fmt.Errorf("--offline: %s is not a directory", options.Offline)
+			fmt.Fprintf(flagSet.Output(), "Error: %v\n", err)
+			return nil, err
+		}
+		options.NoCache = true
+		options.ScrapeDelay = 0
+	}
+	if options.ChannelConcurrency < 1 {
+		options.ChannelConcurrency = 1
+	}
+	if options.LogFile != "" {
+		logWriter, err := openLogFile(options.LogFile)
+		if err != nil {
+			fmt.Fprintf(flagSet.Output(), "Error: cannot open log file: %v\n", err)
+			return nil, err
+		}
+		options.Log = logWriter
+	}
+	if options.WARCFile != "" {
+		warc, err := openWARCFile(options.WARCFile)
+		if err != nil {
+			fmt.Fprintf(flagSet.Output(), "Error: cannot open WARC file: %v\n", err)
+			return nil, err
+		}
+		options.WARC = warc
+		options.NoCache = true
+	}
+	configureProviders(*options)
+
+	return positional, nil
+}
 
+func exitCodeForParseError(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	return 2
+}
+
+var subcommands = map[string]func(arguments []string) int{
+	"from-chat":    runFromChatCommand,
+	"emote":        runEmoteCommand,
+	"fav":          runFavoritesCommand,
+	"serve":        runServeCommand,
+	"watch":        runWatchCommand,
+	"doctor":       runDoctorCommand,
+	"completion":   runCompletionCommand,
+	"resolve":      runResolveCommand,
+	"export":       runExportCommand,
+	"verify":       runVerifyCommand,
+	"retry-failed": runRetryFailedCommand,
+	"diff":         runDiffCommand,
+	"bot":          runBotCommand,
+	"auth":         runAuthCommand,
+	"gif":          runGIFCommand,
+	"stats":        runStatsCommand,
+	"follows":      runFollowsCommand,
+	"top":          runTopCommand,
+	"search":       runSearchCommand,
+	"whois":        runWhoisCommand,
+}
+
+func main() {
+	loadProviderPlugins()
 	if len(os.Args) >= 2 {
-		channelIdentifier := strings.TrimSpace(os.Args[1])
+		if command, exists := subcommands[os.Args[1]]; exists {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
+
+	var options downloadOptions
+	flagSet := newCommandFlagSet("twe-dlp", &options)
+	positional, err := parseCommandLine(flagSet, &options, os.Args[1:])
+	if err != nil {
+		os.Exit(exitCodeForParseError(err))
+	}
+	if len(positional) == 0 && (!term.IsTerminal(os.Stdout.Fd()) || !term.IsTerminal(os.Stdin.Fd())) {
+		if term.IsTerminal(os.Stdin.Fd()) {
+			fmt.Fprintln(os.Stderr, "Standard output is not a terminal, reading channels from standard input (one per line, Ctrl+D to finish)")
+		}
+		positional = []string{"-"}
+		options.NoColor = true
+	}
+	channelsFromStdin := slices.Contains(positional, "-")
+	positional, err = expandStdinArguments(positional, os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if options.DryRun {
+		options.NoCache = true
+	}
+	httpClient := createHTTPClient(options)
+
+	if options.Check && len(positional) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: --check needs at least one channel")
+		os.Exit(2)
+	}
+	if options.DryRun && len(positional) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: --dry-run needs at least one channel")
+		os.Exit(2)
+	}
+	if len(positional) > 0 {
+		options.ConfirmExisting = confirmExistingArchive(options.ExistingMode, os.Stdin, os.Stdout, term.IsTerminal(os.Stdin.Fd()) && !channelsFromStdin)
+	}
+	if len(positional) > 1 {
+		os.Exit(runBatchMode(httpClient, positional, options))
+	}
+	if len(positional) == 1 {
+		channelIdentifier := strings.TrimSpace(positional[0])
 		if channelIdentifier == "" {
 			fmt.Fprintln(os.Stderr, "No channel identifier provided.")
 			os.Exit(1)
 		}
-		exitCode := runTextMode(httpClient, channelIdentifier)
+		exitCode := runTextMode(httpClient, channelIdentifier, options)
 		os.Exit(exitCode)
 	}
 
-	initialModel := newModel(httpClient)
-	if _, err := tea.NewProgram(initialModel).Run(); err != nil {
+	previewMode, err := resolvePreviewMode(options.Preview)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	config := loadConfig()
+	if options.Theme == "" {
+		options.Theme = config.Theme
+	}
+	options.Palette, err = resolveTheme(options.Theme, config.Palette)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if options.NoColor {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+
+	initialModel := newModel(httpClient, options, previewMode)
+	finalModel, err := tea.NewProgram(initialModel).Run()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
 		os.Exit(1)
 	}
+	if finalState, isModel := finalModel.(model); isModel {
+		if err := finalState.saveState(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: cannot save %s: %v\n", tuiStateFileName, err)
+		}
+	}
 }
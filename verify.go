@@ -0,0 +1,253 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	verifyStatusOK      = "ok"
+	verifyStatusMissing = "missing"
+	verifyStatusCorrupt = "corrupt"
+)
+
+type verifySummary struct {
+	Checked  int
+	OK       int
+	Missing  int
+	Corrupt  int
+	Repaired int
+	Extra    int
+}
+
+func newVerifyFlagSet(options *downloadOptions, repair *bool) *flag.FlagSet {
+	flagSet := newCommandFlagSet("verify", options)
+	flagSet.BoolVar(repair, "repair", false, "re-download missing and corrupt files and update the manifest")
+	return flagSet
+}
+
+func checkManifestFile(channelRoot string, file manifestFile) (string, string) {
+	filePath := filepath.Join(channelRoot, filepath.FromSlash(file.Path))
+	info, err := os.Stat(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return verifyStatusMissing, ""
+	}
+	if err != nil {
+		return verifyStatusMissing, err.Error()
+	}
+	if info.Size() != file.Bytes {
+		return verifyStatusCorrupt, fmt.Sprintf("%d bytes, expected %d", info.Size(), file.Bytes)
+	}
+	if file.SHA256 == "" {
+		return verifyStatusOK, ""
+	}
+	digest, err := hashFile(filePath)
+	if err != nil {
+		return verifyStatusCorrupt, err.Error()
+	}
+	if digest != file.SHA256 {
+		return verifyStatusCorrupt, "sha256 mismatch"
+	}
+	return verifyStatusOK, ""
+}
+
+func repairManifestFile(httpClient *http.Client, channelRoot string, file *manifestFile, durable bool) error {
+	sourceURL := file.SourceURL
+	if sourceURL == "" {
+		sourceURL = file.URL
+	}
+	response, _, err := requestImage(httpClient, []imageVariant{{URL: sourceURL}}, manifestFile{}, false, 0, "")
+	if err != nil {
+		return err
+	}
+	imageBytes, err := readImage(response, nil)
+	if err != nil {
+		return err
+	}
+
+	digest := sha256Hex(imageBytes)
+	if isObjectPath(file.Path, file.SHA256) && digest != file.SHA256 {
+		file.Path = path.Join(path.Dir(file.Path), objectFileName(digest, strings.TrimPrefix(path.Ext(file.Path), ".")))
+	}
+	if err := (localStorage{Durable: durable}).WriteFile(filepath.Join(channelRoot, filepath.FromSlash(file.Path)), imageBytes); err != nil {
+		return err
+	}
+	file.Bytes = int64(len(imageBytes))
+	file.SHA256 = digest
+	file.ETag = response.Header.Get("ETag")
+	file.LastModified = response.Header.Get("Last-Modified")
+	return nil
+}
+
+func findExtraFiles(channelRoot string, manifest *channelManifest) []string {
+	known := make(map[string]bool)
+	var framePrefixes []string
+	folders := make(map[string]bool)
+	var flatPrefixes []string
+	for _, emote := range manifest.Emotes {
+		if emote.Folder != "" && len(emote.Files) > 0 && path.Dir(emote.Files[0].Path) == "." {
+			flatPrefixes = append(flatPrefixes, emote.Folder+"_", emote.Folder+".")
+		} else if emote.Folder != "" {
+			folders[emote.Folder] = true
+		}
+		known[emote.Largest] = true
+		known[emote.Zip] = true
+		for _, file := range emote.Files {
+			known[file.Path] = true
+			known[file.Converted] = true
+			known[file.Normalized] = true
+			if strings.HasSuffix(file.Path, ".gif") {
+				framePrefixes = append(framePrefixes, strings.TrimSuffix(file.Path, ".gif")+"_frames/")
+			}
+		}
+	}
+
+	var extras []string
+	addExtra := func(relativePath string) {
+		if known[relativePath] || strings.HasSuffix(relativePath, ".part") || strings.HasSuffix(relativePath, compressedGIFSuffix) {
+			return
+		}
+		for _, prefix := range framePrefixes {
+			if strings.HasPrefix(relativePath, prefix) {
+				return
+			}
+		}
+		extras = append(extras, relativePath)
+	}
+	for folder := range folders {
+		filepath.WalkDir(filepath.Join(channelRoot, folder), func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return nil
+			}
+			if relativePath, err := filepath.Rel(channelRoot, filePath); err == nil {
+				addExtra(filepath.ToSlash(relativePath))
+			}
+			return nil
+		})
+	}
+	// The flat layout shares the channel folder with the manifest, reports and
+	// other channel files, so only names starting with an emote's code count.
+	if len(flatPrefixes) > 0 {
+		entries, _ := os.ReadDir(channelRoot)
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			for _, prefix := range flatPrefixes {
+				if strings.HasPrefix(entry.Name(), prefix) {
+					addExtra(entry.Name())
+					break
+				}
+			}
+		}
+	}
+	sort.Strings(extras)
+	return extras
+}
+
+func verifyChannel(httpClient *http.Client, channelRoot string, manifest *channelManifest, durable bool) (verifySummary, bool) {
+	var summary verifySummary
+	changed := false
+	for emoteIndex := range manifest.Emotes {
+		emote := &manifest.Emotes[emoteIndex]
+		for fileIndex := range emote.Files {
+			file := &emote.Files[fileIndex]
+			summary.Checked++
+			status, detail := checkManifestFile(channelRoot, *file)
+			if status == verifyStatusOK {
+				summary.OK++
+				continue
+			}
+
+			line := fmt.Sprintf("[%s] %s", status, file.Path)
+			if detail != "" {
+				line += " (" + detail + ")"
+			}
+			fmt.Println(line)
+			if httpClient != nil {
+				if err := repairManifestFile(httpClient, channelRoot, file, durable); err != nil {
+					fmt.Printf("[error] cannot repair %s: %v\n", file.Path, err)
+				} else {
+					fmt.Printf("[repaired] %s\n", file.Path)
+					summary.Repaired++
+					changed = true
+					continue
+				}
+			}
+			if status == verifyStatusMissing {
+				summary.Missing++
+			} else {
+				summary.Corrupt++
+			}
+		}
+	}
+
+	for _, extra := range findExtraFiles(channelRoot, manifest) {
+		fmt.Printf("[extra] %s\n", extra)
+		summary.Extra++
+	}
+	return summary, changed
+}
+
+func runVerifyCommand(arguments []string) int {
+	var options downloadOptions
+	var repair bool
+
+	flagSet := newVerifyFlagSet(&options, &repair)
+	positional, err := parseCommandLine(flagSet, &options, arguments)
+	if err != nil {
+		return exitCodeForParseError(err)
+	}
+	if len(positional) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: twe-dlp verify [--repair] <channel folder>...")
+		return 2
+	}
+	var httpClient *http.Client
+	if repair {
+		httpClient = createHTTPClient(options)
+	}
+
+	exitCode := 0
+	for _, channelRoot := range positional {
+		if _, err := os.Stat(filepath.Join(channelRoot, manifestFileName)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s has no %s; download the channel first\n", channelRoot, manifestFileName)
+			exitCode = 1
+			continue
+		}
+		manifest, err := loadManifest(localStorage{}, channelRoot)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exitCode = 1
+			continue
+		}
+
+		if len(positional) > 1 {
+			fmt.Printf("Verifying %s\n", channelRoot)
+		}
+		summary, changed := verifyChannel(httpClient, channelRoot, manifest, options.Durable)
+		if changed {
+			if err := saveManifest(localStorage{Durable: options.Durable}, channelRoot, manifest); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: cannot write manifest: %v\n", err)
+				exitCode = 1
+			}
+		}
+
+		result := fmt.Sprintf("Checked %d files: %d ok, %d missing, %d corrupt, %d extra", summary.Checked, summary.OK, summary.Missing, summary.Corrupt, summary.Extra)
+		if repair {
+			result += fmt.Sprintf(", %d repaired", summary.Repaired)
+		}
+		fmt.Println(result)
+		if summary.Missing > 0 || summary.Corrupt > 0 {
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var warcRedactedHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization"}
+
+type warcWriter struct {
+	lock       sync.Mutex
+	file       *os.File
+	compressed bool
+}
+
+type warcTransport struct {
+	base   http.RoundTripper
+	writer *warcWriter
+}
+
+func openWARCFile(path string) (*warcWriter, error) {
+	if directory := filepath.Dir(path); directory != "." {
+		if err := os.MkdirAll(directory, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	writer := &warcWriter{file: file, compressed: strings.HasSuffix(strings.ToLower(path), ".gz")}
+	info := fmt.Sprintf("software: twe-dlp\r\nformat: WARC File Format 1.1\r\ncommand: %s\r\n", strings.Join(os.Args, " "))
+	if err := writer.writeRecord("warcinfo", "", "application/warc-fields", []byte(info), map[string]string{"WARC-Filename": filepath.Base(path)}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return writer, nil
+}
+
+func newWARCRecordID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+}
+
+func warcDigest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+func (writer *warcWriter) writeRecord(recordType string, targetURI string, contentType string, block []byte, extraHeaders map[string]string) error {
+	var record bytes.Buffer
+	record.WriteString("WARC/1.1\r\n")
+	fmt.Fprintf(&record, "WARC-Type: %s\r\n", recordType)
+	recordID := extraHeaders["WARC-Record-ID"]
+	if recordID == "" {
+		recordID = newWARCRecordID()
+	}
+	fmt.Fprintf(&record, "WARC-Record-ID: %s\r\n", recordID)
+	fmt.Fprintf(&record, "WARC-Date: %s\r\n", time.Now().UTC().Format(time.RFC3339))
+	if targetURI != "" {
+		fmt.Fprintf(&record, "WARC-Target-URI: %s\r\n", targetURI)
+	}
+	for name, value := range extraHeaders {
+		if name != "WARC-Record-ID" {
+			fmt.Fprintf(&record, "%s: %s\r\n", name, value)
+		}
+	}
+	fmt.Fprintf(&record, "WARC-Block-Digest: %s\r\n", warcDigest(block))
+	fmt.Fprintf(&record, "Content-Type: %s\r\n", contentType)
+	fmt.Fprintf(&record, "Content-Length: %d\r\n\r\n", len(block))
+	record.Write(block)
+	record.WriteString("\r\n\r\n")
+
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+	if !writer.compressed {
+		_, err := writer.file.Write(record.Bytes())
+		return err
+	}
+	compressor := gzip.NewWriter(writer.file)
+	if _, err := compressor.Write(record.Bytes()); err != nil {
+		return err
+	}
+	return compressor.Close()
+}
+
+func warcRequestBlock(request *http.Request, requestBody []byte) []byte {
+	var block bytes.Buffer
+	fmt.Fprintf(&block, "%s %s HTTP/1.1\r\n", request.Method, request.URL.RequestURI())
+	fmt.Fprintf(&block, "Host: %s\r\n", request.URL.Host)
+	header := request.Header.Clone()
+	for _, name := range warcRedactedHeaders {
+		header.Del(name)
+	}
+	header.Write(&block)
+	block.WriteString("\r\n")
+	block.Write(requestBody)
+	return block.Bytes()
+}
+
+func warcResponseBlock(response *http.Response, responseBody []byte) []byte {
+	var block bytes.Buffer
+	fmt.Fprintf(&block, "HTTP/%d.%d %s\r\n", response.ProtoMajor, response.ProtoMinor, response.Status)
+	header := response.Header.Clone()
+	header.Del("Transfer-Encoding")
+	if response.Uncompressed {
+		header.Del("Content-Encoding")
+	}
+	header.Set("Content-Length", fmt.Sprint(len(responseBody)))
+	header.Write(&block)
+	block.WriteString("\r\n")
+	block.Write(responseBody)
+	return block.Bytes()
+}
+
+func (transport *warcTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if request.Body != nil && request.Body != http.NoBody {
+		var err error
+		if requestBody, err = io.ReadAll(request.Body); err != nil {
+			return nil, err
+		}
+		request.Body.Close()
+		request = request.Clone(request.Context())
+		request.Body = io.NopCloser(bytes.NewReader(requestBody))
+	}
+	response, err := transport.base.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	response.Body = io.NopCloser(bytes.NewReader(responseBody))
+	if err != nil {
+		return response, nil
+	}
+
+	targetURI := request.URL.String()
+	responseID := newWARCRecordID()
+	err = transport.writer.writeRecord("response", targetURI, "application/http;msgtype=response", warcResponseBlock(response, responseBody), map[string]string{
+		"WARC-Record-ID":      responseID,
+		"WARC-Payload-Digest": warcDigest(responseBody),
+	})
+	if err == nil {
+		err = transport.writer.writeRecord("request", targetURI, "application/http;msgtype=request", warcRequestBlock(request, requestBody), map[string]string{
+			"WARC-Concurrent-To": responseID,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("writing WARC record: %w", err)
+	}
+	return response, nil
+}
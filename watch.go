@@ -0,0 +1,548 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWatchInterval  = 6 * time.Hour
+	webhookFormatJSON     = "json"
+	webhookFormatDiscord  = "discord"
+	discordEmbedsPerPost  = 10
+	webhookRequestTimeout = 15 * time.Second
+	watchStatusFileName   = "watch-status.json"
+	watchResultOK         = "ok"
+	watchResultFailed     = "failed"
+)
+
+type watchOptions struct {
+	Interval      time.Duration
+	Schedule      string
+	Jitter        time.Duration
+	WebhookURL    string
+	WebhookFormat string
+	SinkURLs      []string
+	Sinks         []notificationSink
+	Listen        string
+	ShowStatus    bool
+}
+
+type watchTarget struct {
+	Channel   string `json:"channel"`
+	Provider  string `json:"provider,omitempty"`
+	OutputDir string `json:"output_dir,omitempty"`
+}
+
+type watchChannelStatus struct {
+	Channel    string    `json:"channel"`
+	Provider   string    `json:"provider,omitempty"`
+	OutputDir  string    `json:"output_dir,omitempty"`
+	Folder     string    `json:"folder,omitempty"`
+	Result     string    `json:"result"`
+	Error      string    `json:"error,omitempty"`
+	LastSync   time.Time `json:"last_sync"`
+	NewEmotes  []string  `json:"new_emotes,omitempty"`
+	LastChange time.Time `json:"last_change,omitzero"`
+}
+
+type watchStatus struct {
+	NextSync time.Time            `json:"next_sync,omitzero"`
+	Channels []watchChannelStatus `json:"channels"`
+}
+
+type watchState struct {
+	lock   sync.Mutex
+	status watchStatus
+}
+
+type syncResult struct {
+	Provider    string
+	ChannelID   string
+	ChannelName string
+	OutputRoot  string
+	NewEmotes   []manifestEmote
+	FirstSync   bool
+}
+
+type webhookEmote struct {
+	ID       string `json:"id"`
+	Code     string `json:"code"`
+	ImageURL string `json:"image_url,omitempty"`
+}
+
+type webhookPayload struct {
+	Provider    string         `json:"provider"`
+	ChannelID   string         `json:"channel_id"`
+	ChannelName string         `json:"channel_name,omitempty"`
+	DetectedAt  time.Time      `json:"detected_at"`
+	NewEmotes   []webhookEmote `json:"new_emotes"`
+}
+
+type discordEmbed struct {
+	Title     string `json:"title"`
+	Thumbnail struct {
+		URL string `json:"url"`
+	} `json:"thumbnail"`
+}
+
+type discordMessage struct {
+	Username string         `json:"username"`
+	Content  string         `json:"content,omitempty"`
+	Embeds   []discordEmbed `json:"embeds"`
+}
+
+func syncChannel(httpClient *http.Client, channelIdentifier string, options downloadOptions, logFunc func(string)) (*syncResult, error) {
+	provider, providerIdentifier, err := selectProvider(channelIdentifier, options.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	channelClient := throttledClient(rateLimitedClient(httpClient, options.ChannelRateLimit), logFunc)
+	channelID, err := provider.ResolveChannelID(channelClient, providerIdentifier)
+	if err != nil {
+		appMetrics.recordChannel(provider.Name(), err)
+		return nil, fmt.Errorf("resolving channel: %w", err)
+	}
+	channel, err := provider.FetchChannel(channelClient, channelID)
+	if err != nil {
+		appMetrics.recordChannel(provider.Name(), err)
+		return nil, err
+	}
+
+	outputRoot := channelOutputRoot(provider, channel, options)
+	previousManifest, err := loadManifest(outputStorage(options), outputRoot)
+	if err != nil {
+		previousManifest = &channelManifest{}
+	}
+	err = downloadChannelData(channelClient, provider, channel, options, logFunc)
+	appMetrics.recordChannel(provider.Name(), err)
+	if err != nil {
+		return nil, err
+	}
+	currentManifest, err := loadManifest(outputStorage(options), outputRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &syncResult{
+		Provider:    provider.Name(),
+		ChannelID:   channel.ID,
+		ChannelName: channel.DisplayName,
+		OutputRoot:  outputRoot,
+		FirstSync:   len(previousManifest.Emotes) == 0,
+	}
+	for _, emote := range diffManifests(previousManifest, currentManifest).Added {
+		if len(emote.Files) > 0 {
+			result.NewEmotes = append(result.NewEmotes, emote)
+		}
+	}
+	return result, nil
+}
+
+func buildWebhookPayload(result *syncResult) webhookPayload {
+	payload := webhookPayload{
+		Provider:    result.Provider,
+		ChannelID:   result.ChannelID,
+		ChannelName: result.ChannelName,
+		DetectedAt:  time.Now().UTC(),
+		NewEmotes:   make([]webhookEmote, 0, len(result.NewEmotes)),
+	}
+	for _, emote := range result.NewEmotes {
+		payload.NewEmotes = append(payload.NewEmotes, newWebhookEmote(emote))
+	}
+	return payload
+}
+
+func newWebhookEmote(emote manifestEmote) webhookEmote {
+	imageURL := ""
+	if len(emote.Files) > 0 {
+		largestFile := emote.Files[len(emote.Files)-1]
+		imageURL = largestFile.URL
+		if largestFile.SourceURL != "" {
+			imageURL = largestFile.SourceURL
+		}
+	}
+	return webhookEmote{ID: emote.ID, Code: emote.Code, ImageURL: imageURL}
+}
+
+func discordMessages(payload webhookPayload) []discordMessage {
+	messages := make([]discordMessage, 0, len(payload.NewEmotes)/discordEmbedsPerPost+1)
+	for start := 0; start < len(payload.NewEmotes); start += discordEmbedsPerPost {
+		message := discordMessage{Username: "twe-dlp"}
+		if start == 0 {
+			message.Content = notificationSummary(payload)
+		}
+		for _, emote := range payload.NewEmotes[start:min(start+discordEmbedsPerPost, len(payload.NewEmotes))] {
+			embed := discordEmbed{Title: emote.Code}
+			embed.Thumbnail.URL = emote.ImageURL
+			message.Embeds = append(message.Embeds, embed)
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+func postWebhook(webhookURL string, format string, result *syncResult) error {
+	payload := buildWebhookPayload(result)
+	bodies := []any{payload}
+	if format == webhookFormatDiscord {
+		bodies = bodies[:0]
+		for _, message := range discordMessages(payload) {
+			bodies = append(bodies, message)
+		}
+	}
+
+	webhookClient := &http.Client{Timeout: webhookRequestTimeout}
+	for _, body := range bodies {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		response, err := webhookClient.Post(webhookURL, "application/json", bytes.NewReader(bodyBytes))
+		if err != nil {
+			return err
+		}
+		response.Body.Close()
+		if response.StatusCode < 200 || response.StatusCode > 299 {
+			return fmt.Errorf("webhook returned status %s", response.Status)
+		}
+	}
+	return nil
+}
+
+func (target watchTarget) label() string {
+	if target.Provider != "" {
+		return target.Provider + ":" + target.Channel
+	}
+	return target.Channel
+}
+
+func (target watchTarget) options(options downloadOptions) downloadOptions {
+	if target.Provider != "" {
+		options.Provider = target.Provider
+	}
+	if target.OutputDir != "" {
+		options.OutputDir = target.OutputDir
+	}
+	return options
+}
+
+func newWatchState(targets []watchTarget, options downloadOptions) *watchState {
+	state := &watchState{}
+	if previous, err := loadWatchStatus(); err == nil {
+		state.status = *previous
+	}
+
+	channels := make([]watchChannelStatus, 0, len(targets))
+	for _, target := range targets {
+		targetOptions := target.options(options)
+		channelStatus := watchChannelStatus{Channel: target.Channel, Provider: targetOptions.Provider, OutputDir: targetOptions.OutputDir}
+		for _, previous := range state.status.Channels {
+			if previous.Channel == channelStatus.Channel && previous.Provider == channelStatus.Provider && previous.OutputDir == channelStatus.OutputDir {
+				channelStatus = previous
+				break
+			}
+		}
+		channels = append(channels, channelStatus)
+	}
+	state.status.Channels = channels
+	return state
+}
+
+func (state *watchState) update(index int, result *syncResult, err error) {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	channelStatus := &state.status.Channels[index]
+	channelStatus.LastSync = time.Now().UTC()
+	if err != nil {
+		channelStatus.Result = watchResultFailed
+		channelStatus.Error = err.Error()
+		return
+	}
+	channelStatus.Result = watchResultOK
+	channelStatus.Error = ""
+	channelStatus.Folder = result.OutputRoot
+	if !result.FirstSync && len(result.NewEmotes) > 0 {
+		channelStatus.NewEmotes = channelStatus.NewEmotes[:0]
+		for _, emote := range result.NewEmotes {
+			channelStatus.NewEmotes = append(channelStatus.NewEmotes, emote.Code)
+		}
+		channelStatus.LastChange = channelStatus.LastSync
+	}
+}
+
+func (state *watchState) setNextSync(nextSync time.Time) {
+	state.lock.Lock()
+	state.status.NextSync = nextSync.UTC()
+	state.lock.Unlock()
+}
+
+func (state *watchState) snapshot() watchStatus {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	return watchStatus{
+		NextSync: state.status.NextSync,
+		Channels: append([]watchChannelStatus(nil), state.status.Channels...),
+	}
+}
+
+func (state *watchState) save() error {
+	directory, err := configDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return err
+	}
+	statusBytes, err := json.MarshalIndent(state.snapshot(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(directory, watchStatusFileName), statusBytes, false)
+}
+
+func (state *watchState) handleStatus(writer http.ResponseWriter, request *http.Request) {
+	writeJSON(writer, http.StatusOK, state.snapshot())
+}
+
+func (state *watchState) channelRoots() []string {
+	var channelRoots []string
+	for _, channelStatus := range state.snapshot().Channels {
+		if channelStatus.Folder != "" {
+			channelRoots = append(channelRoots, channelStatus.Folder)
+		}
+	}
+	return channelRoots
+}
+
+func loadWatchStatus() (*watchStatus, error) {
+	directory, err := configDir()
+	if err != nil {
+		return nil, err
+	}
+	statusBytes, err := os.ReadFile(filepath.Join(directory, watchStatusFileName))
+	if err != nil {
+		return nil, err
+	}
+	var status watchStatus
+	if err := json.Unmarshal(statusBytes, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func printWatchStatus(status *watchStatus) {
+	for _, channelStatus := range status.Channels {
+		label := watchTarget{Channel: channelStatus.Channel, Provider: channelStatus.Provider}.label()
+		switch {
+		case channelStatus.LastSync.IsZero():
+			fmt.Printf("%s: not synced yet\n", label)
+		case channelStatus.Result == watchResultFailed:
+			fmt.Printf("%s: failed at %s: %s\n", label, channelStatus.LastSync.Local().Format(time.DateTime), channelStatus.Error)
+		default:
+			fmt.Printf("%s: synced at %s into %s\n", label, channelStatus.LastSync.Local().Format(time.DateTime), channelStatus.OutputDir)
+		}
+		if len(channelStatus.NewEmotes) > 0 {
+			fmt.Printf("    last new emotes (%s): %s\n", channelStatus.LastChange.Local().Format(time.DateTime), strings.Join(channelStatus.NewEmotes, ", "))
+		}
+	}
+	if !status.NextSync.IsZero() {
+		fmt.Printf("Next sync at %s\n", status.NextSync.Local().Format(time.DateTime))
+	}
+}
+
+func runWatchCycle(httpClient *http.Client, targets []watchTarget, options downloadOptions, watch watchOptions, state *watchState) {
+	var outputLock sync.Mutex
+	slots := make(chan struct{}, options.ChannelConcurrency)
+	var workers sync.WaitGroup
+
+	for index, target := range targets {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+
+			logFunc := func(line string) {
+				outputLock.Lock()
+				fmt.Printf("%s[%s] %s\n", logTimestamp(options.Timestamps), target.label(), line)
+				outputLock.Unlock()
+				options.Log.write(fmt.Sprintf("[%s] %s", target.label(), line))
+			}
+			if watch.Jitter > 0 {
+				delay := rand.N(watch.Jitter)
+				logFunc(fmt.Sprintf("Starting in %s", delay.Round(time.Second)))
+				time.Sleep(delay)
+			}
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			result, err := syncChannel(httpClient, target.Channel, target.options(options), logFunc)
+			state.update(index, result, err)
+			if err != nil {
+				logFunc(fmt.Sprintf("Error: %v", err))
+				return
+			}
+			switch {
+			case result.FirstSync:
+				logFunc(fmt.Sprintf("Initial sync finished with %d emotes", len(result.NewEmotes)))
+			case len(result.NewEmotes) == 0:
+				logFunc("No new emotes")
+			default:
+				codes := make([]string, 0, len(result.NewEmotes))
+				for _, emote := range result.NewEmotes {
+					codes = append(codes, emote.Code)
+				}
+				logFunc(fmt.Sprintf("New emotes: %s", strings.Join(codes, ", ")))
+				for _, sink := range watch.Sinks {
+					if err := sink.Notify(result); err != nil {
+						logFunc(fmt.Sprintf("[error] %s: %v", sink, err))
+					}
+				}
+			}
+		}()
+	}
+	workers.Wait()
+}
+
+func newWatchFlagSet(options *downloadOptions, watch *watchOptions) *flag.FlagSet {
+	flagSet := newCommandFlagSet("watch", options)
+	flagSet.DurationVar(&watch.Interval, "interval", defaultWatchInterval, "time between syncs")
+	flagSet.StringVar(&watch.Schedule, "schedule", "", "cron expression for syncs, e.g. \"0 */6 * * *\" (overrides --interval)")
+	flagSet.DurationVar(&watch.Jitter, "jitter", 0, "random delay of up to this long before each channel sync")
+	flagSet.StringVar(&watch.WebhookURL, "webhook", "", "URL to POST to when new emotes are found")
+	flagSet.StringVar(&watch.WebhookFormat, "webhook-format", webhookFormatJSON, "webhook payload format ("+webhookFormatJSON+", "+webhookFormatDiscord+")")
+	flagSet.Func("sink", "also report new emotes to this Matrix room (matrix://TOKEN@host/!room:host), IRC channel (irc[s]://nick@host/channel) or webhook URL (repeatable)", func(value string) error {
+		watch.SinkURLs = append(watch.SinkURLs, value)
+		return nil
+	})
+	flagSet.StringVar(&watch.Listen, "listen", "", "address to serve /status, /metrics and /feeds on, e.g. :9090")
+	flagSet.StringVar(&watch.Listen, "metrics-listen", "", "alias for --listen")
+	flagSet.BoolVar(&watch.ShowStatus, "status", false, "print the last sync results of the running watcher and exit")
+	return flagSet
+}
+
+func runWatchCommand(arguments []string) int {
+	var options downloadOptions
+	var watch watchOptions
+
+	flagSet := newWatchFlagSet(&options, &watch)
+	positional, err := parseCommandLine(flagSet, &options, arguments)
+	if err != nil {
+		return exitCodeForParseError(err)
+	}
+	if watch.ShowStatus {
+		status, err := loadWatchStatus()
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Println("No watch status recorded yet")
+			return 0
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading watch status: %v\n", err)
+			return 1
+		}
+		printWatchStatus(status)
+		return 0
+	}
+
+	targets := make([]watchTarget, 0, len(positional))
+	for _, channelIdentifier := range positional {
+		if channelIdentifier = strings.TrimSpace(channelIdentifier); channelIdentifier != "" {
+			targets = append(targets, watchTarget{Channel: channelIdentifier})
+		}
+	}
+	if len(targets) == 0 {
+		targets = loadConfig().Watch
+	}
+	if len(targets) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: twe-dlp watch [--interval <duration> | --schedule <cron>] [--webhook <url>] <channel>...")
+		fmt.Fprintln(os.Stderr, "Channels can also be listed under \"watch\" in config.json.")
+		return 2
+	}
+	for _, target := range targets {
+		if strings.TrimSpace(target.Channel) == "" {
+			fmt.Fprintln(os.Stderr, "Error: watch entries in config.json need a channel")
+			return 2
+		}
+		if target.Provider != "" {
+			if _, err := lookupProvider(target.Provider); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s: %v\n", target.Channel, err)
+				return 2
+			}
+		}
+	}
+	if watch.WebhookFormat != webhookFormatJSON && watch.WebhookFormat != webhookFormatDiscord {
+		fmt.Fprintf(os.Stderr, "Error: unknown webhook format %q (available: %s, %s)\n", watch.WebhookFormat, webhookFormatJSON, webhookFormatDiscord)
+		return 2
+	}
+	if watch.WebhookURL != "" {
+		watch.Sinks = append(watch.Sinks, webhookSink{url: watch.WebhookURL, format: watch.WebhookFormat})
+	}
+	for _, sinkURL := range watch.SinkURLs {
+		sink, err := parseNotificationSink(sinkURL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		watch.Sinks = append(watch.Sinks, sink)
+	}
+	if watch.Interval <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --interval must be positive")
+		return 2
+	}
+	if watch.Jitter < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --jitter must not be negative")
+		return 2
+	}
+	var schedule *cronSchedule
+	if watch.Schedule != "" {
+		if schedule, err = parseCronSchedule(watch.Schedule); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		if _, err := schedule.next(time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+	}
+	options.RecordChanges = true
+	httpClient := createHTTPClient(options)
+	state := newWatchState(targets, options)
+
+	if watch.Listen != "" {
+		statusMux := http.NewServeMux()
+		statusMux.HandleFunc("GET /status", state.handleStatus)
+		statusMux.HandleFunc("GET /metrics", handleMetrics)
+		listFeeds, serveFeed := feedHandlers(outputStorage(options), state.channelRoots)
+		statusMux.HandleFunc("GET /feeds", listFeeds)
+		statusMux.HandleFunc("GET /feeds/{name}", serveFeed)
+		go func() {
+			if err := http.ListenAndServe(watch.Listen, statusMux); err != nil {
+				fmt.Fprintf(os.Stderr, "Error serving status: %v\n", err)
+			}
+		}()
+	}
+
+	for {
+		runWatchCycle(httpClient, targets, options, watch, state)
+		nextSync := time.Now().Add(watch.Interval)
+		if schedule != nil {
+			nextSync, _ = schedule.next(time.Now())
+		}
+		state.setNextSync(nextSync)
+		if err := state.save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: cannot save watch status: %v\n", err)
+		}
+		fmt.Printf("Next sync at %s\n", nextSync.Format(time.DateTime))
+		time.Sleep(time.Until(nextSync))
+	}
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	sevenTVAPIURL = "https://7tv.io/v3"
+	bttvAPIURL    = "https://api.betterttv.net/3"
+
+	sevenTVEmoteIDPattern = regexp.MustCompile(`^(?:[0-9a-f]{24}|[0-9A-HJKMNP-TV-Z]{26})$`)
+	emoteCDNURLPattern    = regexp.MustCompile(`(static-cdn\.jtvnw\.net/emoticons/v[12]|cdn\.7tv\.app/emote|cdn\.betterttv\.net/emote|cdn\.frankerfacez\.com/emote)/([^/?#]+)`)
+)
+
+type emoteOwner struct {
+	Source     string `json:"source"`
+	EmoteID    string `json:"emote_id"`
+	Code       string `json:"code,omitempty"`
+	OwnerID    string `json:"owner_id,omitempty"`
+	OwnerLogin string `json:"owner_login,omitempty"`
+	OwnerName  string `json:"owner_name,omitempty"`
+	SetID      string `json:"set_id,omitempty"`
+	EmoteType  string `json:"type,omitempty"`
+	TwitchID   string `json:"twitch_id,omitempty"`
+}
+
+func parseEmoteReference(input string) (string, string) {
+	input = strings.TrimSpace(input)
+	if match := emoteCDNURLPattern.FindStringSubmatch(input); len(match) == 3 {
+		switch {
+		case strings.HasPrefix(match[1], "static-cdn"):
+			return "twitch", match[2]
+		case strings.HasPrefix(match[1], "cdn.7tv"):
+			return "7tv", match[2]
+		case strings.HasPrefix(match[1], "cdn.betterttv"):
+			return "bttv", match[2]
+		default:
+			return "ffz", match[2]
+		}
+	}
+	if prefix, remainder, hasPrefix := strings.Cut(input, ":"); hasPrefix && !strings.Contains(remainder, "/") {
+		return strings.ToLower(prefix), remainder
+	}
+	return "", input
+}
+
+func lookupTwitchEmoteOwner(httpClient *http.Client, emoteID string) (*emoteOwner, error) {
+	requestBody, err := json.Marshal(map[string]any{
+		"query":     "query($id: ID!) { emote(id: $id) { id token setID type owner { id login displayName } } }",
+		"variables": map[string]string{"id": emoteID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest("POST", twitchGQLURL, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Client-ID", twitchGQLClientID)
+	request.Header.Set("Content-Type", "application/json")
+
+	var result struct {
+		Data struct {
+			Emote *struct {
+				ID    string `json:"id"`
+				Token string `json:"token"`
+				SetID string `json:"setID"`
+				Type  string `json:"type"`
+				Owner *struct {
+					ID          string `json:"id"`
+					Login       string `json:"login"`
+					DisplayName string `json:"displayName"`
+				} `json:"owner"`
+			} `json:"emote"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := getSearchJSON(httpClient, request, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Errors) > 0 {
+		return nil, errors.New(result.Errors[0].Message)
+	}
+	if result.Data.Emote == nil {
+		return nil, fmt.Errorf("no Twitch emote with ID %s", emoteID)
+	}
+	emote := result.Data.Emote
+	owner := &emoteOwner{Source: "twitch", EmoteID: emote.ID, Code: emote.Token, SetID: emote.SetID, EmoteType: strings.ToLower(emote.Type)}
+	if emote.Owner != nil {
+		owner.OwnerID, owner.OwnerLogin, owner.OwnerName = emote.Owner.ID, emote.Owner.Login, emote.Owner.DisplayName
+	}
+	return owner, nil
+}
+
+func lookupSevenTVEmoteOwner(httpClient *http.Client, emoteID string) (*emoteOwner, error) {
+	var emote struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Owner struct {
+			ID          string `json:"id"`
+			Username    string `json:"username"`
+			DisplayName string `json:"display_name"`
+		} `json:"owner"`
+	}
+	if err := fetchJSON(httpClient, sevenTVAPIURL+"/emotes/"+url.PathEscape(emoteID), &emote); err != nil {
+		return nil, err
+	}
+	owner := &emoteOwner{Source: "7tv", EmoteID: emote.ID, Code: emote.Name, OwnerID: emote.Owner.ID, OwnerLogin: emote.Owner.Username, OwnerName: emote.Owner.DisplayName}
+	if owner.OwnerID == "" {
+		return owner, nil
+	}
+
+	var user struct {
+		Connections []struct {
+			Platform   string `json:"platform"`
+			EmoteSetID string `json:"emote_set_id"`
+		} `json:"connections"`
+	}
+	if err := fetchJSON(httpClient, sevenTVAPIURL+"/users/"+url.PathEscape(owner.OwnerID), &user); err == nil {
+		for _, connection := range user.Connections {
+			if connection.EmoteSetID != "" {
+				owner.SetID = connection.EmoteSetID
+				if strings.EqualFold(connection.Platform, "twitch") {
+					break
+				}
+			}
+		}
+	}
+	return owner, nil
+}
+
+func lookupBTTVEmoteOwner(httpClient *http.Client, emoteID string) (*emoteOwner, error) {
+	var emote struct {
+		ID   string `json:"id"`
+		Code string `json:"code"`
+		User struct {
+			ID          string `json:"id"`
+			Name        string `json:"name"`
+			DisplayName string `json:"displayName"`
+			ProviderID  string `json:"providerId"`
+		} `json:"user"`
+	}
+	if err := fetchJSON(httpClient, bttvAPIURL+"/emotes/"+url.PathEscape(emoteID), &emote); err != nil {
+		return nil, err
+	}
+	return &emoteOwner{Source: "bttv", EmoteID: emote.ID, Code: emote.Code, OwnerID: emote.User.ID, OwnerLogin: emote.User.Name, OwnerName: emote.User.DisplayName, TwitchID: emote.User.ProviderID}, nil
+}
+
+func lookupEmoteOwner(httpClient *http.Client, source string, emoteID string) (*emoteOwner, error) {
+	switch source {
+	case "twitch":
+		return lookupTwitchEmoteOwner(httpClient, emoteID)
+	case "7tv":
+		return lookupSevenTVEmoteOwner(httpClient, emoteID)
+	case "bttv":
+		return lookupBTTVEmoteOwner(httpClient, emoteID)
+	case "":
+	default:
+		return nil, fmt.Errorf("whois does not support %s emotes (supported: twitch, 7tv, bttv)", source)
+	}
+
+	if twitchEmoteIDPattern.MatchString(emoteID) {
+		return lookupTwitchEmoteOwner(httpClient, emoteID)
+	}
+	if !sevenTVEmoteIDPattern.MatchString(emoteID) {
+		return nil, fmt.Errorf("%q does not look like a Twitch, 7TV or BetterTTV emote ID", emoteID)
+	}
+	owner, sevenTVErr := lookupSevenTVEmoteOwner(httpClient, emoteID)
+	if sevenTVErr == nil {
+		return owner, nil
+	}
+	owner, bttvErr := lookupBTTVEmoteOwner(httpClient, emoteID)
+	if bttvErr == nil {
+		return owner, nil
+	}
+	return nil, fmt.Errorf("emote %s not found (7tv: %v; bttv: %v)", emoteID, sevenTVErr, bttvErr)
+}
+
+func fetchSevenTVEmoteSet(httpClient *http.Client, owner *emoteOwner) (*ChannelData, error) {
+	var set struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Emotes []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			Data struct {
+				Animated bool `json:"animated"`
+			} `json:"data"`
+		} `json:"emotes"`
+	}
+	if err := fetchJSON(httpClient, sevenTVAPIURL+"/emote-sets/"+url.PathEscape(owner.SetID), &set); err != nil {
+		return nil, err
+	}
+	channel := &ChannelData{ID: owner.OwnerID, DisplayName: owner.displayName(), Emotes: make(map[string]EmoteData, len(set.Emotes))}
+	for _, emote := range set.Emotes {
+		extension, formatType := "png", "static"
+		if emote.Data.Animated {
+			extension, formatType = "gif", "animated"
+		}
+		channel.Emotes[emote.ID] = EmoteData{BaseURL: fmt.Sprintf("https://cdn.7tv.app/emote/%s/%s.%s", emote.ID, pluginSizePlaceholder, extension), FormatType: formatType, EmoteCode: emote.Name}
+	}
+	return channel, nil
+}
+
+func fetchBTTVUserEmotes(httpClient *http.Client, owner *emoteOwner) (*ChannelData, error) {
+	type bttvEmote struct {
+		ID       string `json:"id"`
+		Code     string `json:"code"`
+		Animated bool   `json:"animated"`
+	}
+	var user struct {
+		ChannelEmotes []bttvEmote `json:"channelEmotes"`
+		SharedEmotes  []bttvEmote `json:"sharedEmotes"`
+	}
+	if err := fetchJSON(httpClient, bttvAPIURL+"/cached/users/twitch/"+url.PathEscape(owner.TwitchID), &user); err != nil {
+		return nil, err
+	}
+	channel := &ChannelData{ID: owner.OwnerID, DisplayName: owner.displayName(), Emotes: make(map[string]EmoteData)}
+	for _, emote := range append(user.ChannelEmotes, user.SharedEmotes...) {
+		formatType := "static"
+		if emote.Animated {
+			formatType = "animated"
+		}
+		channel.Emotes[emote.ID] = EmoteData{BaseURL: fmt.Sprintf("https://cdn.betterttv.net/emote/%s/%s", emote.ID, pluginSizePlaceholder), FormatType: formatType, EmoteCode: emote.Code}
+	}
+	return channel, nil
+}
+
+func (owner *emoteOwner) displayName() string {
+	if owner.OwnerName != "" {
+		return owner.OwnerName
+	}
+	if owner.OwnerLogin != "" {
+		return owner.OwnerLogin
+	}
+	return owner.OwnerID
+}
+
+func downloadOwnerEmotes(httpClient *http.Client, owner *emoteOwner, options downloadOptions, logFunc func(string)) error {
+	if owner.OwnerID == "" {
+		return fmt.Errorf("the owner of %s emote %s is unknown", owner.Source, owner.EmoteID)
+	}
+	var provider emoteProvider
+	var channel *ChannelData
+	var err error
+	switch owner.Source {
+	case "twitch":
+		provider = twitchProvider{}
+		channel, err = provider.FetchChannel(httpClient, owner.OwnerID)
+	case "7tv":
+		if owner.SetID == "" {
+			return fmt.Errorf("%s has no active 7TV emote set", owner.displayName())
+		}
+		provider = searchProvider{name: "7tv", sizes: []string{"1x", "2x", "3x", "4x"}}
+		channel, err = fetchSevenTVEmoteSet(httpClient, owner)
+	case "bttv":
+		if owner.TwitchID == "" {
+			return fmt.Errorf("%s has no linked Twitch account on BetterTTV", owner.displayName())
+		}
+		provider = searchProvider{name: "bttv", sizes: []string{"1x", "2x", "3x"}}
+		channel, err = fetchBTTVUserEmotes(httpClient, owner)
+	}
+	if err != nil {
+		return err
+	}
+	return downloadChannelData(httpClient, provider, channel, options, logFunc)
+}
+
+func newWhoisFlagSet(options *downloadOptions, download *bool, jsonOutput *bool) *flag.FlagSet {
+	flagSet := newCommandFlagSet("whois", options)
+	flagSet.BoolVar(download, "download", false, "download every emote of the owning channel's set")
+	flagSet.BoolVar(jsonOutput, "json", false, "print the owner as JSON")
+	return flagSet
+}
+
+func runWhoisCommand(arguments []string) int {
+	var options downloadOptions
+	var download, jsonOutput bool
+
+	flagSet := newWhoisFlagSet(&options, &download, &jsonOutput)
+	positional, err := parseCommandLine(flagSet, &options, arguments)
+	if err != nil {
+		return exitCodeForParseError(err)
+	}
+	if len(positional) != 1 || strings.TrimSpace(positional[0]) == "" {
+		fmt.Fprintln(os.Stderr, "Usage: twe-dlp whois [--download] [--json] <emote id|emote URL>")
+		return 2
+	}
+	httpClient := createHTTPClient(options)
+
+	source, emoteID := parseEmoteReference(positional[0])
+	owner, err := lookupEmoteOwner(httpClient, source, emoteID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(owner); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	} else {
+		fmt.Printf("Emote: %s (%s)\n", owner.Code, owner.EmoteID)
+		fmt.Printf("Source: %s\n", owner.Source)
+		if owner.OwnerID == "" {
+			fmt.Println("Owner: unknown")
+		} else {
+			fmt.Printf("Owner: %s (%s, ID %s)\n", owner.displayName(), owner.OwnerLogin, owner.OwnerID)
+		}
+		if owner.SetID != "" {
+			fmt.Printf("Set: %s\n", owner.SetID)
+		}
+		if owner.TwitchID != "" {
+			fmt.Printf("Twitch ID: %s\n", owner.TwitchID)
+		}
+		if owner.EmoteType != "" {
+			fmt.Printf("Type: %s\n", owner.EmoteType)
+		}
+	}
+	if !download {
+		return 0
+	}
+
+	logFunc := func(line string) {
+		fmt.Println(logTimestamp(options.Timestamps) + line)
+		options.Log.write(line)
+	}
+	if err := downloadOwnerEmotes(httpClient, owner, options, logFunc); err != nil {
+		fmt.Fprintf(os.Stderr, "Error downloading emotes: %v\n", err)
+		return 1
+	}
+	return 0
+}